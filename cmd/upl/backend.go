@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/localfs"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+// localScheme marks endpoints that are directories on this machine instead
+// of S3 services, e.g. file:///srv/termup.
+const localScheme = "file://"
+
+// openBackend returns the storage the profile in cfg points at.
+func openBackend(globals *globalOptions, cfg *config.Config) (s3storage.Backend, error) {
+	if dir, ok := strings.CutPrefix(cfg.Endpoint, localScheme); ok {
+		if dir == "" {
+			return nil, &configError{fmt.Errorf("endpoint %s names no directory", cfg.Endpoint)}
+		}
+		backend, err := localfs.New(dir, cfg.Bucket)
+		if err != nil {
+			return nil, &configError{fmt.Errorf("error opening local storage: %w", err)}
+		}
+		if cfg.PublicUrl != "" && cfg.PublicUrl != config.DefaultPublicUrl {
+			backend.PublicURL = cfg.PublicUrl
+		}
+		return backend, nil
+	}
+
+	backend, err := s3storage.NewS3Backend(context.Background(), cfg, &s3storage.ClientOptions{InsecureTLS: globals.insecure})
+	if err != nil {
+		return nil, &configError{fmt.Errorf("error creating storage client: %w", err)}
+	}
+	return backend, nil
+}
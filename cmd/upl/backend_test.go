@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/localfs"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+func TestOpenBackend(t *testing.T) {
+	dir := t.TempDir()
+	globals := &globalOptions{}
+
+	backend, err := openBackend(globals, &config.Config{Endpoint: "file://" + dir, Bucket: "files", PublicUrl: config.DefaultPublicUrl})
+	if err != nil {
+		t.Fatalf("openBackend() error = %v", err)
+	}
+	if _, ok := backend.(*localfs.Backend); !ok {
+		t.Fatalf("openBackend() = %T, want *localfs.Backend", backend)
+	}
+	if url := backend.URL("a.txt"); url != "file://"+filepath.ToSlash(filepath.Join(dir, "files", "a.txt")) {
+		t.Errorf("URL() = %s", url)
+	}
+
+	backend, err = openBackend(globals, &config.Config{Endpoint: "file://" + dir, Bucket: "files", PublicUrl: "https://files.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url := backend.URL("a.txt"); url != "https://files.example.com/a.txt" {
+		t.Errorf("URL() = %s, want the public URL", url)
+	}
+
+	if _, err := openBackend(globals, &config.Config{Endpoint: "file://", Bucket: "files"}); err == nil || exitCode(err) != exitConfig {
+		t.Errorf("openBackend() without a directory error = %v, want a config error", err)
+	}
+
+	backend, err = openBackend(globals, &config.Config{Endpoint: "https://s3.example.com", Bucket: "files"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := backend.(*s3storage.S3Backend); !ok || !strings.HasPrefix(backend.Endpoint(), "https://") {
+		t.Errorf("openBackend() = %T, want *s3storage.S3Backend", backend)
+	}
+}
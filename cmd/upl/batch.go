@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nizar0x1f/termup/pkg/batch"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+// runBatchUpload uploads several files in the queue UI and prints a summary
+// once all of them have finished. Quitting the UI cancels the remaining
+// files.
+func runBatchUpload(globals *globalOptions, cfg *config.Config, backend s3storage.Backend, files []batch.File, opts *s3storage.UploadOptions, headers config.Headers, jobs int) error {
+	items := make([]ui.QueueItem, len(files))
+	for i, file := range files {
+		items[i] = ui.QueueItem{Name: file.Key, Size: file.Size}
+	}
+
+	runner := &batch.Runner{
+		Workers: jobs,
+		Upload: func(ctx context.Context, file batch.File, progress func(int64)) (*s3storage.UploadResult, error) {
+			fileOpts := *opts
+			fileOpts.Key = file.Key
+			setHeaders(&fileOpts, cfg, file.Key, headers)
+			return s3storage.UploadFile(ctx, backend, file.Path, &fileOpts, progress)
+		},
+	}
+
+	model := ui.NewQueueModel(items, runner.Cancel)
+	if opts.Limiter != nil {
+		model.SetRateLimit(opts.Limiter.Rate(), opts.Limiter.SetRate)
+	}
+	p := tea.NewProgram(model)
+
+	runner.OnStart = func(index int) {
+		p.Send(ui.QueueStartMsg(index))
+	}
+	runner.OnProgress = func(index int, uploaded int64) {
+		p.Send(ui.QueueProgressMsg{Index: index, Uploaded: uploaded})
+	}
+	runner.OnDone = func(index int, result batch.Result) {
+		p.Send(ui.QueueDoneMsg{
+			Index:     index,
+			URL:       result.URL(),
+			Err:       result.Err,
+			Cancelled: errors.Is(result.Err, batch.ErrCancelled),
+			Skipped:   result.Upload != nil && result.Upload.Skipped,
+		})
+	}
+
+	start := time.Now()
+	resultsCh := make(chan []batch.Result, 1)
+	go func() {
+		resultsCh <- runner.Run(files)
+	}()
+
+	finalModel, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("error running upload UI: %w", err)
+	}
+
+	if !finalModel.(ui.QueueModel).Finished() {
+		for i := range files {
+			runner.Cancel(i)
+		}
+	}
+
+	results := <-resultsCh
+	uploads := make([]*s3storage.UploadResult, 0, len(results))
+	for _, result := range results {
+		if result.Err == nil {
+			uploads = append(uploads, result.Upload)
+		}
+	}
+	recordUploads(globals, backend, opts.PresignExpiry, uploads...)
+
+	return printBatchSummary(results, time.Since(start))
+}
+
+func printBatchSummary(results []batch.Result, elapsed time.Duration) error {
+	for _, result := range results {
+		switch {
+		case errors.Is(result.Err, batch.ErrCancelled):
+			fmt.Printf("⏹️  %s: cancelled\n", result.File.Path)
+		case result.Err != nil:
+			fmt.Printf("❌ %s: %v\n", result.File.Path, result.Err)
+		case result.Upload.Skipped:
+			fmt.Printf("⏭️  %s (already uploaded)\n", result.URL())
+		case len(result.Upload.Retries) > 0:
+			fmt.Printf("✅ %s (%s)\n", result.URL(), s3storage.FormatRetries(result.Upload.Retries))
+		default:
+			fmt.Printf("✅ %s\n", result.URL())
+		}
+	}
+
+	summary := batch.Summarize(results)
+	fmt.Printf("\nUploaded %d of %d files (%s) in %s",
+		summary.Succeeded, len(results), ui.FormatBytes(summary.Bytes), ui.FormatDuration(elapsed))
+	if summary.Skipped > 0 {
+		fmt.Printf(", %d already uploaded", summary.Skipped)
+	}
+	if summary.Cancelled > 0 {
+		fmt.Printf(", %d cancelled", summary.Cancelled)
+	}
+	if summary.Failed > 0 {
+		fmt.Printf(", %d failed", summary.Failed)
+	}
+	fmt.Println()
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return failureExit(errs)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+// browserPageSize keeps each listing request small enough for the browser
+// to stay responsive in very large buckets.
+const browserPageSize = 500
+
+// bucketSource shows the bucket of a profile in the browser.
+type bucketSource struct {
+	cfg     *config.Config
+	backend s3storage.Backend
+}
+
+func (s *bucketSource) List(ctx context.Context, prefix, token string) (*ui.BrowserPage, error) {
+	listing, err := s3storage.ListPage(ctx, s.backend, &s3storage.ListOptions{
+		Prefix:   prefix,
+		PageSize: browserPageSize,
+		Token:    token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ui.BrowserPage{NextToken: listing.NextToken}
+	for _, p := range listing.Prefixes {
+		page.Entries = append(page.Entries, ui.BrowserEntry{Key: p, Dir: true})
+	}
+	for _, object := range listing.Objects {
+		if object.Key == prefix {
+			// Folder placeholder objects created by some consoles.
+			continue
+		}
+		page.Entries = append(page.Entries, ui.BrowserEntry{
+			Key:          object.Key,
+			Size:         object.Size,
+			LastModified: object.LastModified,
+		})
+	}
+	return page, nil
+}
+
+func (s *bucketSource) Details(ctx context.Context, key string) (*ui.ObjectDetails, error) {
+	info, err := s3storage.Stat(ctx, s.backend, key)
+	if err != nil {
+		return nil, err
+	}
+	return &ui.ObjectDetails{
+		Key:          info.Key,
+		Size:         info.Size,
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		Metadata:     info.Metadata,
+	}, nil
+}
+
+func (s *bucketSource) URL(ctx context.Context, key string) (string, error) {
+	expiry, err := linkExpiry("", 0, s.cfg)
+	if err != nil || expiry == 0 {
+		return s.backend.URL(key), err
+	}
+	return s3storage.Presign(ctx, s.backend, key, expiry)
+}
+
+func (s *bucketSource) Delete(ctx context.Context, key string) error {
+	return s3storage.Delete(ctx, s.backend, key)
+}
+
+func newBrowseCommand(globals *globalOptions) *command {
+	cmd := &command{
+		name:    "browse",
+		args:    "[prefix]",
+		summary: "Browse the bucket in a full-screen view",
+	}
+	cmd.run = func(args []string) error {
+		if len(args) > 1 {
+			return newUsageError(cmd, "expected at most one prefix")
+		}
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		return runBrowse(globals, prefix)
+	}
+	return cmd
+}
+
+// runBrowse shows the browser until it is quit. Downloads and uploads
+// started from the browser run in their own progress view, after which the
+// browser opens again at the same folder.
+func runBrowse(globals *globalOptions, prefix string) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+	source := &bucketSource{cfg: cfg, backend: backend}
+
+	for {
+		finalModel, err := tea.NewProgram(ui.NewBrowserModel(source, prefix), tea.WithAltScreen()).Run()
+		if err != nil {
+			return fmt.Errorf("error running browser: %w", err)
+		}
+
+		action := finalModel.(ui.BrowserModel).Action()
+		prefix = action.Prefix
+
+		switch action.Kind {
+		case ui.BrowserDownload:
+			dest, err := downloadPath(action.Key, "")
+			if err == nil {
+				err = runDownloadUI(backend, action.Key, dest, &s3storage.DownloadOptions{})
+			}
+			reportError(err)
+		case ui.BrowserUpload:
+			opts := &s3storage.UploadOptions{
+				Key:       prefix + filepath.Base(action.Path),
+				Collision: s3storage.CollisionAsk,
+				Checksum:  true,
+			}
+			setHeaders(opts, cfg, opts.Key, config.Headers{})
+			autoCopy, err := copyURL(&uploadFlags{}, cfg)
+			if err == nil {
+				err = runUploadUI(globals, backend, action.Path, opts, autoCopy)
+			}
+			reportError(err)
+		default:
+			return nil
+		}
+	}
+}
+
+// reportError prints errors the progress views have not shown already.
+func reportError(err error) {
+	var exitErr *exitError
+	if err != nil && !errors.As(err, &exitErr) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command is a node in the CLI command tree. Commands with subcommands may
+// also have a run function, which handles arguments that do not name a
+// subcommand (the root command uses this for the `upl <file>` shortcut).
+type command struct {
+	name     string
+	aliases  []string
+	args     string
+	summary  string
+	flags    *flagSet
+	run      func(args []string) error
+	commands []*command
+	parent   *command
+	hidden   bool
+
+	// extraHelp is appended to the generated help text.
+	extraHelp string
+}
+
+// usageError is returned for invalid command lines; it makes the command
+// print a hint to its help and exit with status 2.
+type usageError struct {
+	cmd *command
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// exitError ends the program with code after the failure has already been
+// reported to the user, for example by the TUI.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func newUsageError(cmd *command, format string, args ...any) error {
+	return &usageError{cmd: cmd, msg: fmt.Sprintf(format, args...)}
+}
+
+func (c *command) add(cmds ...*command) *command {
+	for _, sub := range cmds {
+		sub.parent = c
+		c.commands = append(c.commands, sub)
+	}
+	return c
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.commands {
+		if sub.name == name {
+			return sub
+		}
+		for _, alias := range sub.aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+func (c *command) path() string {
+	if c.parent == nil {
+		return c.name
+	}
+	return c.parent.path() + " " + c.name
+}
+
+// execute parses args for c, descending into subcommands, and runs the
+// selected command. globals are accepted by every command.
+func (c *command) execute(args []string, globals *flagSet, help *bool) error {
+	fs := newFlagSet(c.name)
+	fs.merge(globals)
+	if c.flags != nil {
+		fs.merge(c.flags)
+	}
+
+	if len(c.commands) > 0 {
+		head, tail := args, []string(nil)
+		for i, arg := range args {
+			if arg == "--" {
+				head, tail = args[:i], args[i:]
+				break
+			}
+		}
+
+		if err := fs.Parse(head); err != nil {
+			return flagError(c, err)
+		}
+		rest := append(fs.Args(), tail...)
+		if len(rest) > 0 {
+			if sub := c.find(rest[0]); sub != nil {
+				return sub.execute(rest[1:], globals, help)
+			}
+		}
+		if c.run == nil {
+			if *help || len(rest) == 0 {
+				c.printHelp(os.Stdout)
+				return nil
+			}
+			return newUsageError(c, "unknown command %q for %q", rest[0], c.path())
+		}
+		args = rest
+	}
+
+	positional, err := parseInterspersed(fs.FlagSet, args)
+	if err != nil {
+		return flagError(c, err)
+	}
+
+	if *help {
+		c.printHelp(os.Stdout)
+		return nil
+	}
+
+	return c.run(positional)
+}
+
+func flagError(c *command, err error) error {
+	msg := err.Error()
+	if name, ok := strings.CutPrefix(msg, "flag provided but not defined: -"); ok {
+		if len(name) > 1 {
+			name = "-" + name
+		}
+		msg = "unknown flag: -" + name
+	}
+	return newUsageError(c, "%s", msg)
+}
+
+// parseInterspersed parses flags that appear anywhere among the positional
+// arguments. Everything after "--" is positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional, tail []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, tail = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	return append(positional, tail...), nil
+}
+
+func (c *command) printHelp(w io.Writer) {
+	if c.summary != "" {
+		fmt.Fprintln(w, c.summary)
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "USAGE:")
+	synopsis := c.path()
+	if c.flags != nil && len(c.flags.defs) > 0 {
+		synopsis += " [OPTIONS]"
+	}
+	if c.args != "" {
+		synopsis += " " + c.args
+	}
+	if c.run != nil || len(c.commands) == 0 {
+		fmt.Fprintf(w, "    %s\n", synopsis)
+	}
+	if len(c.commands) > 0 {
+		fmt.Fprintf(w, "    %s [COMMAND]\n", c.path())
+	}
+
+	if c.flags != nil && len(c.flags.defs) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "OPTIONS:")
+		c.flags.printDefaults(w)
+	}
+
+	var visible []*command
+	for _, sub := range c.commands {
+		if !sub.hidden {
+			visible = append(visible, sub)
+		}
+	}
+	if len(visible) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "COMMANDS:")
+		for _, sub := range visible {
+			name := sub.name
+			if sub.args != "" {
+				name += " " + sub.args
+			}
+			fmt.Fprintf(w, "    %-24s %s\n", name, sub.summary)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "GLOBAL OPTIONS:")
+	newGlobalFlags(&globalOptions{}, new(bool)).printDefaults(w)
+
+	if c.extraHelp != "" {
+		fmt.Fprintln(w)
+		fmt.Fprint(w, c.extraHelp)
+	}
+
+	if len(c.commands) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Run '%s <command> --help' for more information on a command.\n", c.path())
+	}
+}
+
+// flagSet wraps flag.FlagSet to keep long and short names together and to
+// generate help text from the definitions.
+type flagSet struct {
+	*flag.FlagSet
+	defs []*flagDef
+}
+
+type flagDef struct {
+	name        string
+	short       string
+	placeholder string
+	usage       string
+	value       flag.Value
+}
+
+func newFlagSet(name string) *flagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return &flagSet{FlagSet: fs}
+}
+
+func (f *flagSet) define(def *flagDef) {
+	f.defs = append(f.defs, def)
+	f.Var(def.value, def.name, def.usage)
+	if def.short != "" {
+		f.Var(def.value, def.short, def.usage)
+	}
+}
+
+func (f *flagSet) merge(other *flagSet) {
+	for _, def := range other.defs {
+		f.define(def)
+	}
+}
+
+func (f *flagSet) stringVar(p *string, name, short, placeholder, usage string) {
+	f.define(&flagDef{name: name, short: short, placeholder: placeholder, usage: usage, value: (*stringValue)(p)})
+}
+
+func (f *flagSet) boolVar(p *bool, name, short, usage string) {
+	f.define(&flagDef{name: name, short: short, usage: usage, value: (*boolValue)(p)})
+}
+
+func (f *flagSet) intVar(p *int, name, short, placeholder, usage string) {
+	f.define(&flagDef{name: name, short: short, placeholder: placeholder, usage: usage, value: (*intValue)(p)})
+}
+
+func (f *flagSet) sizeVar(p *int64, name, short, placeholder, usage string) {
+	f.define(&flagDef{name: name, short: short, placeholder: placeholder, usage: usage, value: (*sizeValue)(p)})
+}
+
+func (f *flagSet) durationVar(p *time.Duration, name, short, placeholder, usage string) {
+	f.define(&flagDef{name: name, short: short, placeholder: placeholder, usage: usage, value: (*durationValue)(p)})
+}
+
+func (f *flagSet) stringsVar(p *[]string, name, short, placeholder, usage string) {
+	f.define(&flagDef{name: name, short: short, placeholder: placeholder, usage: usage, value: (*stringsValue)(p)})
+}
+
+func (f *flagSet) printDefaults(w io.Writer) {
+	defs := append([]*flagDef(nil), f.defs...)
+	sort.SliceStable(defs, func(i, j int) bool { return defs[i].name < defs[j].name })
+
+	for _, def := range defs {
+		var b strings.Builder
+		if def.short != "" {
+			fmt.Fprintf(&b, "-%s, ", def.short)
+		} else {
+			b.WriteString("    ")
+		}
+		fmt.Fprintf(&b, "--%s", def.name)
+		if def.placeholder != "" {
+			fmt.Fprintf(&b, " <%s>", def.placeholder)
+		}
+		fmt.Fprintf(w, "    %-28s %s\n", b.String(), def.usage)
+	}
+}
+
+type stringValue string
+
+func (s *stringValue) Set(v string) error { *s = stringValue(v); return nil }
+func (s *stringValue) String() string     { return string(*s) }
+
+// stringsValue collects every occurrence of a repeatable flag.
+type stringsValue []string
+
+func (s *stringsValue) Set(v string) error { *s = append(*s, v); return nil }
+func (s *stringsValue) String() string     { return strings.Join(*s, ",") }
+
+type boolValue bool
+
+func (b *boolValue) Set(v string) error {
+	switch strings.ToLower(v) {
+	case "true", "1", "yes":
+		*b = true
+	case "false", "0", "no":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %q", v)
+	}
+	return nil
+}
+func (b *boolValue) String() string   { return fmt.Sprint(bool(*b)) }
+func (b *boolValue) IsBoolFlag() bool { return true }
+
+type intValue int
+
+func (i *intValue) Set(v string) error {
+	var n int
+	if _, err := fmt.Sscanf(v, "%d", &n); err != nil || fmt.Sprint(n) != v {
+		return fmt.Errorf("invalid number %q", v)
+	}
+	*i = intValue(n)
+	return nil
+}
+func (i *intValue) String() string { return fmt.Sprint(int(*i)) }
+
+type sizeValue int64
+
+func (s *sizeValue) Set(v string) error {
+	n, err := parseSize(v)
+	if err != nil {
+		return err
+	}
+	*s = sizeValue(n)
+	return nil
+}
+func (s *sizeValue) String() string { return fmt.Sprint(int64(*s)) }
+
+type durationValue time.Duration
+
+func (d *durationValue) Set(v string) error {
+	duration, err := parseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d = durationValue(duration)
+	return nil
+}
+func (d *durationValue) String() string { return time.Duration(*d).String() }
+
+// parseDuration parses a Go duration such as "90m" or "24h", or a number of
+// days such as "7d".
+func parseDuration(s string) (time.Duration, error) {
+	str := strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(str, "d"); ok {
+		value, err := strconv.ParseFloat(days, 64)
+		if err != nil || value <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(value * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(str)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// parseSize parses a byte size such as "512", "64K", "16MB" or "1.5GiB".
+// Units are binary: 1 MB is 1024*1024 bytes.
+func parseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "IB")
+	str = strings.TrimSuffix(str, "B")
+
+	multiplier := int64(1)
+	if n := len(str); n > 0 {
+		switch str[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			str = str[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// parseRate parses a transfer rate such as "5MB/s" or "500K", a size per
+// second; the "/s" may be left out.
+func parseRate(s string) (int64, error) {
+	str := strings.TrimSpace(s)
+	if n := len(str); n >= 2 && strings.EqualFold(str[n-2:], "/s") {
+		str = str[:n-2]
+	}
+	rate, err := parseSize(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return rate, nil
+}
+
+// confirm lists items on out and asks question, such as "Delete these 3
+// objects?". Only "y" or "yes" counts as a yes.
+func confirm(in io.Reader, out io.Writer, items []string, question string) bool {
+	for _, item := range items {
+		fmt.Fprintf(out, "  %s\n", item)
+	}
+	fmt.Fprintf(out, "%s (y/N): ", question)
+
+	var response string
+	_, _ = fmt.Fscanln(in, &response)
+	switch strings.ToLower(response) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", usageErr)
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", usageErr.cmd.path())
+		os.Exit(exitUsage)
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{input: "512", expected: 512},
+		{input: "64K", expected: 64 << 10},
+		{input: "16MB", expected: 16 << 20},
+		{input: "16mib", expected: 16 << 20},
+		{input: "1.5GiB", expected: 3 << 29},
+		{input: "2T", expected: 2 << 40},
+		{input: "", wantErr: true},
+		{input: "lots", wantErr: true},
+		{input: "-5MB", wantErr: true},
+		{input: "5x", wantErr: true},
+		{input: "1.5.2MB", wantErr: true},
+		{input: "16Q", wantErr: true},
+		{input: "16 MB", wantErr: true},
+		{input: "InfMB", wantErr: true},
+		{input: "NaN", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("parseSize(%q) = %d, want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{input: "5MB/s", expected: 5 << 20},
+		{input: "500k/S", expected: 500 << 10},
+		{input: "1.5M", expected: 3 << 19},
+		{input: "0", expected: 0},
+		{input: "/s", wantErr: true},
+		{input: "fast", wantErr: true},
+		{input: "5x", wantErr: true},
+		{input: "1.5.2MB/s", wantErr: true},
+		{input: "16Q/s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		result, err := parseRate(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("parseRate(%q) = %d, want %d", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func newTestTree(ran *[]string, key *string, globals *globalOptions) *command {
+	uploadFlags := newFlagSet("upload")
+	uploadFlags.stringVar(key, "key", "k", "key", "Object key")
+
+	root := &command{name: "upl", flags: uploadFlags}
+	root.run = func(args []string) error {
+		*ran = append([]string{"root"}, args...)
+		return nil
+	}
+
+	profile := &command{name: "profile"}
+	profile.add(&command{
+		name:    "list",
+		aliases: []string{"ls"},
+		run: func(args []string) error {
+			*ran = append([]string{"profile list"}, args...)
+			return nil
+		},
+	})
+
+	return root.add(profile)
+}
+
+func TestCommandDispatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expectedRan []string
+		expectedKey string
+		profile     string
+		usageErr    bool
+	}{
+		{
+			name:        "file shortcut",
+			args:        []string{"photo.jpg"},
+			expectedRan: []string{"root", "photo.jpg"},
+		},
+		{
+			name:        "flags after the file",
+			args:        []string{"photo.jpg", "--key", "images/photo.jpg", "-p", "r2"},
+			expectedRan: []string{"root", "photo.jpg"},
+			expectedKey: "images/photo.jpg",
+			profile:     "r2",
+		},
+		{
+			name:        "global flag before subcommand",
+			args:        []string{"--profile", "minio", "profile", "ls"},
+			expectedRan: []string{"profile list"},
+			profile:     "minio",
+		},
+		{
+			name:        "double dash ends flags",
+			args:        []string{"--", "--key"},
+			expectedRan: []string{"root", "--key"},
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"--foo"},
+			usageErr: true,
+		},
+		{
+			name:     "upload flag on subcommand",
+			args:     []string{"profile", "list", "--key", "x"},
+			usageErr: true,
+		},
+		{
+			name:     "unknown subcommand",
+			args:     []string{"profile", "bogus"},
+			usageErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				ran     []string
+				key     string
+				globals globalOptions
+				help    bool
+			)
+			root := newTestTree(&ran, &key, &globals)
+
+			err := root.execute(tt.args, newGlobalFlags(&globals, &help), &help)
+
+			var usageErr *usageError
+			if tt.usageErr {
+				if !errors.As(err, &usageErr) {
+					t.Fatalf("expected usage error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("execute(%q) error = %v", tt.args, err)
+			}
+
+			if !reflect.DeepEqual(ran, tt.expectedRan) {
+				t.Errorf("ran %q, want %q", ran, tt.expectedRan)
+			}
+			if key != tt.expectedKey {
+				t.Errorf("key = %q, want %q", key, tt.expectedKey)
+			}
+			if globals.profile != tt.profile {
+				t.Errorf("profile = %q, want %q", globals.profile, tt.profile)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{input: "24h", expected: 24 * time.Hour},
+		{input: "90m", expected: 90 * time.Minute},
+		{input: "7d", expected: 7 * 24 * time.Hour},
+		{input: "1d12h", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "0s", wantErr: true},
+		{input: "-1h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("parseDuration(%q) = %s, want %s", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if got := confirm(strings.NewReader(tt.input), &out, []string{"tmp/a.txt"}, "Delete this object?"); got != tt.expected {
+			t.Errorf("confirm(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+		if !strings.Contains(out.String(), "tmp/a.txt") {
+			t.Errorf("confirm() did not list the items: %q", out.String())
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/keytemplate"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/secrets"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+func newConfigCommand(globals *globalOptions) *command {
+	cmd := &command{
+		name:    "config",
+		summary: "Show the active configuration and where each value comes from",
+		run: func(args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unknown config command %q", args[0])
+			}
+			return showConfig(globals.profile)
+		},
+	}
+
+	cmd.add(
+		&command{
+			name:    "show",
+			summary: "Show the active configuration and where each value comes from",
+			run: func(args []string) error {
+				return showConfig(globals.profile)
+			},
+		},
+		&command{
+			name:    "edit",
+			summary: "Reconfigure the active profile (same as 'upl relogin')",
+			run: func(args []string) error {
+				return runRelogin(globals.profile)
+			},
+		},
+		newConfigSetCommand(globals),
+		newSecretBackendCommand(),
+	)
+	return cmd
+}
+
+// settingValidators check values given to 'upl config set' before they are
+// saved.
+var settingValidators = map[string]func(string) error{
+	"key_template": func(value string) error {
+		_, err := keytemplate.Parse(value)
+		return err
+	},
+	"on_conflict": func(value string) error {
+		_, err := s3storage.ParseCollisionPolicy(value)
+		return err
+	},
+	"link_mode": func(value string) error {
+		_, err := parseLinkMode(value)
+		return err
+	},
+	"link_expiry": func(value string) error {
+		expiry, err := parseDuration(value)
+		if err == nil && expiry > s3storage.MaxLinkExpiry {
+			err = fmt.Errorf("presigned links can be valid for at most 7 days, got %s", expiry)
+		}
+		return err
+	},
+	"paste_name": func(value string) error {
+		_, err := keytemplate.Parse(value)
+		return err
+	},
+	"copy_url": func(value string) error {
+		_, err := parseSwitch(value)
+		return err
+	},
+	"max_attempts": func(value string) error {
+		_, err := parseAttempts(value)
+		return err
+	},
+	"retry_base": func(value string) error {
+		_, err := parseDuration(value)
+		return err
+	},
+	"retry_max_delay": func(value string) error {
+		_, err := parseDuration(value)
+		return err
+	},
+	"retry_on": func(value string) error {
+		_, err := parseRetryOn(value)
+		return err
+	},
+	"speed_limit": func(value string) error {
+		_, err := parseRate(value)
+		return err
+	},
+}
+
+func newConfigSetCommand(globals *globalOptions) *command {
+	cmd := &command{
+		name:    "set",
+		args:    "<setting> [value]",
+		summary: "Change a setting of the active profile (no value clears it)",
+	}
+	cmd.run = func(args []string) error {
+		if len(args) < 1 || len(args) > 2 {
+			return newUsageError(cmd, "expected a setting name and a value")
+		}
+
+		name, value := args[0], ""
+		if len(args) == 2 {
+			value = args[1]
+		}
+		if validate, ok := settingValidators[name]; ok && value != "" {
+			if err := validate(value); err != nil {
+				return err
+			}
+		}
+
+		if err := config.SetValue(globals.profile, name, value); err != nil {
+			return fmt.Errorf("error changing setting: %w", err)
+		}
+		if value == "" {
+			fmt.Printf("Cleared %s\n", name)
+		} else {
+			fmt.Printf("Set %s to %s\n", name, value)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newSecretBackendCommand() *command {
+	cmd := &command{
+		name:    "secret-backend",
+		args:    "<keyring|file|plaintext>",
+		summary: "Choose where secret keys are stored",
+	}
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return newUsageError(cmd, "expected a backend name")
+		}
+
+		backend, err := secrets.ParseBackend(args[0])
+		if err != nil {
+			return newUsageError(cmd, "%v", err)
+		}
+		if err := config.SetSecretBackend(backend); err != nil {
+			return fmt.Errorf("error changing secret backend: %w", err)
+		}
+		fmt.Printf("Secrets are now stored in: %s\n", args[0])
+		return nil
+	}
+	return cmd
+}
+
+func newReloginCommand(globals *globalOptions) *command {
+	return &command{
+		name:    "relogin",
+		aliases: []string{"login"},
+		summary: "Reconfigure S3 credentials of the active profile",
+		run: func(args []string) error {
+			return runRelogin(globals.profile)
+		},
+	}
+}
+
+func runRelogin(profile string) error {
+	name := profileName(profile)
+
+	var existing *config.Config
+	if f, err := config.LoadFile(); err == nil {
+		existing, _ = f.Profile(name)
+	}
+
+	if runConfigUI(name, existing) == nil {
+		return &exitError{code: exitCancelled}
+	}
+	return nil
+}
+
+func runConfigUI(profile string, existing *config.Config) *config.Config {
+	model := ui.NewProfileConfigModel(profileName(profile), existing)
+	p := tea.NewProgram(model)
+
+	finalModel, err := p.Run()
+	if err != nil {
+		fmt.Printf("Error running config UI: %v\n", err)
+		return nil
+	}
+
+	configModel := finalModel.(ui.ConfigModel)
+	if !configModel.IsFinished() {
+		return nil
+	}
+
+	cfg := configModel.GetConfig()
+	if cfg == nil {
+		return nil
+	}
+
+	err = config.SaveProfile(profile, cfg)
+	if err != nil {
+		fmt.Printf("Error saving config: %v\n", err)
+		return nil
+	}
+
+	return cfg
+}
+
+func showConfig(profile string) error {
+	_, settings, err := config.LoadWithSources(profile)
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	fmt.Printf("Profile: %s\n\n", profileName(profile))
+
+	fmt.Printf("%-18s %-40s %-14s %s\n", "SETTING", "VALUE", "SOURCE", "ENV VAR")
+	for _, setting := range settings {
+		value := setting.Value
+		if setting.Secret && value != "" {
+			value = maskSecret(value)
+		}
+		fmt.Printf("%-18s %-40s %-14s %s\n", setting.Name, value, setting.Source, setting.EnvVar)
+	}
+	return nil
+}
+
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
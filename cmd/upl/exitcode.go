@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+// Exit codes are part of the command-line interface: scripts may rely on
+// them, so existing values must not change.
+const (
+	exitOK        = 0
+	exitFailure   = 1
+	exitUsage     = 2
+	exitConfig    = 3
+	exitAuth      = 4
+	exitNotFound  = 5
+	exitConflict  = 6
+	exitNetwork   = 7
+	exitCancelled = 130
+)
+
+// configError marks missing or invalid configuration.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+func (e *configError) Unwrap() error { return e.err }
+
+// exitCode maps an error to the exit code of its class.
+func exitCode(err error) int {
+	var (
+		exitErr   *exitError
+		usageErr  *usageError
+		cfgErr    *configError
+		existsErr *s3storage.ObjectExistsError
+	)
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &cfgErr):
+		return exitConfig
+	case errors.Is(err, context.Canceled), errors.Is(err, s3storage.ErrCancelled):
+		return exitCancelled
+	case errors.As(err, &existsErr):
+		return exitConflict
+	case errors.Is(err, s3storage.ErrObjectNotFound):
+		return exitNotFound
+	case s3storage.IsAuthError(err):
+		return exitAuth
+	case s3storage.IsNetworkError(err):
+		return exitNetwork
+	}
+	return exitFailure
+}
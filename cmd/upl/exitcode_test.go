@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/nizar0x1f/termup/pkg/batch"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"nil", nil, exitOK},
+		{"generic", errors.New("boom"), exitFailure},
+		{"exit error", &exitError{code: 42}, 42},
+		{"usage", &usageError{msg: "bad flag"}, exitUsage},
+		{"config", &configError{errNoConfig}, exitConfig},
+		{"cancelled", fmt.Errorf("upload: %w", context.Canceled), exitCancelled},
+		{"batch cancelled", batch.ErrCancelled, exitCancelled},
+		{"exists", &s3storage.ObjectExistsError{Key: "a.txt"}, exitConflict},
+		{"not found", fmt.Errorf("info: %w", s3storage.ErrObjectNotFound), exitNotFound},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDenied"}, exitAuth},
+		{"bad signature", fmt.Errorf("put: %w", &smithy.GenericAPIError{Code: "SignatureDoesNotMatch"}), exitAuth},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, exitNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.expected {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFailureExit(t *testing.T) {
+	notFound := s3storage.ErrObjectNotFound
+	tests := []struct {
+		name     string
+		errs     []error
+		expected int
+	}{
+		{"none", nil, exitOK},
+		{"same class", []error{notFound, fmt.Errorf("b: %w", notFound)}, exitNotFound},
+		{"mixed", []error{notFound, errors.New("boom")}, exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(failureExit(tt.errs)); got != tt.expected {
+				t.Errorf("failureExit() exit code = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
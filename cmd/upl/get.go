@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+type getFlags struct {
+	partSize    int64
+	concurrency int
+	force       bool
+	limit       string
+	output      string
+}
+
+func newGetCommand(globals *globalOptions) *command {
+	var opts getFlags
+
+	fs := newFlagSet("get")
+	fs.sizeVar(&opts.partSize, "part-size", "", "size", "Size of each ranged request for large objects, e.g. 16MB")
+	fs.intVar(&opts.concurrency, "concurrency", "", "n", "Number of ranges downloaded at once")
+	fs.boolVar(&opts.force, "force", "f", "Overwrite the destination if it exists")
+	fs.stringVar(&opts.limit, "limit", "", "rate", "Cap the download speed of all ranges together, e.g. 5MB/s (0 for none)")
+	fs.stringVar(&opts.output, "output", "o", "format", "Print the result without the UI: json or plain (default plain when not a terminal)")
+
+	cmd := &command{
+		name:    "get",
+		aliases: []string{"download"},
+		args:    "<key|url> [dest]",
+		summary: "Download an object from the bucket",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) == 0 || len(args) > 2 {
+			return newUsageError(cmd, "expected a key and an optional destination")
+		}
+		dest := ""
+		if len(args) == 2 {
+			dest = args[1]
+		}
+		format, err := downloadFormat(cmd, opts.output)
+		if err != nil {
+			return err
+		}
+		return runGet(globals, &opts, args[0], dest, format)
+	}
+	return cmd
+}
+
+// downloadFormat returns the output format for downloads: --output, or
+// plain when stdout is not a terminal. An empty format means the progress UI.
+func downloadFormat(cmd *command, output string) (string, error) {
+	switch output {
+	case formatJSON, formatPlain:
+		return output, nil
+	case "":
+		if !interactive() {
+			return formatPlain, nil
+		}
+		return "", nil
+	}
+	return "", newUsageError(cmd, "unknown output format %q (expected json or plain)", output)
+}
+
+func runGet(globals *globalOptions, opts *getFlags, arg, dest, format string) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+
+	limiter, err := speedLimiter(opts.limit, cfg)
+	if err != nil {
+		return err
+	}
+
+	key, err := objectKey(cfg, arg)
+	if err != nil {
+		return err
+	}
+	dest, err = downloadPath(key, dest)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); err == nil && !opts.force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", dest)
+	}
+
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+	downloadOpts := &s3storage.DownloadOptions{
+		PartSize:    opts.partSize,
+		Concurrency: opts.concurrency,
+		Limiter:     limiter,
+	}
+	if format != "" {
+		return runPlainDownload(backend, key, dest, downloadOpts, format)
+	}
+	return runDownloadUI(backend, key, dest, downloadOpts)
+}
+
+// downloadPath returns where to save key: dest, or inside dest if it is a
+// directory, or the key's base name in the working directory.
+func downloadPath(key, dest string) (string, error) {
+	name := path.Base(key)
+	if name == "." || name == "/" {
+		return "", fmt.Errorf("cannot derive a file name from key %q", key)
+	}
+
+	if dest == "" {
+		return name, nil
+	}
+	if strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, string(filepath.Separator)) {
+		return filepath.Join(dest, name), nil
+	}
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		return filepath.Join(dest, name), nil
+	}
+	return dest, nil
+}
+
+func runDownloadUI(backend s3storage.Backend, key, dest string, opts *s3storage.DownloadOptions) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	info, err := s3storage.Stat(ctx, backend, key)
+	if err != nil {
+		return err
+	}
+
+	model := ui.NewDownloadModel(key, info.Size)
+	model.SetCancel(cancel)
+	if opts.Limiter != nil {
+		model.SetRateLimit(opts.Limiter.Rate(), opts.Limiter.SetRate)
+	}
+	p := tea.NewProgram(model)
+
+	go func() {
+		result, err := s3storage.Download(ctx, backend, key, dest, opts, func(downloaded int64) {
+			p.Send(ui.UploadProgressMsg(downloaded))
+		})
+		if err != nil {
+			p.Send(ui.UploadErrorMsg(err))
+			return
+		}
+		p.Send(ui.UploadCompleteMsg(result.Path))
+	}()
+
+	finalModel, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("error running download UI: %w", err)
+	}
+
+	model = finalModel.(ui.UploadModel)
+	if err := model.GetError(); err != nil {
+		return &exitError{code: exitCode(err)}
+	}
+	if !model.IsDone() {
+		fmt.Println("Download interrupted. Run the same command again to resume it.")
+		return &exitError{code: exitCancelled}
+	}
+	return nil
+}
+
+// downloadOutput is the line --output json prints for a download.
+type downloadOutput struct {
+	Key     string `json:"key"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ETag    string `json:"etag,omitempty"`
+	Resumed int64  `json:"resumed,omitempty"`
+
+	// Verified names what the file was checked against: sha256, md5 or
+	// nothing.
+	Verified string `json:"verified,omitempty"`
+
+	// Duration is the download time in seconds.
+	Duration float64 `json:"duration"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// runPlainDownload downloads key without the UI and prints the result.
+func runPlainDownload(backend s3storage.Backend, key, dest string, opts *s3storage.DownloadOptions, format string) error {
+	// Ctrl+C stops the download and keeps the partial file for resuming.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	result, err := s3storage.Download(ctx, backend, key, dest, opts, func(int64) {})
+	printDownload(os.Stdout, os.Stderr, format, key, dest, result, err, time.Since(start))
+	if err != nil {
+		return &exitError{code: exitCode(err)}
+	}
+	return nil
+}
+
+// printDownload writes the result of a download to stdout. In plain format
+// errors go to stderr.
+func printDownload(stdout, stderr io.Writer, format, key, dest string, result *s3storage.DownloadResult, err error, elapsed time.Duration) {
+	if format == formatJSON {
+		out := downloadOutput{Key: key, Path: dest, Duration: elapsed.Seconds()}
+		if err != nil {
+			out.Error = err.Error()
+		} else {
+			out.Path = result.Path
+			out.Size = result.Size
+			out.ETag = result.ETag
+			out.Resumed = result.Resumed
+			out.Verified = result.Checksum
+		}
+		line, _ := json.Marshal(out)
+		fmt.Fprintln(stdout, string(line))
+		return
+	}
+
+	switch {
+	case exitCode(err) == exitCancelled:
+		fmt.Fprintln(stderr, "Download interrupted. Run the same command again to resume it.")
+	case err != nil:
+		fmt.Fprintf(stderr, "Error: %s: %v\n", key, err)
+	default:
+		fmt.Fprintf(stdout, "Downloaded %s (%s) in %s: %s\n", key, ui.FormatBytes(result.Size), ui.FormatDuration(elapsed), result.Path)
+	}
+}
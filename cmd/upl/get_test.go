@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+func TestDownloadPath(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		key      string
+		dest     string
+		expected string
+		wantErr  bool
+	}{
+		{key: "reports/q3.pdf", expected: "q3.pdf"},
+		{key: "reports/q3.pdf", dest: "out.pdf", expected: "out.pdf"},
+		{key: "reports/q3.pdf", dest: dir, expected: filepath.Join(dir, "q3.pdf")},
+		{key: "reports/q3.pdf", dest: "new/", expected: filepath.Join("new", "q3.pdf")},
+		{key: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		result, err := downloadPath(tt.key, tt.dest)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("downloadPath(%q, %q) error = %v, wantErr %v", tt.key, tt.dest, err, tt.wantErr)
+		}
+		if result != tt.expected {
+			t.Errorf("downloadPath(%q, %q) = %q, want %q", tt.key, tt.dest, result, tt.expected)
+		}
+	}
+}
+
+func TestPlainDownload(t *testing.T) {
+	cfg := &config.Config{Endpoint: "file://" + t.TempDir(), Bucket: "files", PublicUrl: config.DefaultPublicUrl}
+	backend, err := openBackend(&globalOptions{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.Put(context.Background(), "reports/q3.pdf", strings.NewReader("quarterly"), 9, &s3storage.WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "q3.pdf")
+	if err := runPlainDownload(backend, "reports/q3.pdf", dest, &s3storage.DownloadOptions{}, formatPlain); err != nil {
+		t.Fatalf("runPlainDownload() error = %v", err)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "quarterly" {
+		t.Errorf("downloaded file = %q, %v, want %q", data, err, "quarterly")
+	}
+
+	err = runPlainDownload(backend, "reports/missing.pdf", filepath.Join(t.TempDir(), "missing.pdf"), &s3storage.DownloadOptions{}, formatPlain)
+	if exitCode(err) != exitNotFound {
+		t.Errorf("runPlainDownload() of a missing key = %v, want exit code %d", err, exitNotFound)
+	}
+}
+
+func TestPrintDownloadJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	result := &s3storage.DownloadResult{Key: "a.txt", Path: "out/a.txt", Size: 3, Checksum: "md5"}
+	printDownload(&stdout, &stderr, formatJSON, "a.txt", "out/a.txt", result, nil, 0)
+
+	var out downloadOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("output %q is not JSON: %v", stdout.String(), err)
+	}
+	if out.Path != "out/a.txt" || out.Size != 3 || out.Verified != "md5" || out.Error != "" {
+		t.Errorf("output = %+v", out)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want nothing", stderr.String())
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/clipboard"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/history"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+// defaultHistoryLimit is how many entries 'upl history' lists by default.
+const defaultHistoryLimit = 20
+
+// activeProfile returns the name of the profile commands run with.
+func activeProfile(globals *globalOptions) string {
+	file, _ := config.LoadFile()
+	return file.ActiveProfile(globals.profile)
+}
+
+// recordUploads adds finished uploads to the history. Objects that were
+// skipped because they already existed are not recorded. A history that
+// cannot be written only produces a warning, since the uploads succeeded.
+func recordUploads(globals *globalOptions, backend s3storage.Backend, expiry time.Duration, results ...*s3storage.UploadResult) {
+	profile := activeProfile(globals)
+	now := time.Now()
+
+	var entries []history.Entry
+	for _, result := range results {
+		if result == nil || result.Skipped {
+			continue
+		}
+		entry := history.Entry{
+			Time:     now,
+			Profile:  profile,
+			Endpoint: backend.Endpoint(),
+			Bucket:   result.Bucket,
+			Key:      result.Key,
+			Size:     result.Size,
+			Checksum: result.Checksum,
+			URL:      result.URL,
+		}
+		if expiry > 0 {
+			expiresAt := now.Add(expiry)
+			entry.ExpiresAt = &expiresAt
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := history.Add(entries...); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record upload history: %v\n", err)
+	}
+}
+
+type historyFlags struct {
+	since  time.Duration
+	bucket string
+	limit  int
+	all    bool
+	output string
+}
+
+func newHistoryCommand(globals *globalOptions) *command {
+	var opts historyFlags
+
+	fs := newFlagSet("history")
+	fs.durationVar(&opts.since, "since", "", "duration", "Only show uploads from the last duration, e.g. 7d")
+	fs.stringVar(&opts.bucket, "bucket", "b", "bucket", "Only show uploads to bucket")
+	fs.intVar(&opts.limit, "limit", "n", "n", "Number of entries to show (default 20)")
+	fs.boolVar(&opts.all, "all", "a", "Show all entries")
+	outputVar(fs, &opts.output)
+
+	cmd := &command{
+		name:    "history",
+		args:    "[search]",
+		summary: "List, search and re-share previous uploads",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) > 1 {
+			return newUsageError(cmd, "expected at most one search term")
+		}
+		if err := checkOutput(cmd, opts.output); err != nil {
+			return err
+		}
+		query := ""
+		if len(args) == 1 {
+			query = args[0]
+		}
+		return runHistory(globals, &opts, query)
+	}
+
+	cmd.add(
+		newHistoryLinkCommand(globals),
+		newHistoryCopyCommand(),
+		newHistoryRemoveCommand(globals),
+		&command{
+			name:    "clear",
+			summary: "Forget all recorded uploads (objects are kept)",
+			run: func(args []string) error {
+				if err := history.Clear(); err != nil {
+					return fmt.Errorf("error clearing history: %w", err)
+				}
+				fmt.Println("History cleared.")
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+func runHistory(globals *globalOptions, opts *historyFlags, query string) error {
+	entries, err := history.Load()
+	if err != nil {
+		return fmt.Errorf("error reading history: %w", err)
+	}
+
+	filter := history.Filter{
+		Query:   query,
+		Profile: globals.profile,
+		Bucket:  opts.bucket,
+		Limit:   opts.limit,
+	}
+	if opts.since > 0 {
+		filter.Since = time.Now().Add(-opts.since)
+	}
+	if filter.Limit == 0 && !opts.all {
+		filter.Limit = defaultHistoryLimit
+	}
+	entries = filter.Apply(entries)
+
+	if opts.output == outputJSON {
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		return printJSON(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No uploads found.")
+		return nil
+	}
+
+	now := time.Now()
+	fmt.Printf("%5s  %-16s %-10s %10s  %s\n", "ID", "UPLOADED", "PROFILE", "SIZE", "KEY")
+	for _, entry := range entries {
+		fmt.Printf("%5d  %-16s %-10s %10s  %s\n",
+			entry.ID,
+			entry.Time.Local().Format("2006-01-02 15:04"),
+			entry.Profile,
+			ui.FormatBytes(entry.Size),
+			entry.Key,
+		)
+
+		status := ""
+		switch {
+		case entry.Deleted:
+			status = " (deleted)"
+		case entry.Expired(now):
+			status = " (link expired)"
+		case entry.Presigned():
+			status = fmt.Sprintf(" (expires %s)", entry.ExpiresAt.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("%5s  %s%s\n", "", entry.URL, status)
+	}
+	return nil
+}
+
+func historyEntry(cmd *command, args []string) (*history.Entry, error) {
+	if len(args) != 1 {
+		return nil, newUsageError(cmd, "expected one history ID")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, newUsageError(cmd, "invalid history ID %q", args[0])
+	}
+	return history.Get(id)
+}
+
+// historyBackend loads the profile an entry was uploaded with and checks
+// that it still points at the same bucket.
+func historyBackend(globals *globalOptions, entry *history.Entry) (*config.Config, s3storage.Backend, error) {
+	cfg, err := config.LoadProfile(entry.Profile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading profile %q: %w", entry.Profile, err)
+	}
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if backend.Bucket() != entry.Bucket || backend.Endpoint() != entry.Endpoint {
+		return nil, nil, fmt.Errorf("profile %q no longer uses bucket %s at %s", entry.Profile, entry.Bucket, entry.Endpoint)
+	}
+	return cfg, backend, nil
+}
+
+func newHistoryLinkCommand(globals *globalOptions) *command {
+	var (
+		expires  time.Duration
+		copyLink bool
+	)
+
+	fs := newFlagSet("link")
+	fs.durationVar(&expires, "expires", "e", "duration", "Create a presigned link valid this long, e.g. 24h or 7d")
+	fs.boolVar(&copyLink, "copy", "c", "Copy the link to the clipboard")
+
+	cmd := &command{
+		name:    "link",
+		args:    "<id>",
+		summary: "Create a new link for an uploaded object",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		entry, err := historyEntry(cmd, args)
+		if err != nil {
+			return err
+		}
+		return runHistoryLink(globals, entry, expires, copyLink)
+	}
+	return cmd
+}
+
+// runHistoryLink creates a new link for an entry: a presigned link if the
+// original was one or --expires is given, the public URL otherwise.
+func runHistoryLink(globals *globalOptions, entry *history.Entry, expires time.Duration, copyLink bool) error {
+	if entry.Deleted {
+		return fmt.Errorf("%s was deleted", entry.Key)
+	}
+	cfg, backend, err := historyBackend(globals, entry)
+	if err != nil {
+		return err
+	}
+
+	link := backend.URL(entry.Key)
+	if expires > 0 || entry.Presigned() {
+		if expires == 0 {
+			if expires, err = profileLinkExpiry(cfg); err != nil {
+				return err
+			}
+		}
+		link, err = s3storage.Presign(context.Background(), backend, entry.Key, expires)
+		if err != nil {
+			return err
+		}
+		expiresAt := time.Now().Add(expires)
+		err = history.Update(entry.ID, func(e *history.Entry) {
+			e.URL = link
+			e.ExpiresAt = &expiresAt
+		})
+		if err != nil {
+			return fmt.Errorf("error updating history: %w", err)
+		}
+	}
+
+	fmt.Println(link)
+	if copyLink {
+		return clipboard.Write(link)
+	}
+	return nil
+}
+
+func newHistoryCopyCommand() *command {
+	cmd := &command{
+		name:    "copy",
+		args:    "<id>",
+		summary: "Copy the recorded link of an upload to the clipboard",
+	}
+	cmd.run = func(args []string) error {
+		entry, err := historyEntry(cmd, args)
+		if err != nil {
+			return err
+		}
+		if entry.Expired(time.Now()) {
+			return fmt.Errorf("the link expired on %s, create a new one with 'upl history link %d'",
+				entry.ExpiresAt.Local().Format("2006-01-02 15:04"), entry.ID)
+		}
+		if err := clipboard.Write(entry.URL); err != nil {
+			return err
+		}
+		fmt.Printf("Copied %s\n", entry.URL)
+		return nil
+	}
+	return cmd
+}
+
+func newHistoryRemoveCommand(globals *globalOptions) *command {
+	cmd := &command{
+		name:    "rm",
+		args:    "<id>",
+		summary: "Delete an uploaded object from the bucket",
+	}
+	cmd.run = func(args []string) error {
+		entry, err := historyEntry(cmd, args)
+		if err != nil {
+			return err
+		}
+		if entry.Deleted {
+			return fmt.Errorf("%s was already deleted", entry.Key)
+		}
+		_, backend, err := historyBackend(globals, entry)
+		if err != nil {
+			return err
+		}
+
+		if err := s3storage.Delete(context.Background(), backend, entry.Key); err != nil {
+			return err
+		}
+		if err := history.Update(entry.ID, func(e *history.Entry) { e.Deleted = true }); err != nil {
+			return fmt.Errorf("error updating history: %w", err)
+		}
+		fmt.Printf("Deleted %s\n", entry.Key)
+		return nil
+	}
+	return cmd
+}
@@ -4,128 +4,130 @@ import (
 	"fmt"
 	"os"
 
-	tea "github.com/charmbracelet/bubbletea"
-	"github.com/nizar0x1f/termup/pkg/config"
-	"github.com/nizar0x1f/termup/pkg/s3storage"
-	"github.com/nizar0x1f/termup/pkg/ui"
 	"github.com/nizar0x1f/termup/pkg/update"
 	"github.com/nizar0x1f/termup/pkg/version"
 )
 
-func main() {
-
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
-		showVersion()
-		return
-	}
-
-	if len(os.Args) > 1 && (os.Args[1] == "--help" || os.Args[1] == "-h" || os.Args[1] == "help") {
-		showHelp()
-		return
-	}
-
-	if len(os.Args) > 1 && (os.Args[1] == "--update" || os.Args[1] == "update") {
-		runUpdate()
-		return
-	}
-
-	if len(os.Args) > 1 && os.Args[1] == "relogin" {
-		runConfigUI()
-		return
-	}
-
-	if len(os.Args) < 2 {
-		showUsage()
-		os.Exit(1)
-	}
-
-	filePath := os.Args[1]
-
-	configExists, err := config.Exists()
-	if err != nil {
-		fmt.Printf("Error checking for config file: %v\n", err)
-		os.Exit(1)
-	}
-
-	var cfg *config.Config
-	if !configExists {
-
-		cfg = runConfigUI()
-		if cfg == nil {
-			os.Exit(1)
-		}
-	} else {
-		cfg, err = config.Load()
-		if err != nil {
-			fmt.Printf("Error loading config: %v\n", err)
-			os.Exit(1)
-		}
-	}
-
-	runUploadUI(cfg, filePath)
+type globalOptions struct {
+	profile  string
+	insecure bool
+	version  bool
 }
 
-func runConfigUI() *config.Config {
-	model := ui.NewConfigModel()
-	p := tea.NewProgram(model)
-
-	finalModel, err := p.Run()
-	if err != nil {
-		fmt.Printf("Error running config UI: %v\n", err)
-		return nil
-	}
-
-	configModel := finalModel.(ui.ConfigModel)
-	if !configModel.IsFinished() {
-		return nil
-	}
-
-	cfg := configModel.GetConfig()
-	if cfg == nil {
-		return nil
-	}
-
-	err = config.Save(cfg)
-	if err != nil {
-		fmt.Printf("Error saving config: %v\n", err)
-		return nil
-	}
-
-	return cfg
+func newGlobalFlags(opts *globalOptions, help *bool) *flagSet {
+	fs := newFlagSet("global")
+	fs.stringVar(&opts.profile, "profile", "p", "name", "Use the named configuration profile")
+	fs.boolVar(&opts.insecure, "insecure", "", "Skip TLS certificate verification")
+	fs.boolVar(help, "help", "h", "Print help information")
+	fs.boolVar(&opts.version, "version", "v", "Print version information")
+	return fs
 }
 
-func runUploadUI(cfg *config.Config, filePath string) {
+func main() {
+	var (
+		globals globalOptions
+		help    bool
+	)
 
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		fmt.Printf("Error accessing file: %v\n", err)
-		os.Exit(1)
-	}
+	root := newRootCommand(&globals)
+	exitOnError(root.execute(os.Args[1:], newGlobalFlags(&globals, &help), &help))
+}
 
-	model := ui.NewUploadModel(filePath, fileInfo.Size())
-	p := tea.NewProgram(model)
-
-	go func() {
-		url, err := s3storage.UploadWithProgress(cfg, filePath, func(uploaded int64) {
-			p.Send(ui.UploadProgressMsg(uploaded))
-		})
-		if err != nil {
-			p.Send(ui.UploadErrorMsg(err))
-		} else {
-			p.Send(ui.UploadCompleteMsg(url))
+func newRootCommand(globals *globalOptions) *command {
+	var (
+		uploadOpts  uploadFlags
+		checkUpdate bool
+	)
+
+	rootFlags := newFlagSet("upl")
+	uploadOpts.register(rootFlags)
+	rootFlags.boolVar(&checkUpdate, "update", "", "Update to the latest version")
+
+	root := &command{
+		name:    "upl",
+		args:    "<path>...",
+		summary: "TermUp - S3 Compatible Filesharing from Terminal",
+		flags:   rootFlags,
+		extraHelp: `EXAMPLES:
+    upl document.pdf
+    upl photo.jpg --key images/photo.jpg
+    upl *.png notes.txt --prefix shared/
+    upl ./site --exclude node_modules --exclude '*.map' --jobs 8
+    upl --profile minio build.tar.gz
+    upl secret.pdf --expires 24h
+    upl paste --prefix screenshots/
+    upl share reports/q3.pdf --expires 7d
+    upl get reports/q3.pdf ~/Downloads/
+    upl ls reports/ --output json
+    upl relogin
+
+ENVIRONMENT:
+    S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY, S3_BUCKET, S3_ENDPOINT, S3_PUBLIC_URL
+    override ~/.termup.json; no config file is needed if the first four are set
+    TERMUP_PROFILE selects the profile when --profile is not given
+
+SUPPORTED PROVIDERS:
+    Cloudflare R2, AWS S3, MinIO, DigitalOcean Spaces
+    and any other S3-compatible storage service
+`,
+	}
+	root.run = func(args []string) error {
+		switch {
+		case globals.version:
+			showVersion()
+			return nil
+		case checkUpdate:
+			return runUpdate()
+		case len(args) == 0 && !uploadOpts.clipboard:
+			showUsage()
+			return &exitError{code: exitUsage}
 		}
-	}()
-
-	finalModel, err := p.Run()
-	if err != nil {
-		fmt.Printf("Error running upload UI: %v\n", err)
-		os.Exit(1)
-	}
-
-	uploadModel := finalModel.(ui.UploadModel)
-	if uploadModel.GetError() != nil {
-		os.Exit(1)
-	}
+		return runUpload(root, globals, &uploadOpts, args)
+	}
+
+	root.add(
+		newUploadCommand(globals),
+		newPasteCommand(globals),
+		newConfigCommand(globals),
+		newReloginCommand(globals),
+		newProfileCommand(),
+		newResumeCommand(globals),
+		newShareCommand(globals),
+		newGetCommand(globals),
+		newListCommand(globals),
+		newInfoCommand(globals),
+		newCopyCommand(globals, false),
+		newCopyCommand(globals, true),
+		newRemoveCommand(globals),
+		newBrowseCommand(globals),
+		newHistoryCommand(globals),
+		newServeLocalCommand(globals),
+		&command{
+			name:    "update",
+			summary: "Update to the latest version",
+			run: func(args []string) error {
+				return runUpdate()
+			},
+		},
+		&command{
+			name:    "version",
+			summary: "Print version information",
+			run: func(args []string) error {
+				showVersion()
+				return nil
+			},
+		},
+		&command{
+			name:    "help",
+			args:    "[command]",
+			summary: "Print help for a command",
+			run: func(args []string) error {
+				return showHelp(root, args)
+			},
+		},
+	)
+
+	return root
 }
 
 func showVersion() {
@@ -140,52 +142,36 @@ func showVersion() {
 	}
 }
 
-func showHelp() {
-	fmt.Println("TermUp - S3 Compatible Filesharing from Terminal")
-	fmt.Println()
-	fmt.Println("USAGE:")
-	fmt.Println("    upl [OPTIONS] <file-path>")
-	fmt.Println("    upl [COMMAND]")
-	fmt.Println()
-	fmt.Println("ARGS:")
-	fmt.Println("    <file-path>    Path to the file to upload")
-	fmt.Println()
-	fmt.Println("OPTIONS:")
-	fmt.Println("    -h, --help       Print help information")
-	fmt.Println("    -v, --version    Print version information")
-	fmt.Println("        --update     Update to the latest version")
-	fmt.Println()
-	fmt.Println("COMMANDS:")
-	fmt.Println("    relogin          Reconfigure S3 credentials")
-	fmt.Println("    update           Update to the latest version")
-	fmt.Println("    help             Print this help message")
-	fmt.Println()
-	fmt.Println("EXAMPLES:")
-	fmt.Println("    upl document.pdf")
-	fmt.Println("    upl photo.jpg")
-	fmt.Println("    upl relogin")
-	fmt.Println()
-	fmt.Println("SUPPORTED PROVIDERS:")
-	fmt.Println("    Cloudflare R2, AWS S3, MinIO, DigitalOcean Spaces")
-	fmt.Println("    and any other S3-compatible storage service")
+func showHelp(root *command, args []string) error {
+	cmd := root
+	for _, name := range args {
+		sub := cmd.find(name)
+		if sub == nil {
+			return newUsageError(root, "unknown command %q", name)
+		}
+		cmd = sub
+	}
+
+	cmd.printHelp(os.Stdout)
+	return nil
 }
 
 func showUsage() {
-	fmt.Println("Usage: upl <file-path>")
-	fmt.Println("       upl relogin")
+	fmt.Println("Usage: upl <path>...")
+	fmt.Println("       upl <command> [options]")
 	fmt.Println("       upl --help")
 	fmt.Println("       upl --version")
 	fmt.Println("       upl --update")
 }
 
-func runUpdate() {
+func runUpdate() error {
 	fmt.Println("Checking for updates...")
 
 	canUpdate, message := update.CanSelfUpdate()
 	if !canUpdate {
 		fmt.Printf("Self-update not available: %s\n", message)
 		fmt.Printf("\nPlease update manually using: %s\n", update.GetUpdateCommand())
-		return
+		return nil
 	}
 
 	updateInfo, err := update.CheckForUpdates()
@@ -193,12 +179,12 @@ func runUpdate() {
 		fmt.Printf("Error checking for updates: %v\n", err)
 		fmt.Println("\nYou can manually update using:")
 		fmt.Printf("  %s\n", update.GetUpdateCommand())
-		os.Exit(1)
+		return &exitError{code: exitFailure}
 	}
 
 	if !updateInfo.Available {
 		fmt.Printf("✅ You're already running the latest version (%s)\n", updateInfo.CurrentVersion)
-		return
+		return nil
 	}
 
 	fmt.Printf("🚀 Update available!\n")
@@ -214,16 +200,17 @@ func runUpdate() {
 		fmt.Println("Update cancelled.")
 		fmt.Printf("\nTo update later, run: upl --update\n")
 		fmt.Printf("Or manually: %s\n", update.GetUpdateCommand())
-		return
+		return nil
 	}
 
 	fmt.Println("\n⬇️  Updating TermUp...")
 	if err := update.PerformSelfUpdate(); err != nil {
 		fmt.Printf("❌ Update failed: %v\n", err)
 		fmt.Printf("\nPlease update manually using: %s\n", update.GetUpdateCommand())
-		os.Exit(1)
+		return &exitError{code: exitFailure}
 	}
 
 	fmt.Println("✅ Update completed successfully!")
 	fmt.Println("The new version will be available the next time you run 'upl'")
+	return nil
 }
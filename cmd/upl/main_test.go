@@ -36,7 +36,13 @@ func TestMainFunction(t *testing.T) {
 			name:           "no arguments",
 			args:           []string{},
 			expectError:    true,
-			expectedOutput: "Usage: upl <file-path>",
+			expectedOutput: "Usage: upl <path>...",
+		},
+		{
+			name:           "unknown flag",
+			args:           []string{"--foo"},
+			expectError:    true,
+			expectedOutput: "unknown flag: --foo",
 		},
 		{
 			name:           "non-existent file",
@@ -113,10 +119,14 @@ func TestMainWithValidFile(t *testing.T) {
 	cmd = exec.Command(binaryPath, tmpFile.Name())
 	cmd.Env = append(os.Environ(), "HOME="+tmpHome)
 
-	output, _ := cmd.CombinedOutput()
+	output, err := cmd.CombinedOutput()
 	outputStr := string(output)
 
-	if !strings.Contains(outputStr, "Enter Access Key ID") && !strings.Contains(outputStr, "could not open a new TTY") {
-		t.Errorf("Expected to prompt for config or get TTY error, got: %s", outputStr)
+	// Without a terminal there is nobody to answer the config prompt.
+	if !strings.Contains(outputStr, "no configuration found") {
+		t.Errorf("Expected a missing config error, got: %s", outputStr)
+	}
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != exitConfig {
+		t.Errorf("Expected exit code %d, got: %v", exitConfig, err)
 	}
 }
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// objectJSON is how objects are printed with --output json.
+type objectJSON struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	LastModified time.Time         `json:"last_modified"`
+	ETag         string            `json:"etag,omitempty"`
+	ContentType  string            `json:"content_type,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	URL          string            `json:"url"`
+}
+
+func newObjectJSON(backend s3storage.Backend, info *s3storage.ObjectInfo) objectJSON {
+	return objectJSON{
+		Key:          info.Key,
+		Size:         info.Size,
+		LastModified: info.LastModified,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		Metadata:     info.Metadata,
+		URL:          backend.URL(info.Key),
+	}
+}
+
+func outputVar(fs *flagSet, p *string) {
+	fs.stringVar(p, "output", "o", "format", "Output format: table or json")
+}
+
+func checkOutput(cmd *command, format string) error {
+	switch format {
+	case "", outputTable, outputJSON:
+		return nil
+	}
+	return newUsageError(cmd, "unknown output format %q (expected %s or %s)", format, outputTable, outputJSON)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func newListCommand(globals *globalOptions) *command {
+	var (
+		recursive bool
+		output    string
+	)
+
+	fs := newFlagSet("ls")
+	fs.boolVar(&recursive, "recursive", "r", "List every key under the prefix instead of grouping by folder")
+	outputVar(fs, &output)
+
+	cmd := &command{
+		name:    "ls",
+		aliases: []string{"list"},
+		args:    "[prefix]",
+		summary: "List objects in the bucket",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) > 1 {
+			return newUsageError(cmd, "expected at most one prefix")
+		}
+		if err := checkOutput(cmd, output); err != nil {
+			return err
+		}
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		return runList(globals, prefix, recursive, output)
+	}
+	return cmd
+}
+
+func runList(globals *globalOptions, prefix string, recursive bool, output string) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	listing, err := s3storage.List(context.Background(), backend, &s3storage.ListOptions{
+		Prefix:    prefix,
+		Recursive: recursive,
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(listing.Prefixes)
+	sort.Slice(listing.Objects, func(i, j int) bool {
+		return listing.Objects[i].Key < listing.Objects[j].Key
+	})
+
+	if output == outputJSON {
+		objects := make([]objectJSON, 0, len(listing.Objects))
+		for i := range listing.Objects {
+			objects = append(objects, newObjectJSON(backend, &listing.Objects[i]))
+		}
+		return printJSON(struct {
+			Prefixes []string     `json:"prefixes"`
+			Objects  []objectJSON `json:"objects"`
+		}{append([]string{}, listing.Prefixes...), objects})
+	}
+
+	if len(listing.Prefixes) == 0 && len(listing.Objects) == 0 {
+		fmt.Println("No objects found.")
+		return nil
+	}
+	fmt.Printf("%-16s %10s  %s\n", "LAST MODIFIED", "SIZE", "KEY")
+	for _, p := range listing.Prefixes {
+		fmt.Printf("%-16s %10s  %s\n", "", "PRE", p)
+	}
+	var total int64
+	for _, object := range listing.Objects {
+		total += object.Size
+		fmt.Printf("%-16s %10s  %s  %s\n",
+			object.LastModified.Local().Format("2006-01-02 15:04"),
+			ui.FormatBytes(object.Size),
+			object.Key,
+			backend.URL(object.Key),
+		)
+	}
+	fmt.Printf("\n%d objects, %s\n", len(listing.Objects), ui.FormatBytes(total))
+	return nil
+}
+
+func newInfoCommand(globals *globalOptions) *command {
+	var output string
+
+	fs := newFlagSet("info")
+	outputVar(fs, &output)
+
+	cmd := &command{
+		name:    "info",
+		aliases: []string{"stat"},
+		args:    "<key|url>",
+		summary: "Show an object's size, checksum, headers and metadata",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return newUsageError(cmd, "expected one key")
+		}
+		if err := checkOutput(cmd, output); err != nil {
+			return err
+		}
+		return runInfo(globals, args[0], output)
+	}
+	return cmd
+}
+
+func runInfo(globals *globalOptions, arg, output string) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	key, err := objectKey(cfg, arg)
+	if err != nil {
+		return err
+	}
+
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	info, err := s3storage.Stat(context.Background(), backend, key)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		return printJSON(newObjectJSON(backend, info))
+	}
+
+	fmt.Printf("%-15s %s\n", "Key:", info.Key)
+	fmt.Printf("%-15s %s (%d bytes)\n", "Size:", ui.FormatBytes(info.Size), info.Size)
+	fmt.Printf("%-15s %s\n", "Last modified:", info.LastModified.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("%-15s %s\n", "ETag:", info.ETag)
+	fmt.Printf("%-15s %s\n", "Content-Type:", info.ContentType)
+	fmt.Printf("%-15s %s\n", "URL:", backend.URL(info.Key))
+	if len(info.Metadata) > 0 {
+		fmt.Println("Metadata:")
+		names := make([]string, 0, len(info.Metadata))
+		for name := range info.Metadata {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %s: %s\n", name, info.Metadata[name])
+		}
+	}
+	return nil
+}
+
+func newRemoveCommand(globals *globalOptions) *command {
+	var (
+		recursive bool
+		yes       bool
+		output    string
+	)
+
+	fs := newFlagSet("rm")
+	fs.boolVar(&recursive, "recursive", "r", "Delete every object under the given folders")
+	fs.boolVar(&yes, "yes", "y", "Do not ask before deleting the objects found by --recursive")
+	outputVar(fs, &output)
+
+	cmd := &command{
+		name:    "rm",
+		aliases: []string{"delete"},
+		args:    "<key|url>...",
+		summary: "Delete objects from the bucket",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			return newUsageError(cmd, "expected at least one key")
+		}
+		if err := checkOutput(cmd, output); err != nil {
+			return err
+		}
+		return runRemove(globals, args, recursive, yes, output)
+	}
+	return cmd
+}
+
+func runRemove(globals *globalOptions, args []string, recursive, yes bool, output string) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	keys, err := removeTargets(ctx, backend, cfg, args, recursive)
+	if err != nil {
+		return err
+	}
+	if recursive && len(keys) > 0 && !yes {
+		if output == outputJSON || !term.IsTerminal(os.Stdin.Fd()) {
+			return fmt.Errorf("refusing to delete %d objects without confirmation, use --yes", len(keys))
+		}
+		if !confirm(os.Stdin, os.Stderr, keys, fmt.Sprintf("Delete these %d objects?", len(keys))) {
+			fmt.Println("Nothing was deleted.")
+			return nil
+		}
+	}
+
+	deleted := []string{}
+	var errs []error
+	for _, key := range keys {
+		if err := s3storage.Delete(ctx, backend, key); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			errs = append(errs, err)
+			continue
+		}
+		deleted = append(deleted, key)
+		if output != outputJSON {
+			fmt.Printf("Deleted %s\n", key)
+		}
+	}
+
+	if output == outputJSON {
+		if err := printJSON(struct {
+			Deleted []string `json:"deleted"`
+		}{deleted}); err != nil {
+			return err
+		}
+	} else if recursive && len(keys) == 0 {
+		fmt.Println("No objects found.")
+	}
+	return failureExit(errs)
+}
+
+// removeTargets returns the keys upl rm deletes for args. With recursive,
+// each argument is a folder and the keys are the objects under it, so
+// "reports" does not match "reports-old.pdf". An argument that names the
+// whole bucket is rejected.
+func removeTargets(ctx context.Context, backend s3storage.Backend, cfg *config.Config, args []string, recursive bool) ([]string, error) {
+	var keys []string
+	for _, arg := range args {
+		key, err := objectKey(cfg, arg)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Trim(key, "/") == "" {
+			return nil, fmt.Errorf("refusing to delete %q, it names the whole bucket", arg)
+		}
+		if !recursive {
+			keys = append(keys, key)
+			continue
+		}
+
+		if !strings.HasSuffix(key, "/") {
+			key += "/"
+		}
+		listing, err := s3storage.List(ctx, backend, &s3storage.ListOptions{
+			Prefix:    key,
+			Recursive: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range listing.Objects {
+			keys = append(keys, object.Key)
+		}
+	}
+	return keys, nil
+}
+
+func newCopyCommand(globals *globalOptions, move bool) *command {
+	var (
+		force  bool
+		output string
+	)
+
+	name, summary := "cp", "Copy an object inside the bucket without downloading it"
+	if move {
+		name, summary = "mv", "Move or rename an object inside the bucket"
+	}
+
+	fs := newFlagSet(name)
+	fs.boolVar(&force, "force", "f", "Overwrite the destination if it exists")
+	outputVar(fs, &output)
+
+	cmd := &command{
+		name:    name,
+		args:    "<src> <dst>",
+		summary: summary,
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) != 2 {
+			return newUsageError(cmd, "expected a source and a destination key")
+		}
+		if err := checkOutput(cmd, output); err != nil {
+			return err
+		}
+		return runCopy(globals, args[0], args[1], move, force, output)
+	}
+	return cmd
+}
+
+func runCopy(globals *globalOptions, srcArg, dstArg string, move, force bool, output string) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	src, err := objectKey(cfg, srcArg)
+	if err != nil {
+		return err
+	}
+	dst, err := objectKey(cfg, dstArg)
+	if err != nil {
+		return err
+	}
+	dst = copyDestination(src, dst)
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	opts := &s3storage.CopyOptions{Overwrite: force}
+
+	copyFn, verb := s3storage.Copy, "Copied"
+	if move {
+		copyFn, verb = s3storage.Move, "Moved"
+	}
+	info, err := copyFn(context.Background(), backend, src, dst, opts)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		return printJSON(newObjectJSON(backend, info))
+	}
+	fmt.Printf("%s %s to %s\n", verb, src, dst)
+	fmt.Printf("URL: %s\n", backend.URL(dst))
+	return nil
+}
+
+// copyDestination puts src under dst when dst names a folder ("dir/").
+func copyDestination(src, dst string) string {
+	if dst == "" || strings.HasSuffix(dst, "/") {
+		return dst + path.Base(src)
+	}
+	return dst
+}
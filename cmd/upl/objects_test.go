@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+func TestCopyDestination(t *testing.T) {
+	tests := []struct{ src, dst, expected string }{
+		{"a/photo.png", "b/pic.png", "b/pic.png"},
+		{"a/photo.png", "archive/", "archive/photo.png"},
+		{"a/photo.png", "", "photo.png"},
+	}
+	for _, tt := range tests {
+		if got := copyDestination(tt.src, tt.dst); got != tt.expected {
+			t.Errorf("copyDestination(%q, %q) = %q, want %q", tt.src, tt.dst, got, tt.expected)
+		}
+	}
+}
+
+func TestRemoveTargets(t *testing.T) {
+	cfg := &config.Config{Endpoint: "file://" + t.TempDir(), Bucket: "files", PublicUrl: config.DefaultPublicUrl}
+	backend, err := openBackend(&globalOptions{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, key := range []string{"reports/q3.pdf", "reports/2025/q4.pdf", "reports-old.pdf", "notes.txt"} {
+		if _, err := backend.Put(ctx, key, strings.NewReader(key), int64(len(key)), &s3storage.WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		args      []string
+		recursive bool
+		expected  []string
+		wantErr   bool
+	}{
+		{[]string{"notes.txt"}, false, []string{"notes.txt"}, false},
+		{[]string{"reports"}, true, []string{"reports/2025/q4.pdf", "reports/q3.pdf"}, false},
+		{[]string{"/reports/"}, true, []string{"reports/2025/q4.pdf", "reports/q3.pdf"}, false},
+		{[]string{"report"}, true, nil, false},
+		{[]string{"/"}, true, nil, true},
+		{[]string{""}, true, nil, true},
+		{[]string{"/"}, false, nil, true},
+		{[]string{"notes.txt", ""}, false, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := removeTargets(ctx, backend, cfg, tt.args, tt.recursive)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("removeTargets(%q, %v) error = %v, wantErr %v", tt.args, tt.recursive, err, tt.wantErr)
+			continue
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("removeTargets(%q, %v) = %q, want %q", tt.args, tt.recursive, got, tt.expected)
+		}
+	}
+}
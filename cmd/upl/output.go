@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/nizar0x1f/termup/pkg/batch"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+// Output formats of uploads without the progress UI.
+const (
+	formatJSON  = "json"
+	formatPlain = "plain"
+	formatURL   = "url"
+)
+
+// uploadFormat returns the output format for uploads: --output, or plain
+// when stdout is not a terminal. An empty format means the progress UI.
+func uploadFormat(cmd *command, output string) (string, error) {
+	switch output {
+	case formatJSON, formatPlain, formatURL:
+		return output, nil
+	case "":
+		if !interactive() {
+			return formatPlain, nil
+		}
+		return "", nil
+	}
+	return "", newUsageError(cmd, "unknown output format %q (expected json, plain or url)", output)
+}
+
+// interactive reports whether stdout is a terminal the UI can draw on.
+func interactive() bool {
+	return term.IsTerminal(os.Stdout.Fd())
+}
+
+// uploadOutput is one line of --output json: a JSON object per file,
+// written when the file has finished.
+type uploadOutput struct {
+	URL      string `json:"url"`
+	Key      string `json:"key"`
+	Bucket   string `json:"bucket"`
+	Size     int64  `json:"size"`
+	ETag     string `json:"etag"`
+	Checksum string `json:"checksum"`
+
+	// Duration is the upload time in seconds.
+	Duration float64 `json:"duration"`
+
+	File    string `json:"file,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	// Retries lists the requests that needed more than one attempt; part
+	// 0 is an upload sent in a single request.
+	Retries []retryOutput `json:"retries,omitempty"`
+}
+
+type retryOutput struct {
+	Part     int32 `json:"part"`
+	Attempts int   `json:"attempts"`
+}
+
+// uploadPrinter writes the result of each upload in one of the output
+// formats. Results go to stdout; in plain and url format errors go to
+// stderr.
+type uploadPrinter struct {
+	format string
+	mu     sync.Mutex
+}
+
+func (p *uploadPrinter) print(file string, result *s3storage.UploadResult, err error, elapsed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.format == formatJSON {
+		out := uploadOutput{File: file, Duration: elapsed.Seconds()}
+		if err != nil {
+			out.Error = err.Error()
+		} else {
+			out.URL = result.URL
+			out.Key = result.Key
+			out.Bucket = result.Bucket
+			out.Size = result.Size
+			out.ETag = result.ETag
+			out.Checksum = result.Checksum
+			out.Skipped = result.Skipped
+			for _, retry := range result.Retries {
+				out.Retries = append(out.Retries, retryOutput{Part: retry.Part, Attempts: retry.Attempts})
+			}
+		}
+		line, _ := json.Marshal(out)
+		fmt.Println(string(line))
+		return
+	}
+
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", file, err)
+	case p.format == formatURL:
+		fmt.Println(result.URL)
+	case result.Skipped:
+		fmt.Printf("Already uploaded %s: %s\n", file, result.URL)
+	default:
+		fmt.Printf("Uploaded %s (%s) in %s: %s\n", file, ui.FormatBytes(result.Size), ui.FormatDuration(elapsed), result.URL)
+	}
+	if err == nil && len(result.Retries) > 0 {
+		fmt.Fprintf(os.Stderr, "Retried %s: %s\n", file, s3storage.FormatRetries(result.Retries))
+	}
+}
+
+// runPlainUpload uploads files without the UI, printing each result as it
+// finishes. The exit code is that of the failures if they are all of one
+// class.
+func runPlainUpload(globals *globalOptions, cfg *config.Config, backend s3storage.Backend, files []batch.File, opts *s3storage.UploadOptions, headers config.Headers, jobs int, format string) error {
+	printer := &uploadPrinter{format: format}
+
+	runner := &batch.Runner{
+		Workers: jobs,
+		Upload: func(ctx context.Context, file batch.File, progress func(int64)) (*s3storage.UploadResult, error) {
+			fileOpts := *opts
+			fileOpts.Key = file.Key
+			setHeaders(&fileOpts, cfg, file.Key, headers)
+			return s3storage.UploadFile(ctx, backend, file.Path, &fileOpts, progress)
+		},
+		OnDone: func(index int, result batch.Result) {
+			printer.print(result.File.Path, result.Upload, result.Err, result.Duration)
+		},
+	}
+
+	// Ctrl+C cancels the uploads, so that they stop their requests and keep
+	// their finished parts for resuming before upl exits.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		for i := range files {
+			runner.Cancel(i)
+		}
+	}()
+
+	results := runner.Run(files)
+	stop()
+
+	var (
+		uploads []*s3storage.UploadResult
+		errs    []error
+	)
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		} else {
+			uploads = append(uploads, result.Upload)
+		}
+	}
+	recordUploads(globals, backend, opts.PresignExpiry, uploads...)
+	return failureExit(errs)
+}
+
+// runPlainStreamUpload uploads stdin without the UI.
+func runPlainStreamUpload(globals *globalOptions, backend s3storage.Backend, opts *s3storage.UploadOptions, format string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	result, err := s3storage.UploadStream(ctx, backend, os.Stdin, opts, func(int64) {})
+	(&uploadPrinter{format: format}).print(stdinPath, result, err, time.Since(start))
+	if err != nil {
+		return &exitError{code: exitCode(err)}
+	}
+	recordUploads(globals, backend, opts.PresignExpiry, result)
+	return nil
+}
+
+// failureExit returns the exit error for failed uploads that have already
+// been reported.
+func failureExit(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	code := exitCode(errs[0])
+	for _, err := range errs[1:] {
+		if exitCode(err) != code {
+			return &exitError{code: exitFailure}
+		}
+	}
+	return &exitError{code: code}
+}
+
+// nonInteractiveCollision turns the ask policy into fail when there is no
+// UI to ask in.
+func nonInteractiveCollision(policy s3storage.CollisionPolicy) s3storage.CollisionPolicy {
+	if policy == s3storage.CollisionAsk {
+		return s3storage.CollisionFail
+	}
+	return policy
+}
+
+var errNoConfig = errors.New("no configuration found: run 'upl config edit' in a terminal or set S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY, S3_BUCKET and S3_ENDPOINT")
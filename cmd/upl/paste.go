@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/nizar0x1f/termup/pkg/batch"
+	"github.com/nizar0x1f/termup/pkg/clipboard"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/keytemplate"
+)
+
+// defaultPasteName names clipboard uploads unless the profile's paste_name
+// or --name says otherwise.
+const defaultPasteName = "clipboard-{{.Date}}-{{.Time}}.{{.Ext}}"
+
+func newPasteCommand(globals *globalOptions) *command {
+	var opts uploadFlags
+
+	fs := newFlagSet("paste")
+	opts.register(fs)
+
+	cmd := &command{
+		name:    "paste",
+		summary: "Upload the clipboard contents: images as PNG, text as .txt",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return newUsageError(cmd, "paste takes no arguments")
+		}
+		opts.clipboard = true
+		return runUpload(cmd, globals, &opts, args)
+	}
+	return cmd
+}
+
+// runPaste reads the clipboard into a temporary file and uploads it like a
+// file given on the command line.
+func runPaste(cmd *command, globals *globalOptions, opts *uploadFlags, reader clipboard.Reader) error {
+	headers, err := opts.headers()
+	if err != nil {
+		return newUsageError(cmd, "%v", err)
+	}
+	format, err := uploadFormat(cmd, opts.output)
+	if err != nil {
+		return err
+	}
+
+	content, err := reader.Read()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "termup-paste-")
+	if err != nil {
+		return fmt.Errorf("error saving clipboard contents: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file, err := pasteFile(dir, content, opts, cfg)
+	if err != nil {
+		return err
+	}
+	return uploadFiles(globals, cfg, opts, headers, format, []batch.File{*file}, true)
+}
+
+// pasteFile writes clipboard contents to dir under the name from --name or
+// the paste name template.
+func pasteFile(dir string, content *clipboard.Content, opts *uploadFlags, cfg *config.Config) (*batch.File, error) {
+	tmp := filepath.Join(dir, "clipboard."+content.Ext)
+	if err := os.WriteFile(tmp, content.Data, 0o600); err != nil {
+		return nil, fmt.Errorf("error saving clipboard contents: %w", err)
+	}
+
+	name := opts.name
+	if name == "" {
+		raw := cfg.PasteName
+		if raw == "" {
+			raw = defaultPasteName
+		}
+		tmpl, err := keytemplate.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		if name, err = tmpl.Render(tmp, path.Base(tmp)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The upload UI shows the local file name, so give it the final one.
+	local := filepath.Join(dir, path.Base(name))
+	if err := os.Rename(tmp, local); err != nil {
+		return nil, fmt.Errorf("error saving clipboard contents: %w", err)
+	}
+
+	return &batch.File{
+		Path: local,
+		Key:  batch.JoinKey(opts.prefix, name),
+		Size: int64(len(content.Data)),
+		Rel:  name,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/clipboard"
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+func TestPasteFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   clipboard.Content
+		opts      uploadFlags
+		pasteName string
+		expected  string
+	}{
+		{"image", clipboard.Content{Data: []byte("\x89PNG"), Ext: "png"}, uploadFlags{}, "",
+			`^clipboard-\d{4}-\d{2}-\d{2}-\d{6}\.png$`},
+		{"text with prefix", clipboard.Content{Data: []byte("hello"), Ext: "txt"}, uploadFlags{prefix: "notes/"}, "",
+			`^notes/clipboard-\d{4}-\d{2}-\d{2}-\d{6}\.txt$`},
+		{"profile template", clipboard.Content{Data: []byte("hello"), Ext: "txt"}, uploadFlags{}, "snippets/{{.Hash8}}.{{.Ext}}",
+			`^snippets/2cf24dba\.txt$`},
+		{"name flag", clipboard.Content{Data: []byte("hello"), Ext: "txt"}, uploadFlags{name: "todo.md"}, "snippets/{{.Hash8}}.{{.Ext}}",
+			`^todo\.md$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file, err := pasteFile(dir, &tt.content, &tt.opts, &config.Config{PasteName: tt.pasteName})
+			if err != nil {
+				t.Fatalf("pasteFile() error = %v", err)
+			}
+			if !regexp.MustCompile(tt.expected).MatchString(file.Key) {
+				t.Errorf("key = %q, want match for %s", file.Key, tt.expected)
+			}
+			if file.Path != filepath.Join(dir, filepath.Base(file.Key)) {
+				t.Errorf("path = %q, want the key's name in %s", file.Path, dir)
+			}
+			data, err := os.ReadFile(file.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != string(tt.content.Data) || file.Size != int64(len(data)) {
+				t.Errorf("file holds %q (size %d), want %q", data, file.Size, tt.content.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+// profileName resolves the profile that commands act on when profile is
+// empty.
+func profileName(profile string) string {
+	f, err := config.LoadFile()
+	if err != nil {
+		f = nil
+	}
+	return f.ActiveProfile(profile)
+}
+
+func newProfileCommand() *command {
+	cmd := &command{
+		name:    "profile",
+		summary: "Manage configuration profiles",
+	}
+
+	list := &command{
+		name:    "list",
+		aliases: []string{"ls"},
+		summary: "List configuration profiles",
+		run: func(args []string) error {
+			return listProfiles()
+		},
+	}
+
+	add := &command{
+		name:    "add",
+		args:    "<name>",
+		summary: "Create a profile",
+	}
+	add.run = func(args []string) error {
+		if len(args) != 1 {
+			return newUsageError(add, "expected a profile name")
+		}
+		return addProfile(args[0])
+	}
+
+	remove := &command{
+		name:    "remove",
+		aliases: []string{"rm"},
+		args:    "<name>",
+		summary: "Delete a profile",
+	}
+	remove.run = func(args []string) error {
+		if len(args) != 1 {
+			return newUsageError(remove, "expected a profile name")
+		}
+		if err := updateProfiles(func(f *config.File) error { return f.RemoveProfile(args[0]) }); err != nil {
+			return err
+		}
+		fmt.Printf("Removed profile %s\n", args[0])
+		return nil
+	}
+
+	use := &command{
+		name:    "use",
+		args:    "<name>",
+		summary: "Set the default profile",
+	}
+	use.run = func(args []string) error {
+		if len(args) != 1 {
+			return newUsageError(use, "expected a profile name")
+		}
+		if err := updateProfiles(func(f *config.File) error { return f.UseProfile(args[0]) }); err != nil {
+			return err
+		}
+		fmt.Printf("Default profile is now %s\n", args[0])
+		return nil
+	}
+
+	return cmd.add(list, add, remove, use)
+}
+
+func listProfiles() error {
+	f, err := config.LoadFile()
+	if os.IsNotExist(err) {
+		fmt.Println("No profiles configured. Add one with 'upl profile add <name>'.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	active := f.ActiveProfile("")
+	for _, name := range f.ProfileNames() {
+		marker := " "
+		if name == active {
+			marker = "*"
+		}
+		cfg := f.Profiles[name]
+		fmt.Printf("%s %-16s %-24s %s\n", marker, name, cfg.Bucket, cfg.Endpoint)
+	}
+	return nil
+}
+
+func addProfile(name string) error {
+	if f, err := config.LoadFile(); err == nil {
+		if _, err := f.Profile(name); err == nil {
+			return fmt.Errorf("profile %s already exists, edit it with 'upl relogin --profile %s'", name, name)
+		}
+	}
+
+	if runConfigUI(name, nil) == nil {
+		return &exitError{code: exitCancelled}
+	}
+	return nil
+}
+
+func updateProfiles(update func(*config.File) error) error {
+	f, err := config.LoadFile()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	if err := update(f); err != nil {
+		return err
+	}
+
+	if err := config.SaveFile(f); err != nil {
+		return fmt.Errorf("error saving config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+func newResumeCommand(globals *globalOptions) *command {
+	var opts uploadFlags
+
+	resumeFlags := newFlagSet("resume")
+	opts.registerTransfer(resumeFlags)
+
+	cmd := &command{
+		name:    "resume",
+		summary: "Resume interrupted uploads",
+		flags:   resumeFlags,
+		run: func(args []string) error {
+			return runResume(globals, &opts)
+		},
+		extraHelp: `A multipart upload that fails or is cancelled after finishing some parts
+stays on the bucket, where its parts are billed as storage, until it is
+resumed or discarded. Every upload and 'upl resume' first abort uploads whose
+file changed or that were not touched for 7 days. An upload without finished
+parts is aborted right away.
+
+Resumed uploads use the profile's speed limit and retry settings, or the
+flags above.
+
+EXAMPLES:
+    upl resume
+    upl resume --limit 5MB/s
+    upl resume discard backup.tar.gz
+    upl resume clean
+`,
+	}
+
+	cmd.add(&command{
+		name:    "discard",
+		args:    "<path>...",
+		summary: "Abort interrupted uploads of files and forget their progress",
+		run: func(args []string) error {
+			if len(args) == 0 {
+				return newUsageError(cmd, "expected at least one file path")
+			}
+			return runResumeDiscard(globals, args)
+		},
+	})
+	var yes bool
+	fs := newFlagSet("clean")
+	fs.boolVar(&yes, "yes", "y", "Do not ask before aborting the uploads")
+	cmd.add(&command{
+		name:    "clean",
+		summary: "Abort leftover multipart uploads on the bucket",
+		flags:   fs,
+		run: func(args []string) error {
+			return runResumeClean(globals, yes)
+		},
+	})
+	return cmd
+}
+
+func runResume(globals *globalOptions, opts *uploadFlags) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s3storage.CleanStaleUploads(context.Background(), backend, s3storage.DefaultResumeMaxAge)
+	if err != nil {
+		return fmt.Errorf("error cleaning up stale uploads: %w", err)
+	}
+	for _, state := range removed {
+		fmt.Printf("Discarded stale upload: %s\n", state.FilePath)
+	}
+
+	pending, err := s3storage.PendingUploads(backend)
+	if err != nil {
+		return fmt.Errorf("error reading pending uploads: %w", err)
+	}
+	if len(pending) == 0 {
+		fmt.Println("No uploads to resume.")
+		return nil
+	}
+
+	autoCopy, err := copyURL(opts, cfg)
+	if err != nil {
+		return err
+	}
+	for _, state := range pending {
+		uploadOpts, err := opts.options(cfg)
+		if err != nil {
+			return err
+		}
+		uploadOpts.Key = state.Key
+		uploadOpts.PartSize = state.PartSize
+		if err := runUploadUI(globals, backend, state.FilePath, uploadOpts, autoCopy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runResumeDiscard(globals *globalOptions, paths []string) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	pending, err := s3storage.PendingUploads(backend)
+	if err != nil {
+		return fmt.Errorf("error reading pending uploads: %w", err)
+	}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("error resolving %s: %w", path, err)
+		}
+		found := false
+		for _, state := range pending {
+			if state.FilePath == abs {
+				state.Discard(context.Background(), backend)
+				fmt.Printf("Discarded upload of %s to %s\n", path, state.Key)
+				found = true
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "Error: no interrupted upload of %s\n", path)
+			return &exitError{code: exitNotFound}
+		}
+	}
+	return nil
+}
+
+func runResumeClean(globals *globalOptions, yes bool) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	leftover, err := s3storage.LeftoverUploads(ctx, backend, 24*time.Hour)
+	if err != nil {
+		return fmt.Errorf("error listing multipart uploads: %w", err)
+	}
+	if len(leftover) == 0 {
+		fmt.Println("No leftover multipart uploads.")
+		return nil
+	}
+
+	// Uploads without local state may have been started by other machines
+	// or tools sharing the bucket, so they are only aborted when confirmed.
+	if !yes {
+		if !term.IsTerminal(os.Stdin.Fd()) {
+			return fmt.Errorf("refusing to abort %d multipart uploads without confirmation, use --yes", len(leftover))
+		}
+		items := make([]string, len(leftover))
+		for i, upload := range leftover {
+			items[i] = fmt.Sprintf("%s (started %s)", upload.Key, upload.Initiated.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintln(os.Stderr, "These multipart uploads are not tracked here and may belong to other machines or tools:")
+		if !confirm(os.Stdin, os.Stderr, items, fmt.Sprintf("Abort these %d uploads?", len(leftover))) {
+			fmt.Println("Nothing was aborted.")
+			return nil
+		}
+	}
+
+	var errs []error
+	for _, upload := range leftover {
+		if err := s3storage.AbortUpload(ctx, backend, upload); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Printf("Aborted leftover upload: %s\n", upload.Key)
+	}
+	fmt.Printf("Aborted %d leftover multipart upload(s)\n", len(leftover)-len(errs))
+	return failureExit(errs)
+}
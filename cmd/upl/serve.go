@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3server"
+)
+
+const defaultServeAddr = "127.0.0.1:9000"
+
+func newServeLocalCommand(globals *globalOptions) *command {
+	var dir, addr, accessKey, secretKey string
+
+	fs := newFlagSet("serve-local")
+	fs.stringVar(&dir, "dir", "", "path", "Directory the buckets are kept in (required)")
+	fs.stringVar(&addr, "addr", "", "host:port", "Address to listen on (default "+defaultServeAddr+")")
+	fs.stringVar(&accessKey, "access-key", "", "key", "Access key ID requests must be signed with (default: the profile's)")
+	fs.stringVar(&secretKey, "secret-key", "", "key", "Secret access key requests must be signed with (default: the profile's)")
+
+	cmd := &command{
+		name:    "serve-local",
+		summary: "Serve a directory as an S3-compatible endpoint for testing",
+		flags:   fs,
+		extraHelp: `Each top-level directory is a bucket; buckets are created on first use.
+Requests must be path-style and signed with the access keys.
+
+EXAMPLES:
+    upl serve-local --dir ./data
+    upl serve-local --dir ./data --addr :9000 --access-key dev --secret-key devsecret
+`,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) > 0 {
+			return newUsageError(cmd, "serve-local takes no arguments")
+		}
+		if dir == "" {
+			return newUsageError(cmd, "--dir is required")
+		}
+		if addr == "" {
+			addr = defaultServeAddr
+		}
+
+		creds, err := serveCredentials(globals, accessKey, secretKey)
+		if err != nil {
+			return err
+		}
+		if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+			return newUsageError(cmd, "no profile is configured; set --access-key and --secret-key")
+		}
+		return runServeLocal(dir, addr, creds)
+	}
+	return cmd
+}
+
+// serveCredentials fills in the keys not given as flags from the profile,
+// so a profile pointed at the server works without further setup.
+func serveCredentials(globals *globalOptions, accessKey, secretKey string) (s3server.Credentials, error) {
+	creds := s3server.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey}
+	if accessKey != "" && secretKey != "" {
+		return creds, nil
+	}
+
+	configExists, err := config.Exists()
+	if err != nil {
+		return creds, fmt.Errorf("error checking for config file: %w", err)
+	}
+	if !configExists && !config.EnvComplete() {
+		return creds, nil
+	}
+	cfg, err := config.LoadProfile(globals.profile)
+	if err != nil {
+		return creds, &configError{fmt.Errorf("error loading config: %w", err)}
+	}
+	if creds.AccessKeyID == "" {
+		creds.AccessKeyID = cfg.AccessKeyID
+	}
+	if creds.SecretAccessKey == "" {
+		creds.SecretAccessKey = cfg.SecretAccessKey
+	}
+	return creds, nil
+}
+
+func runServeLocal(dir, addr string, creds s3server.Credentials) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           s3server.New(dir, creds),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving %s at http://%s (access key %s)\n", dir, listener.Addr(), creds.AccessKeyID)
+	fmt.Println("Press Ctrl+C to stop.")
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/s3server"
+)
+
+func TestServeCredentials(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range []string{"S3_ACCESS_KEY_ID", "S3_SECRET_ACCESS_KEY", "S3_BUCKET", "S3_ENDPOINT"} {
+		t.Setenv(name, "")
+	}
+	globals := &globalOptions{}
+
+	creds, err := serveCredentials(globals, "", "")
+	if err != nil || creds != (s3server.Credentials{}) {
+		t.Errorf("serveCredentials() without a profile = %+v, %v, want no keys", creds, err)
+	}
+
+	t.Setenv("S3_ACCESS_KEY_ID", "profile-key")
+	t.Setenv("S3_SECRET_ACCESS_KEY", "profile-secret")
+	t.Setenv("S3_BUCKET", "files")
+	t.Setenv("S3_ENDPOINT", "http://127.0.0.1:9000")
+
+	creds, err = serveCredentials(globals, "", "")
+	if err != nil || creds != (s3server.Credentials{AccessKeyID: "profile-key", SecretAccessKey: "profile-secret"}) {
+		t.Errorf("serveCredentials() = %+v, %v, want the profile's keys", creds, err)
+	}
+
+	creds, err = serveCredentials(globals, "dev", "")
+	if err != nil || creds != (s3server.Credentials{AccessKeyID: "dev", SecretAccessKey: "profile-secret"}) {
+		t.Errorf("serveCredentials() with --access-key = %+v, %v", creds, err)
+	}
+}
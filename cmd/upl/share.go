@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+const (
+	linkPublic    = "public"
+	linkPresigned = "presigned"
+)
+
+func parseLinkMode(value string) (string, error) {
+	switch value {
+	case linkPublic, linkPresigned:
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown link mode %q (expected %s or %s)", value, linkPublic, linkPresigned)
+}
+
+// profileLinkExpiry returns how long presigned links of the profile are
+// valid: its link_expiry setting, or the default.
+func profileLinkExpiry(cfg *config.Config) (time.Duration, error) {
+	expiry := s3storage.DefaultLinkExpiry
+	if cfg.LinkExpiry != "" {
+		var err error
+		if expiry, err = parseDuration(cfg.LinkExpiry); err != nil {
+			return 0, fmt.Errorf("invalid link_expiry: %w", err)
+		}
+	}
+	return expiry, nil
+}
+
+// linkExpiry resolves whether uploads return presigned links, and for how
+// long they are valid, from --link and --expires, then the profile's
+// link_mode and link_expiry. Zero means public links. --expires alone asks
+// for a presigned link.
+func linkExpiry(mode string, expires time.Duration, cfg *config.Config) (time.Duration, error) {
+	if mode == "" && expires > 0 {
+		mode = linkPresigned
+	}
+	if mode == "" {
+		mode = cfg.LinkMode
+	}
+	if mode == "" {
+		mode = linkPublic
+	}
+
+	mode, err := parseLinkMode(mode)
+	if err != nil || mode == linkPublic {
+		return 0, err
+	}
+
+	if expires == 0 {
+		if expires, err = profileLinkExpiry(cfg); err != nil {
+			return 0, err
+		}
+	}
+	if expires > s3storage.MaxLinkExpiry {
+		return 0, fmt.Errorf("presigned links can be valid for at most 7 days, got %s", expires)
+	}
+	return expires, nil
+}
+
+func newShareCommand(globals *globalOptions) *command {
+	var expires time.Duration
+
+	fs := newFlagSet("share")
+	fs.durationVar(&expires, "expires", "e", "duration", "How long the link is valid, e.g. 24h or 7d (default 24h)")
+
+	cmd := &command{
+		name:    "share",
+		args:    "<key|url>...",
+		summary: "Create presigned links for objects in the bucket",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			return newUsageError(cmd, "expected at least one key")
+		}
+		return runShare(globals, args, expires)
+	}
+	return cmd
+}
+
+func runShare(globals *globalOptions, keys []string, expires time.Duration) error {
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+
+	if expires == 0 {
+		if expires, err = profileLinkExpiry(cfg); err != nil {
+			return err
+		}
+	}
+	if expires > s3storage.MaxLinkExpiry {
+		return fmt.Errorf("presigned links can be valid for at most 7 days, got %s", expires)
+	}
+
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, arg := range keys {
+		key, err := objectKey(cfg, arg)
+		if err != nil {
+			return err
+		}
+		if _, err := s3storage.Stat(ctx, backend, key); err != nil {
+			return err
+		}
+
+		link, err := s3storage.Presign(ctx, backend, key, expires)
+		if err != nil {
+			return err
+		}
+		fmt.Println(link)
+	}
+	return nil
+}
+
+// objectKey accepts a key, or a public, path-style or presigned URL of an
+// object in the profile's bucket, and returns the key.
+func objectKey(cfg *config.Config, arg string) (string, error) {
+	if !strings.Contains(arg, "://") {
+		return strings.TrimPrefix(arg, "/"), nil
+	}
+
+	link, _, _ := strings.Cut(arg, "?")
+	bases := []string{
+		strings.TrimSuffix(cfg.Endpoint, "/") + "/" + cfg.Bucket + "/",
+	}
+	if cfg.PublicUrl != "" {
+		bases = append(bases, strings.TrimSuffix(cfg.PublicUrl, "/")+"/")
+	}
+	for _, base := range bases {
+		if key, ok := strings.CutPrefix(link, base); ok && key != "" {
+			if unescaped, err := url.PathUnescape(key); err == nil {
+				key = unescaped
+			}
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a link to bucket %s", arg, cfg.Bucket)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+func TestLinkExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		expires  time.Duration
+		cfg      config.Config
+		expected time.Duration
+		wantErr  bool
+	}{
+		{name: "default public"},
+		{name: "expires alone presigns", expires: time.Hour, expected: time.Hour},
+		{name: "profile mode", cfg: config.Config{LinkMode: "presigned"}, expected: 24 * time.Hour},
+		{name: "profile expiry", cfg: config.Config{LinkMode: "presigned", LinkExpiry: "7d"}, expected: 7 * 24 * time.Hour},
+		{name: "flag overrides profile", mode: "public", cfg: config.Config{LinkMode: "presigned"}},
+		{name: "expires overrides profile expiry", expires: 2 * time.Hour, cfg: config.Config{LinkExpiry: "3h"}, expected: 2 * time.Hour},
+		{name: "too long", expires: 8 * 24 * time.Hour, wantErr: true},
+		{name: "unknown mode", mode: "private", wantErr: true},
+		{name: "invalid profile expiry", cfg: config.Config{LinkMode: "presigned", LinkExpiry: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := linkExpiry(tt.mode, tt.expires, &tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("linkExpiry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("linkExpiry() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	cfg := &config.Config{
+		Bucket:    "files",
+		Endpoint:  "https://storage.example.com",
+		PublicUrl: "https://files.example.com/",
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "reports/q3.pdf", expected: "reports/q3.pdf"},
+		{input: "/reports/q3.pdf", expected: "reports/q3.pdf"},
+		{input: "https://files.example.com/reports/q3.pdf", expected: "reports/q3.pdf"},
+		{input: "https://files.example.com/my%20notes.txt", expected: "my notes.txt"},
+		{input: "https://storage.example.com/files/reports/q3.pdf?X-Amz-Expires=60", expected: "reports/q3.pdf"},
+		{input: "https://other.example.com/reports/q3.pdf", wantErr: true},
+	}
+	for _, tt := range tests {
+		result, err := objectKey(cfg, tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("objectKey(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if result != tt.expected {
+			t.Errorf("objectKey(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
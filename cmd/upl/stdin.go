@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+	"github.com/nizar0x1f/termup/pkg/batch"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+// stdinPath is the path argument that uploads data read from stdin.
+const stdinPath = "-"
+
+func runStdinUpload(globals *globalOptions, opts *uploadFlags, headers config.Headers, format string) error {
+	if term.IsTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("stdin is a terminal, pipe data into 'upl -', e.g. 'tar cz logs | upl - --name logs.tar.gz'")
+	}
+
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	name := opts.name
+	if name == "" {
+		name = "stdin-" + time.Now().Format("20060102-150405")
+	}
+
+	uploadOpts, err := opts.options(cfg)
+	if err != nil {
+		return err
+	}
+	if uploadOpts.Key == "" {
+		tmpl, err := keyTemplate(opts, cfg)
+		if err != nil {
+			return err
+		}
+		if tmpl != nil {
+			if name, err = tmpl.Render("", name); err != nil {
+				return err
+			}
+		}
+		uploadOpts.Key = batch.JoinKey(opts.prefix, name)
+	}
+	if uploadOpts.Collision, err = collisionPolicy(opts, cfg, s3storage.CollisionAsk); err != nil {
+		return err
+	}
+	setHeaders(uploadOpts, cfg, uploadOpts.Key, headers)
+
+	if format != "" {
+		uploadOpts.Collision = nonInteractiveCollision(uploadOpts.Collision)
+		return runPlainStreamUpload(globals, backend, uploadOpts, format)
+	}
+	autoCopy, err := copyURL(opts, cfg)
+	if err != nil {
+		return err
+	}
+	return runStreamUploadUI(globals, backend, uploadOpts, autoCopy)
+}
+
+// runStreamUploadUI uploads stdin in the progress UI. The UI reads keys from
+// the terminal instead of stdin, which holds the data.
+func runStreamUploadUI(globals *globalOptions, backend s3storage.Backend, opts *s3storage.UploadOptions, autoCopy bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := ui.NewStreamUploadModel(opts.Key)
+	model.SetAutoCopy(autoCopy)
+	model.SetCancel(cancel)
+	if opts.Limiter != nil {
+		model.SetRateLimit(opts.Limiter.Rate(), opts.Limiter.SetRate)
+	}
+	p := tea.NewProgram(model, tea.WithInputTTY())
+
+	exited := make(chan struct{})
+	if opts.Collision == s3storage.CollisionAsk {
+		opts.OnCollision = askOnCollision(ctx, p, exited)
+	}
+	opts.OnRetry = sendRetries(p)
+
+	var result *s3storage.UploadResult
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		result, err = s3storage.UploadStream(ctx, backend, os.Stdin, opts, func(uploaded int64) {
+			p.Send(ui.UploadProgressMsg(uploaded))
+		})
+		sendUploadResult(p, result, err)
+	}()
+
+	finalModel, err := p.Run()
+	close(exited)
+	if err != nil {
+		return fmt.Errorf("error running upload UI: %w", err)
+	}
+
+	uploadModel := finalModel.(ui.UploadModel)
+	if err := uploadModel.GetError(); err != nil {
+		return &exitError{code: exitCode(err)}
+	}
+	if !uploadModel.IsDone() {
+		return cancelUpload(cancel, done)
+	}
+	recordUploads(globals, backend, opts.PresignExpiry, result)
+	return nil
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nizar0x1f/termup/pkg/batch"
+	"github.com/nizar0x1f/termup/pkg/clipboard"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/keytemplate"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+type uploadFlags struct {
+	key         string
+	contentType string
+	partSize    int64
+	concurrency int
+	prefix      string
+	include     []string
+	exclude     []string
+	jobs        int
+	name        string
+	keyTemplate string
+	onConflict  string
+	disposition string
+	cacheCtl    string
+	language    string
+	meta        []string
+	link        string
+	expires     time.Duration
+	output      string
+	noCopy      bool
+	clipboard   bool
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+	retryOn     string
+	limit       string
+}
+
+func (f *uploadFlags) register(fs *flagSet) {
+	fs.stringVar(&f.key, "key", "k", "key", "Object key to upload to (default: the file name)")
+	fs.stringVar(&f.contentType, "content-type", "", "type", "Content-Type of the uploaded object")
+	fs.sizeVar(&f.partSize, "part-size", "", "size", "Part size for multipart uploads, e.g. 16MB")
+	fs.intVar(&f.concurrency, "concurrency", "", "n", "Number of parts uploaded at once")
+	f.registerTransfer(fs)
+	fs.stringVar(&f.prefix, "prefix", "", "prefix", "Key prefix for uploaded files")
+	fs.stringsVar(&f.include, "include", "", "glob", "Only upload files matching glob (repeatable)")
+	fs.stringsVar(&f.exclude, "exclude", "", "glob", "Skip files and directories matching glob (repeatable)")
+	fs.intVar(&f.jobs, "jobs", "j", "n", "Number of files uploaded at once")
+	fs.stringVar(&f.name, "name", "n", "name", "File name for data read from stdin with '-' or the clipboard")
+	fs.boolVar(&f.clipboard, "clipboard", "", "Upload the clipboard contents (same as 'upl paste')")
+	fs.stringVar(&f.disposition, "disposition", "", "value", "Content-Disposition: inline, attachment or a full header value")
+	fs.stringVar(&f.cacheCtl, "cache-control", "", "value", "Cache-Control header, e.g. 'public, max-age=3600'")
+	fs.stringVar(&f.language, "content-language", "", "lang", "Content-Language header, e.g. en")
+	fs.stringsVar(&f.meta, "meta", "m", "key=value", "Add x-amz-meta-* metadata (repeatable)")
+	fs.stringVar(&f.link, "link", "", "mode", "Link to return: public or presigned")
+	fs.durationVar(&f.expires, "expires", "e", "duration", "Return a presigned link valid this long, e.g. 24h or 7d")
+	fs.stringVar(&f.onConflict, "on-conflict", "", "policy", "When the key exists: ask, fail, overwrite, rename, skip or skip-if-same")
+	fs.stringVar(&f.keyTemplate, "key-template", "t", "template", "Build keys from a template, e.g. '{{.Date}}/{{.Rand8}}-{{.Name}}'")
+	fs.boolVar(&f.noCopy, "no-copy", "", "Do not copy the URL to the clipboard")
+	fs.stringVar(&f.output, "output", "o", "format", "Print results without the UI: json, plain or url (default plain when not a terminal)")
+}
+
+// registerTransfer registers the speed limit and retry flags, which 'upl
+// resume' shares with uploads.
+func (f *uploadFlags) registerTransfer(fs *flagSet) {
+	fs.stringVar(&f.limit, "limit", "", "rate", "Cap the upload speed of all parts and files together, e.g. 5MB/s (0 for none)")
+	fs.intVar(&f.maxAttempts, "max-attempts", "", "n", "Tries per request before the upload fails, 1 disables retries (default 5)")
+	fs.durationVar(&f.retryBase, "retry-base", "", "duration", "Backoff after the first failed attempt, doubled for each further one (default 500ms)")
+	fs.durationVar(&f.retryMax, "retry-max-delay", "", "duration", "Longest backoff between two attempts (default 20s)")
+	fs.stringVar(&f.retryOn, "retry-on", "", "kinds", "Errors to retry, from network, timeout, throttle and server (default all)")
+}
+
+// options returns the upload options given by the flags and the profile.
+// Checksums are always computed for the history and the JSON output.
+func (f *uploadFlags) options(cfg *config.Config) (*s3storage.UploadOptions, error) {
+	opts := &s3storage.UploadOptions{
+		Key:         f.key,
+		PartSize:    f.partSize,
+		Concurrency: f.concurrency,
+		Checksum:    true,
+	}
+	var err error
+	if opts.PresignExpiry, err = linkExpiry(f.link, f.expires, cfg); err != nil {
+		return nil, err
+	}
+	if opts.Retry, err = retryPolicy(f, cfg); err != nil {
+		return nil, err
+	}
+	if opts.Limiter, err = speedLimiter(f.limit, cfg); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// headers returns the headers and metadata given on the command line.
+func (f *uploadFlags) headers() (config.Headers, error) {
+	h := config.Headers{
+		ContentType:        f.contentType,
+		ContentDisposition: f.disposition,
+		CacheControl:       f.cacheCtl,
+		ContentLanguage:    f.language,
+	}
+
+	for _, pair := range f.meta {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return h, fmt.Errorf("invalid metadata %q, expected key=value", pair)
+		}
+		if h.Metadata == nil {
+			h.Metadata = make(map[string]string)
+		}
+		h.Metadata[s3storage.MetadataKey(key)] = value
+	}
+	return h, nil
+}
+
+// setHeaders sets the headers of an upload to key: the profile defaults,
+// then the profile's rule for the key's extension, then the command line.
+func setHeaders(opts *s3storage.UploadOptions, cfg *config.Config, key string, flags config.Headers) {
+	h := cfg.Headers.For(key).Merge(flags)
+	opts.ContentType = h.ContentType
+	opts.ContentDisposition = h.ContentDisposition
+	opts.CacheControl = h.CacheControl
+	opts.ContentLanguage = h.ContentLanguage
+	opts.Metadata = h.Metadata
+}
+
+func newUploadCommand(globals *globalOptions) *command {
+	var opts uploadFlags
+
+	fs := newFlagSet("upload")
+	opts.register(fs)
+
+	cmd := &command{
+		name:    "upload",
+		aliases: []string{"up"},
+		args:    "<path>...",
+		summary: "Upload files and directories, or stdin with '-' (same as 'upl <path>...')",
+		flags:   fs,
+	}
+	cmd.run = func(args []string) error {
+		if len(args) == 0 && !opts.clipboard {
+			return newUsageError(cmd, "expected at least one file path")
+		}
+		return runUpload(cmd, globals, &opts, args)
+	}
+	return cmd
+}
+
+func runUpload(cmd *command, globals *globalOptions, opts *uploadFlags, paths []string) error {
+	if opts.clipboard {
+		if len(paths) > 0 {
+			return newUsageError(cmd, "--clipboard cannot be combined with paths")
+		}
+		return runPaste(cmd, globals, opts, clipboard.NewReader())
+	}
+	for _, path := range paths {
+		if path == stdinPath && len(paths) > 1 {
+			return newUsageError(cmd, "'-' cannot be combined with other paths")
+		}
+	}
+	headers, err := opts.headers()
+	if err != nil {
+		return newUsageError(cmd, "%v", err)
+	}
+	format, err := uploadFormat(cmd, opts.output)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 1 && paths[0] == stdinPath {
+		return runStdinUpload(globals, opts, headers, format)
+	}
+	if opts.name != "" {
+		return newUsageError(cmd, "--name can only be used when uploading from stdin with '-'")
+	}
+
+	files, err := batch.Collect(paths, batch.CollectOptions{
+		Prefix:  opts.prefix,
+		Include: opts.include,
+		Exclude: opts.exclude,
+	})
+	if err != nil {
+		return fmt.Errorf("error collecting files: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files to upload")
+	}
+
+	single := len(paths) == 1 && len(files) == 1 && files[0].Path == paths[0]
+	if opts.key != "" && !single {
+		return newUsageError(cmd, "--key can only be used when uploading a single file")
+	}
+
+	cfg, err := loadConfig(globals.profile)
+	if err != nil {
+		return err
+	}
+	return uploadFiles(globals, cfg, opts, headers, format, files, single)
+}
+
+// uploadFiles uploads collected files: a single file in the progress UI,
+// several in the queue view, or either without a UI when format is set.
+func uploadFiles(globals *globalOptions, cfg *config.Config, opts *uploadFlags, headers config.Headers, format string, files []batch.File, single bool) error {
+	backend, err := openBackend(globals, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Interrupted uploads that can no longer be resumed are aborted here,
+	// so that their parts do not stay on the bucket until 'upl resume'.
+	_, _ = s3storage.CleanStaleUploads(context.Background(), backend, s3storage.DefaultResumeMaxAge)
+
+	if opts.key == "" {
+		if err := applyKeyTemplate(backend, files, opts, cfg); err != nil {
+			return err
+		}
+	}
+
+	uploadOpts, err := opts.options(cfg)
+	if err != nil {
+		return err
+	}
+	if single && format == "" {
+		if uploadOpts.Key == "" {
+			uploadOpts.Key = files[0].Key
+		}
+		if uploadOpts.Collision, err = collisionPolicy(opts, cfg, s3storage.CollisionAsk); err != nil {
+			return err
+		}
+		setHeaders(uploadOpts, cfg, uploadOpts.Key, headers)
+		autoCopy, err := copyURL(opts, cfg)
+		if err != nil {
+			return err
+		}
+		return runUploadUI(globals, backend, files[0].Path, uploadOpts, autoCopy)
+	}
+
+	// The queue view cannot ask about each file, so asking fails instead.
+	if uploadOpts.Collision, err = collisionPolicy(opts, cfg, s3storage.CollisionFail); err != nil {
+		return err
+	}
+	if format != "" {
+		uploadOpts.Collision = nonInteractiveCollision(uploadOpts.Collision)
+		return runPlainUpload(globals, cfg, backend, files, uploadOpts, headers, opts.jobs, format)
+	}
+	return runBatchUpload(globals, cfg, backend, files, uploadOpts, headers, opts.jobs)
+}
+
+func loadConfig(profile string) (*config.Config, error) {
+	configExists, err := config.Exists()
+	if err != nil {
+		return nil, fmt.Errorf("error checking for config file: %w", err)
+	}
+
+	if !configExists && !config.EnvComplete() {
+		if !interactive() {
+			return nil, &configError{errNoConfig}
+		}
+		cfg := runConfigUI(profile, nil)
+		if cfg == nil {
+			return nil, &exitError{code: exitConfig}
+		}
+		return cfg, nil
+	}
+
+	cfg, err := config.LoadProfile(profile)
+	if err != nil {
+		return nil, &configError{fmt.Errorf("error loading config: %w", err)}
+	}
+	return cfg, nil
+}
+
+func runUploadUI(globals *globalOptions, backend s3storage.Backend, filePath string, opts *s3storage.UploadOptions, autoCopy bool) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("error accessing file: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	model := ui.NewUploadModel(filePath, fileInfo.Size())
+	model.SetAutoCopy(autoCopy)
+	model.SetCancel(cancel)
+	if opts.Limiter != nil {
+		model.SetRateLimit(opts.Limiter.Rate(), opts.Limiter.SetRate)
+	}
+	p := tea.NewProgram(model)
+
+	exited := make(chan struct{})
+	if opts.Collision == s3storage.CollisionAsk {
+		opts.OnCollision = askOnCollision(ctx, p, exited)
+	}
+	opts.OnRetry = sendRetries(p)
+
+	var result *s3storage.UploadResult
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		result, err = s3storage.UploadFile(ctx, backend, filePath, opts, func(uploaded int64) {
+			p.Send(ui.UploadProgressMsg(uploaded))
+		})
+		sendUploadResult(p, result, err)
+	}()
+
+	finalModel, err := p.Run()
+	close(exited)
+	if err != nil {
+		return fmt.Errorf("error running upload UI: %w", err)
+	}
+
+	uploadModel := finalModel.(ui.UploadModel)
+	if err := uploadModel.GetError(); err != nil {
+		return &exitError{code: exitCode(err)}
+	}
+	if !uploadModel.IsDone() {
+		err := cancelUpload(cancel, done)
+		if resumable(backend, filePath) {
+			fmt.Fprintln(os.Stderr, "Progress was saved; run the same command again or 'upl resume' to continue.")
+		}
+		return err
+	}
+	recordUploads(globals, backend, opts.PresignExpiry, result)
+	return nil
+}
+
+// resumable reports whether a multipart upload of filePath to the bucket of
+// backend has saved progress.
+func resumable(backend s3storage.Backend, filePath string) bool {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return false
+	}
+	pending, _ := s3storage.PendingUploads(backend)
+	for _, state := range pending {
+		if state.FilePath == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelWait is how long a cancelled upload may take to stop its requests
+// before upl exits anyway.
+const cancelWait = 10 * time.Second
+
+// cancelUpload stops an upload whose UI was quit and waits for its requests
+// to end.
+func cancelUpload(cancel context.CancelFunc, done <-chan struct{}) error {
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(cancelWait):
+	}
+	return s3storage.ErrCancelled
+}
+
+// copyURL reports whether the upload UI copies the URL to the clipboard:
+// yes unless --no-copy is given or the profile's copy_url is off.
+func copyURL(opts *uploadFlags, cfg *config.Config) (bool, error) {
+	if opts.noCopy {
+		return false, nil
+	}
+	if cfg.CopyURL == "" {
+		return true, nil
+	}
+	return parseSwitch(cfg.CopyURL)
+}
+
+// parseSwitch parses an on/off setting.
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes", "1":
+		return true, nil
+	case "off", "false", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid value %q, expected on or off", value)
+}
+
+// keyTemplate returns the key template from --key-template or the profile,
+// or nil if neither sets one.
+func keyTemplate(opts *uploadFlags, cfg *config.Config) (*keytemplate.Template, error) {
+	raw := opts.keyTemplate
+	if raw == "" {
+		raw = cfg.KeyTemplate
+	}
+	if raw == "" {
+		return nil, nil
+	}
+	return keytemplate.Parse(raw)
+}
+
+// applyKeyTemplate renders the keys of files from the key template, if one
+// is set. A file with an interrupted upload keeps that upload's key, so that
+// templates with random or dated parts resume instead of starting over.
+func applyKeyTemplate(backend s3storage.Backend, files []batch.File, opts *uploadFlags, cfg *config.Config) error {
+	tmpl, err := keyTemplate(opts, cfg)
+	if err != nil || tmpl == nil {
+		return err
+	}
+
+	resumed := make(map[string]string)
+	pending, _ := s3storage.PendingUploads(backend)
+	for _, state := range pending {
+		resumed[state.FilePath] = state.Key
+	}
+
+	for i := range files {
+		if abs, err := filepath.Abs(files[i].Path); err == nil && resumed[abs] != "" {
+			files[i].Key = resumed[abs]
+			continue
+		}
+		key, err := tmpl.Render(files[i].Path, files[i].Rel)
+		if err != nil {
+			return err
+		}
+		files[i].Key = batch.JoinKey(opts.prefix, key)
+	}
+	return batch.CheckKeys(files)
+}
+
+// collisionPolicy resolves --on-conflict, then the profile's on_conflict
+// setting, then fallback.
+func collisionPolicy(opts *uploadFlags, cfg *config.Config, fallback s3storage.CollisionPolicy) (s3storage.CollisionPolicy, error) {
+	value := opts.onConflict
+	if value == "" {
+		value = cfg.OnConflict
+	}
+	if value == "" {
+		return fallback, nil
+	}
+	return s3storage.ParseCollisionPolicy(value)
+}
+
+// retryPolicy resolves --max-attempts, --retry-base, --retry-max-delay and
+// --retry-on, then the profile's max_attempts, retry_base, retry_max_delay
+// and retry_on settings. Zero values mean the defaults.
+func retryPolicy(opts *uploadFlags, cfg *config.Config) (*s3storage.RetryPolicy, error) {
+	attempts := opts.maxAttempts
+	if attempts < 0 {
+		return nil, fmt.Errorf("invalid --max-attempts %d, expected at least 1", attempts)
+	}
+	if attempts == 0 && cfg.MaxAttempts != "" {
+		var err error
+		if attempts, err = parseAttempts(cfg.MaxAttempts); err != nil {
+			return nil, err
+		}
+	}
+	policy := &s3storage.RetryPolicy{
+		MaxAttempts: attempts,
+		BaseDelay:   opts.retryBase,
+		MaxDelay:    opts.retryMax,
+	}
+
+	var err error
+	if policy.BaseDelay == 0 && cfg.RetryBase != "" {
+		if policy.BaseDelay, err = parseDuration(cfg.RetryBase); err != nil {
+			return nil, err
+		}
+	}
+	if policy.MaxDelay == 0 && cfg.RetryMaxDelay != "" {
+		if policy.MaxDelay, err = parseDuration(cfg.RetryMaxDelay); err != nil {
+			return nil, err
+		}
+	}
+
+	kinds := opts.retryOn
+	if kinds == "" {
+		kinds = cfg.RetryOn
+	}
+	if kinds != "" {
+		if policy.Retryable, err = parseRetryOn(kinds); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// parseRetryOn parses a comma-separated list of s3storage.RetryKinds.
+func parseRetryOn(value string) (func(error) bool, error) {
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("invalid list of errors to retry %q, expected some of %s", value, strings.Join(s3storage.RetryKinds, ", "))
+	}
+	return s3storage.RetryOn(kinds)
+}
+
+func parseAttempts(value string) (int, error) {
+	attempts, err := strconv.Atoi(value)
+	if err != nil || attempts < 1 {
+		return 0, fmt.Errorf("invalid number of attempts %q, expected a number of at least 1", value)
+	}
+	return attempts, nil
+}
+
+// speedLimiter resolves --limit, then the profile's speed_limit setting.
+// Without a limit it returns nil, so that transfers skip the limiter.
+func speedLimiter(limit string, cfg *config.Config) (*s3storage.Limiter, error) {
+	value := limit
+	if value == "" {
+		value = cfg.SpeedLimit
+	}
+	if value == "" {
+		return nil, nil
+	}
+	rate, err := parseRate(value)
+	if err != nil || rate == 0 {
+		return nil, err
+	}
+	return s3storage.NewLimiter(rate), nil
+}
+
+// sendRetries shows retried requests in the upload UI.
+func sendRetries(p *tea.Program) func(s3storage.RetryEvent) {
+	return func(event s3storage.RetryEvent) {
+		p.Send(ui.UploadRetryMsg{
+			Part:        event.Part,
+			Attempt:     event.Attempt,
+			MaxAttempts: event.MaxAttempts,
+		})
+	}
+}
+
+// askOnCollision asks in the upload UI what to do about an existing object.
+// The upload fails without an answer if ctx is cancelled or exited is closed
+// because the UI has quit.
+func askOnCollision(ctx context.Context, p *tea.Program, exited <-chan struct{}) func(*s3storage.ObjectInfo) s3storage.CollisionPolicy {
+	return func(existing *s3storage.ObjectInfo) s3storage.CollisionPolicy {
+		reply := make(chan ui.ConflictChoice, 1)
+		p.Send(ui.UploadConflictMsg{
+			Key:          existing.Key,
+			Size:         existing.Size,
+			LastModified: existing.LastModified,
+			Reply:        reply,
+		})
+
+		var choice ui.ConflictChoice
+		select {
+		case choice = <-reply:
+		case <-ctx.Done():
+		case <-exited:
+		}
+		switch choice {
+		case ui.ConflictOverwrite:
+			return s3storage.CollisionOverwrite
+		case ui.ConflictRename:
+			return s3storage.CollisionRename
+		case ui.ConflictSkip:
+			return s3storage.CollisionSkip
+		}
+		return s3storage.CollisionFail
+	}
+}
+
+func sendUploadResult(p *tea.Program, result *s3storage.UploadResult, err error) {
+	switch {
+	case err != nil:
+		p.Send(ui.UploadErrorMsg(err))
+	case result.Skipped:
+		p.Send(ui.UploadSkippedMsg(result.URL))
+	default:
+		if len(result.Retries) > 0 {
+			p.Send(ui.UploadRetriedMsg(s3storage.FormatRetries(result.Retries)))
+		}
+		p.Send(ui.UploadCompleteMsg(result.URL))
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mitchellh/go-homedir"
+	"github.com/nizar0x1f/termup/pkg/batch"
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/history"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+	"github.com/nizar0x1f/termup/pkg/ui"
+)
+
+func init() {
+	// Tests point HOME at temporary directories.
+	homedir.DisableCache = true
+}
+
+func TestCopyURL(t *testing.T) {
+	tests := []struct {
+		setting  string
+		noCopy   bool
+		expected bool
+		wantErr  bool
+	}{
+		{"", false, true, false},
+		{"", true, false, false},
+		{"off", false, false, false},
+		{"On", false, true, false},
+		{"on", true, false, false},
+		{"sometimes", false, false, true},
+	}
+	for _, tt := range tests {
+		got, err := copyURL(&uploadFlags{noCopy: tt.noCopy}, &config.Config{CopyURL: tt.setting})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("copyURL(%q, %v) error = %v, wantErr %v", tt.setting, tt.noCopy, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("copyURL(%q, %v) = %v, want %v", tt.setting, tt.noCopy, got, tt.expected)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		setting  string
+		flag     int
+		expected int
+		wantErr  bool
+	}{
+		{"", 0, 0, false},
+		{"", 3, 3, false},
+		{"8", 0, 8, false},
+		{"8", 1, 1, false},
+		{"0", 0, 0, true},
+		{"often", 0, 0, true},
+		{"", -1, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := retryPolicy(&uploadFlags{maxAttempts: tt.flag}, &config.Config{MaxAttempts: tt.setting})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("retryPolicy(%q, %d) error = %v, wantErr %v", tt.setting, tt.flag, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.MaxAttempts != tt.expected {
+			t.Errorf("retryPolicy(%q, %d) = %d attempts, want %d", tt.setting, tt.flag, got.MaxAttempts, tt.expected)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    uploadFlags
+		cfg      config.Config
+		base     time.Duration
+		maxDelay time.Duration
+		wantErr  bool
+	}{
+		{"defaults", uploadFlags{}, config.Config{}, 0, 0, false},
+		{"settings", uploadFlags{}, config.Config{RetryBase: "1s", RetryMaxDelay: "1m"}, time.Second, time.Minute, false},
+		{"flags win", uploadFlags{retryBase: 2 * time.Second}, config.Config{RetryBase: "1s", RetryMaxDelay: "1m"}, 2 * time.Second, time.Minute, false},
+		{"invalid setting", uploadFlags{}, config.Config{RetryMaxDelay: "soon"}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := retryPolicy(&tt.flags, &tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retryPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (got.BaseDelay != tt.base || got.MaxDelay != tt.maxDelay) {
+				t.Errorf("retryPolicy() backoff = %v to %v, want %v to %v", got.BaseDelay, got.MaxDelay, tt.base, tt.maxDelay)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyRetryOn(t *testing.T) {
+	throttled := &smithy.GenericAPIError{Code: "SlowDown"}
+	tests := []struct {
+		name      string
+		flag      string
+		setting   string
+		retryable bool
+		wantErr   bool
+	}{
+		{"default", "", "", true, false},
+		{"setting", "", "network, server", false, false},
+		{"flag wins", "throttle", "network", true, false},
+		{"unknown kind", "", "network,5xx", false, true},
+		{"empty list", ",", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := retryPolicy(&uploadFlags{retryOn: tt.flag}, &config.Config{RetryOn: tt.setting})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retryPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			retryable := s3storage.IsRetryable
+			if got.Retryable != nil {
+				retryable = got.Retryable
+			}
+			if retryable(throttled) != tt.retryable {
+				t.Errorf("retryPolicy() retries SlowDown = %v, want %v", !tt.retryable, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestSpeedLimiter(t *testing.T) {
+	tests := []struct {
+		setting  string
+		flag     string
+		expected int64
+		wantErr  bool
+	}{
+		{"", "", 0, false},
+		{"", "5MB/s", 5 << 20, false},
+		{"1MB/s", "", 1 << 20, false},
+		{"1MB/s", "0", 0, false},
+		{"slow", "", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := speedLimiter(tt.flag, &config.Config{SpeedLimit: tt.setting})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("speedLimiter(%q, %q) error = %v, wantErr %v", tt.setting, tt.flag, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.Rate() != tt.expected {
+			t.Errorf("speedLimiter(%q, %q) = %d B/s, want %d", tt.setting, tt.flag, got.Rate(), tt.expected)
+		}
+		if err == nil && tt.expected == 0 && got != nil {
+			t.Errorf("speedLimiter(%q, %q) returned a limiter without a limit", tt.setting, tt.flag)
+		}
+	}
+}
+
+func TestAskOnCollisionWithoutUI(t *testing.T) {
+	existing := &s3storage.ObjectInfo{Key: "a.txt", Size: 1}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, p *tea.Program, exited chan struct{}) context.Context
+	}{
+		{
+			name: "UI exited",
+			run: func(t *testing.T, p *tea.Program, exited chan struct{}) context.Context {
+				go func() {
+					if _, err := p.Run(); err != nil {
+						t.Error(err)
+					}
+					close(exited)
+				}()
+				p.Quit()
+				<-exited
+				return context.Background()
+			},
+		},
+		{
+			name: "cancelled",
+			run: func(t *testing.T, p *tea.Program, exited chan struct{}) context.Context {
+				go p.Run()
+				t.Cleanup(p.Kill)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tea.NewProgram(ui.NewUploadModel("a.txt", 1), tea.WithInput(nil), tea.WithOutput(io.Discard))
+			exited := make(chan struct{})
+			ctx := tt.run(t, p, exited)
+
+			got := make(chan s3storage.CollisionPolicy, 1)
+			go func() { got <- askOnCollision(ctx, p, exited)(existing) }()
+			select {
+			case policy := <-got:
+				if policy != s3storage.CollisionFail {
+					t.Errorf("askOnCollision() = %v, want %v", policy, s3storage.CollisionFail)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("askOnCollision() is still waiting for an answer")
+			}
+		})
+	}
+}
+
+func TestApplyKeyTemplateResumes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	cfg := &config.Config{Endpoint: "file://" + dir, Bucket: "files", KeyTemplate: "{{.Rand8}}-{{.Name}}"}
+	backend, err := openBackend(&globalOptions{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interrupted := filepath.Join(dir, "big.iso")
+	state, err := json.Marshal(map[string]string{
+		"file_path": interrupted,
+		"endpoint":  backend.Endpoint(),
+		"bucket":    backend.Bucket(),
+		"key":       "k3x9q2ab-big.iso",
+		"upload_id": "upload-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads := filepath.Join(home, ".termup", "uploads")
+	if err := os.MkdirAll(uploads, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(uploads, "big.json"), state, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []batch.File{
+		{Path: interrupted, Rel: "big.iso"},
+		{Path: filepath.Join(dir, "notes.txt"), Rel: "notes.txt"},
+	}
+	if err := applyKeyTemplate(backend, files, &uploadFlags{}, cfg); err != nil {
+		t.Fatalf("applyKeyTemplate() error = %v", err)
+	}
+	if files[0].Key != "k3x9q2ab-big.iso" {
+		t.Errorf("key of the interrupted upload = %q, want its saved key", files[0].Key)
+	}
+	if !strings.HasSuffix(files[1].Key, "-notes.txt") || len(files[1].Key) != len("k3x9q2ab-notes.txt") {
+		t.Errorf("key of a new upload = %q, want it rendered from the template", files[1].Key)
+	}
+}
+
+func TestRecordUploadsChecksum(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	cfg := &config.Config{Endpoint: "file://" + dir, Bucket: "files", PublicUrl: config.DefaultPublicUrl}
+	backend, err := openBackend(&globalOptions{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("hello history")
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := (&uploadFlags{key: "notes.txt"}).options(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s3storage.UploadFile(context.Background(), backend, path, opts, func(int64) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordUploads(&globalOptions{}, backend, 0, result)
+
+	entries, err := history.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	if len(entries) != 1 || entries[0].Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("history = %+v, want one entry with the SHA-256 of the content", entries)
+	}
+}
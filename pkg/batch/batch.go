@@ -0,0 +1,181 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+const DefaultWorkers = 4
+
+// ErrCancelled is the error of a file cancelled with Runner.Cancel.
+var ErrCancelled = s3storage.ErrCancelled
+
+type Result struct {
+	File     File
+	Upload   *s3storage.UploadResult
+	Err      error
+	Duration time.Duration
+}
+
+// URL returns the URL of the uploaded object, or "" if the upload failed.
+func (r Result) URL() string {
+	if r.Upload == nil {
+		return ""
+	}
+	return r.Upload.URL
+}
+
+// UploadFunc uploads one file, reporting the bytes sent so far of that file
+// through progress. It should stop when ctx is cancelled.
+type UploadFunc func(ctx context.Context, file File, progress func(uploaded int64)) (*s3storage.UploadResult, error)
+
+// Runner uploads files with a bounded number of workers. The hooks are
+// optional and may be called from several goroutines at once.
+type Runner struct {
+	Workers int
+	Upload  UploadFunc
+
+	OnStart    func(index int)
+	OnProgress func(index int, uploaded int64)
+	OnDone     func(index int, result Result)
+
+	mu        sync.Mutex
+	cancels   map[int]context.CancelFunc
+	cancelled map[int]bool
+}
+
+// Cancel stops the upload of files[index] if it is running, or skips it if
+// it has not started yet.
+func (r *Runner) Cancel(index int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancelled == nil {
+		r.cancelled = make(map[int]bool)
+	}
+	r.cancelled[index] = true
+	if cancel, ok := r.cancels[index]; ok {
+		cancel()
+	}
+}
+
+func (r *Runner) start(index int) (context.Context, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancelled[index] {
+		return nil, false
+	}
+	if r.cancels == nil {
+		r.cancels = make(map[int]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancels[index] = cancel
+	return ctx, true
+}
+
+func (r *Runner) finish(index int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cancel, ok := r.cancels[index]; ok {
+		cancel()
+		delete(r.cancels, index)
+	}
+	return r.cancelled[index]
+}
+
+// Run uploads all files and returns their results in the order of files.
+func (r *Runner) Run(files []File) []Result {
+	workers := r.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	results := make([]Result, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results[index] = r.upload(index, files[index])
+			}
+		}()
+	}
+
+	for index := range files {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func (r *Runner) upload(index int, file File) Result {
+	ctx, ok := r.start(index)
+	if !ok {
+		result := Result{File: file, Err: ErrCancelled}
+		if r.OnDone != nil {
+			r.OnDone(index, result)
+		}
+		return result
+	}
+
+	if r.OnStart != nil {
+		r.OnStart(index)
+	}
+
+	start := time.Now()
+	upload, err := r.Upload(ctx, file, func(uploaded int64) {
+		if r.OnProgress != nil {
+			r.OnProgress(index, uploaded)
+		}
+	})
+	if r.finish(index) && err != nil {
+		err = ErrCancelled
+	}
+
+	result := Result{File: file, Upload: upload, Err: err, Duration: time.Since(start)}
+	if r.OnDone != nil {
+		r.OnDone(index, result)
+	}
+	return result
+}
+
+// Summary totals the results of a batch.
+type Summary struct {
+	Succeeded int
+	Skipped   int
+	Failed    int
+	Cancelled int
+	Bytes     int64
+}
+
+func Summarize(results []Result) Summary {
+	var s Summary
+	for _, result := range results {
+		if errors.Is(result.Err, ErrCancelled) {
+			s.Cancelled++
+			continue
+		}
+		if result.Err != nil {
+			s.Failed++
+			continue
+		}
+		if result.Upload != nil && result.Upload.Skipped {
+			s.Skipped++
+			continue
+		}
+		s.Succeeded++
+		s.Bytes += result.File.Size
+	}
+	return s
+}
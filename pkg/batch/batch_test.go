@@ -0,0 +1,217 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+func writeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func keys(files []File) []string {
+	var out []string
+	for _, f := range files {
+		out = append(out, f.Key)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir,
+		"site/index.html",
+		"site/app.js",
+		"site/app.js.map",
+		"site/img/logo.png",
+		"site/node_modules/lib/index.js",
+		"notes.txt",
+	)
+	site := filepath.Join(dir, "site")
+	notes := filepath.Join(dir, "notes.txt")
+
+	tests := []struct {
+		name  string
+		paths []string
+		opts  CollectOptions
+		want  []string
+	}{
+		{
+			name:  "single file",
+			paths: []string{notes},
+			want:  []string{"notes.txt"},
+		},
+		{
+			name:  "directory keeps relative paths",
+			paths: []string{site},
+			opts:  CollectOptions{Exclude: []string{"node_modules"}},
+			want:  []string{"site/app.js", "site/app.js.map", "site/img/logo.png", "site/index.html"},
+		},
+		{
+			name:  "prefix",
+			paths: []string{notes, site + "/"},
+			opts:  CollectOptions{Prefix: "shared/", Exclude: []string{"node_modules", "*.map"}},
+			want:  []string{"shared/notes.txt", "shared/site/app.js", "shared/site/img/logo.png", "shared/site/index.html"},
+		},
+		{
+			name:  "include",
+			paths: []string{site},
+			opts:  CollectOptions{Include: []string{"*.js"}},
+			want:  []string{"site/app.js", "site/node_modules/lib/index.js"},
+		},
+		{
+			name:  "pattern with slash matches relative path",
+			paths: []string{site},
+			opts:  CollectOptions{Include: []string{"site/img/*"}},
+			want:  []string{"site/img/logo.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Collect(tt.paths, tt.opts)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+			if got := keys(files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Collect() keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectRelativeDirs(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "site/index.html", "site/img/logo.png")
+	t.Chdir(filepath.Join(dir, "site", "img"))
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{".", []string{"img/logo.png"}},
+		{"..", []string{"site/img/logo.png", "site/index.html"}},
+		{"../img/", []string{"img/logo.png"}},
+		{"../../site", []string{"site/img/logo.png", "site/index.html"}},
+	}
+	for _, tt := range tests {
+		files, err := Collect([]string{tt.path}, CollectOptions{})
+		if err != nil {
+			t.Fatalf("Collect(%q) error = %v", tt.path, err)
+		}
+		if got := keys(files); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Collect(%q) keys = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "a/notes.txt", "b/notes.txt")
+
+	if _, err := Collect([]string{filepath.Join(dir, "missing")}, CollectOptions{}); err == nil {
+		t.Error("expected error for missing path")
+	}
+
+	files := []string{filepath.Join(dir, "a", "notes.txt"), filepath.Join(dir, "b", "notes.txt")}
+	if _, err := Collect(files, CollectOptions{}); err == nil {
+		t.Error("expected error for files uploaded to the same key")
+	}
+}
+
+func TestRunner(t *testing.T) {
+	var files []File
+	for i := 0; i < 20; i++ {
+		files = append(files, File{Key: string(rune('a' + i)), Size: int64(i)})
+	}
+
+	var running, peak int32
+	runner := &Runner{
+		Workers: 3,
+		Upload: func(ctx context.Context, file File, progress func(int64)) (*s3storage.UploadResult, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			defer atomic.AddInt32(&running, -1)
+
+			progress(file.Size)
+			if file.Key == "c" {
+				return nil, errors.New("boom")
+			}
+			if file.Key == "d" {
+				return &s3storage.UploadResult{Key: file.Key, Skipped: true}, nil
+			}
+			return &s3storage.UploadResult{Key: file.Key}, nil
+		},
+	}
+
+	results := runner.Run(files)
+
+	if peak > 3 {
+		t.Errorf("ran %d uploads at once, want at most 3", peak)
+	}
+	for i, result := range results {
+		if result.File.Key != files[i].Key {
+			t.Errorf("results[%d] is for %s, want %s", i, result.File.Key, files[i].Key)
+		}
+	}
+
+	summary := Summarize(results)
+	if summary.Succeeded != 18 || summary.Skipped != 1 || summary.Failed != 1 || summary.Bytes != 190-2-3 {
+		t.Errorf("Summarize() = %+v", summary)
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	files := []File{{Key: "running"}, {Key: "queued"}, {Key: "other"}}
+
+	runner := &Runner{Workers: 1}
+	started := make(chan struct{})
+	runner.Upload = func(ctx context.Context, file File, progress func(int64)) (*s3storage.UploadResult, error) {
+		if file.Key != "running" {
+			return &s3storage.UploadResult{Key: file.Key}, nil
+		}
+		close(started)
+		<-ctx.Done()
+		return nil, fmt.Errorf("failed to upload: %w", ctx.Err())
+	}
+
+	go func() {
+		<-started
+		runner.Cancel(1)
+		runner.Cancel(0)
+	}()
+	results := runner.Run(files)
+
+	for i, want := range []error{ErrCancelled, ErrCancelled, nil} {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("results[%d].Err = %v, want %v", i, results[i].Err, want)
+		}
+	}
+	if s := Summarize(results); s.Cancelled != 2 || s.Succeeded != 1 {
+		t.Errorf("Summarize() = %+v", s)
+	}
+}
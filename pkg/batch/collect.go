@@ -0,0 +1,153 @@
+package batch
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// File is one local file to upload and the key it is uploaded to.
+type File struct {
+	Path string
+	Key  string
+	Size int64
+
+	// Rel is the file name, or the slash-separated path relative to the
+	// uploaded directory's parent, before the prefix is added.
+	Rel string
+}
+
+type CollectOptions struct {
+	// Prefix is prepended to every key.
+	Prefix string
+
+	// Include and Exclude are glob patterns. A pattern without a slash is
+	// matched against the file name, one with a slash against the path
+	// relative to the uploaded directory's parent. If Include is set, only
+	// matching files are uploaded; excluded directories are not descended
+	// into.
+	Include []string
+	Exclude []string
+}
+
+// Collect expands paths into the files to upload. Files given directly are
+// uploaded under their base name; directories are walked recursively and
+// their files keep their path relative to the directory's parent, so
+// "upl dist/" uploads "dist/index.html".
+func Collect(paths []string, opts CollectOptions) ([]File, error) {
+	var files []File
+
+	add := func(localPath, rel string, size int64) error {
+		files = append(files, File{Path: localPath, Key: JoinKey(opts.Prefix, rel), Size: size, Rel: rel})
+		return nil
+	}
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			if err := add(p, filepath.Base(p), info.Size()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		root := filepath.Clean(p)
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		// Keys start with the directory's own name, also for "." or "..".
+		name := filepath.Base(abs)
+		err = filepath.WalkDir(root, func(walkPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, walkPath)
+			if err != nil {
+				return err
+			}
+			rel = path.Join(name, filepath.ToSlash(rel))
+
+			if d.IsDir() {
+				if walkPath != root && matchAny(opts.Exclude, rel) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			info, err := os.Stat(walkPath)
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			if matchAny(opts.Exclude, rel) {
+				return nil
+			}
+			if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+				return nil
+			}
+
+			return add(walkPath, rel, info.Size())
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := CheckKeys(files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// CheckKeys returns an error if two files would be uploaded to the same key.
+func CheckKeys(files []File) error {
+	seen := make(map[string]string, len(files))
+	for _, f := range files {
+		if other, ok := seen[f.Key]; ok {
+			return fmt.Errorf("both %s and %s would be uploaded as %s", other, f.Path, f.Key)
+		}
+		seen[f.Key] = f.Path
+	}
+	return nil
+}
+
+// JoinKey puts rel under prefix, adding a slash between them if needed.
+func JoinKey(prefix, rel string) string {
+	if prefix == "" {
+		return rel
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + rel
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalSize returns the combined size of files.
+func TotalSize(files []File) int64 {
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total
+}
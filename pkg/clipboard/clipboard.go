@@ -0,0 +1,110 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/x/term"
+)
+
+// ErrUnavailable is returned when there is neither a terminal to send an
+// OSC 52 sequence to nor a clipboard tool to run.
+var ErrUnavailable = errors.New("no clipboard available: install wl-clipboard, xclip or xsel, or use a terminal with OSC 52 support")
+
+// tool is a command that copies its stdin to the clipboard.
+type tool struct {
+	name string
+	args []string
+}
+
+func (t tool) String() string {
+	return strings.Join(append([]string{t.name}, t.args...), " ")
+}
+
+// lookPath finds tools; tests replace it.
+var lookPath = exec.LookPath
+
+// Write puts text on the clipboard. It sends an OSC 52 sequence to the
+// terminal, which also works over SSH and inside tmux or screen, and, since
+// not every terminal supports OSC 52, also runs a native clipboard tool when
+// the session is local or there is no terminal.
+func Write(text string) error {
+	oscErr := writeOSC52(text)
+	if oscErr == nil && remote() {
+		return nil
+	}
+
+	nativeErr := writeNative(text)
+	if oscErr == nil || nativeErr == nil {
+		return nil
+	}
+	return nativeErr
+}
+
+func writeOSC52(text string) error {
+	if !term.IsTerminal(os.Stderr.Fd()) {
+		return ErrUnavailable
+	}
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case os.Getenv("STY") != "":
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(os.Stderr)
+	return err
+}
+
+func writeNative(text string) error {
+	for _, t := range writeTools(runtime.GOOS, os.Getenv) {
+		path, err := lookPath(t.name)
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, t.args...)
+		cmd.Stdin = strings.NewReader(text)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s failed: %w: %s", t, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return ErrUnavailable
+}
+
+// writeTools returns the clipboard tools to try, in order, for the platform
+// and display server.
+func writeTools(goos string, getenv func(string) string) []tool {
+	switch goos {
+	case "darwin":
+		return []tool{{"pbcopy", nil}}
+	case "windows":
+		return []tool{{"clip.exe", nil}}
+	}
+
+	var tools []tool
+	if getenv("WAYLAND_DISPLAY") != "" {
+		tools = append(tools, tool{"wl-copy", nil})
+	}
+	if getenv("DISPLAY") != "" {
+		tools = append(tools,
+			tool{"xclip", []string{"-selection", "clipboard", "-in"}},
+			tool{"xsel", []string{"--clipboard", "--input"}},
+		)
+	}
+	if getenv("WSL_DISTRO_NAME") != "" {
+		tools = append(tools, tool{"clip.exe", nil})
+	}
+	return tools
+}
+
+// remote reports whether termup runs in an SSH session, where native tools
+// would copy to the server's clipboard instead of the user's.
+func remote() bool {
+	return os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != ""
+}
@@ -0,0 +1,80 @@
+package clipboard
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestWriteTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		expected []string
+	}{
+		{"macOS", "darwin", nil, []string{"pbcopy"}},
+		{"Windows", "windows", nil, []string{"clip.exe"}},
+		{"Wayland", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			[]string{"wl-copy", "xclip -selection clipboard -in", "xsel --clipboard --input"}},
+		{"X11", "linux", map[string]string{"DISPLAY": ":0"},
+			[]string{"xclip -selection clipboard -in", "xsel --clipboard --input"}},
+		{"WSL", "linux", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, []string{"clip.exe"}},
+		{"headless", "linux", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tool := range writeTools(tt.goos, func(key string) string { return tt.env[key] }) {
+				got = append(got, tool.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("writeTools() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteNative(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the X11 clipboard tool")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "clipboard")
+	script := filepath.Join(dir, "xclip")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat > "+out+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("WSL_DISTRO_NAME", "")
+	t.Setenv("DISPLAY", ":0")
+	lookPath = func(name string) (string, error) {
+		if name == "xclip" {
+			return script, nil
+		}
+		return "", errors.New("not found")
+	}
+	defer func() { lookPath = exec.LookPath }()
+
+	if err := writeNative("https://example.com/a.png"); err != nil {
+		t.Fatalf("writeNative() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "https://example.com/a.png" {
+		t.Errorf("clipboard = %q", data)
+	}
+
+	t.Setenv("DISPLAY", "")
+	if err := writeNative("x"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("writeNative() without a display = %v, want ErrUnavailable", err)
+	}
+}
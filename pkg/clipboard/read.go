@@ -0,0 +1,145 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrEmpty is returned when the clipboard holds neither an image nor text.
+var ErrEmpty = errors.New("the clipboard is empty")
+
+// Content is what was read from the clipboard.
+type Content struct {
+	Data []byte
+
+	// Ext is the file extension matching Data, without the dot: "png" for
+	// images and "txt" for text.
+	Ext string
+}
+
+// Reader reads the clipboard.
+type Reader interface {
+	Read() (*Content, error)
+}
+
+// runner runs a command and returns its stdout.
+type runner func(name string, args ...string) ([]byte, error)
+
+func run(name string, args ...string) ([]byte, error) {
+	path, err := lookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// toolReader reads the clipboard with command-line tools: types lists the
+// MIME types on offer, image prints the PNG image and text the text.
+type toolReader struct {
+	name  string
+	types []string
+	image []string
+	text  []string
+	run   runner
+}
+
+// NewReader returns a Reader for the platform's clipboard tools: wl-paste
+// on Wayland, xclip on X11, and pbpaste (with pngpaste for images) on
+// macOS.
+func NewReader() Reader {
+	return newReader(runtime.GOOS, os.Getenv, run)
+}
+
+func newReader(goos string, getenv func(string) string, run runner) Reader {
+	switch {
+	case goos == "darwin":
+		return &macReader{run: run}
+	case getenv("WAYLAND_DISPLAY") != "":
+		return &toolReader{
+			name:  "wl-paste",
+			types: []string{"wl-paste", "--list-types"},
+			image: []string{"wl-paste", "--no-newline", "--type", "image/png"},
+			text:  []string{"wl-paste", "--no-newline", "--type", "text"},
+			run:   run,
+		}
+	case getenv("DISPLAY") != "":
+		return &toolReader{
+			name:  "xclip",
+			types: []string{"xclip", "-selection", "clipboard", "-target", "TARGETS", "-out"},
+			image: []string{"xclip", "-selection", "clipboard", "-target", "image/png", "-out"},
+			text:  []string{"xclip", "-selection", "clipboard", "-target", "UTF8_STRING", "-out"},
+			run:   run,
+		}
+	}
+	return unavailableReader{}
+}
+
+func (r *toolReader) Read() (*Content, error) {
+	types, err := r.run(r.types[0], r.types[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the clipboard with %s: %w", r.name, err)
+	}
+
+	hasText := false
+	for _, t := range strings.Fields(string(types)) {
+		switch {
+		case t == "image/png":
+			return r.read(r.image, "png")
+		case strings.HasPrefix(t, "text/"), t == "UTF8_STRING", t == "STRING", t == "TEXT":
+			hasText = true
+		}
+	}
+	if !hasText {
+		return nil, ErrEmpty
+	}
+	return r.read(r.text, "txt")
+}
+
+func (r *toolReader) read(command []string, ext string) (*Content, error) {
+	data, err := r.run(command[0], command[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the clipboard with %s: %w", r.name, err)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	return &Content{Data: data, Ext: ext}, nil
+}
+
+// macReader reads images with pngpaste, if it is installed, and text with
+// pbpaste.
+type macReader struct {
+	run runner
+}
+
+func (r *macReader) Read() (*Content, error) {
+	if data, err := r.run("pngpaste", "-"); err == nil && len(data) > 0 {
+		return &Content{Data: data, Ext: "png"}, nil
+	}
+	data, err := r.run("pbpaste")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the clipboard with pbpaste: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	return &Content{Data: data, Ext: "txt"}, nil
+}
+
+type unavailableReader struct{}
+
+func (unavailableReader) Read() (*Content, error) {
+	return nil, errors.New("cannot read the clipboard: install wl-clipboard (Wayland) or xclip (X11)")
+}
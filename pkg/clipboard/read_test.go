@@ -0,0 +1,106 @@
+package clipboard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeTools answers commands from a map keyed by the command line.
+func fakeTools(outputs map[string]string) runner {
+	return func(name string, args ...string) ([]byte, error) {
+		out, ok := outputs[strings.Join(append([]string{name}, args...), " ")]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return []byte(out), nil
+	}
+}
+
+func TestReader(t *testing.T) {
+	wayland := map[string]string{"WAYLAND_DISPLAY": "wayland-0"}
+	x11 := map[string]string{"DISPLAY": ":0"}
+
+	tests := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		outputs  map[string]string
+		expected *Content
+		wantErr  error
+	}{
+		{
+			name: "wayland image",
+			goos: "linux",
+			env:  wayland,
+			outputs: map[string]string{
+				"wl-paste --list-types":                  "image/png\ntext/html\n",
+				"wl-paste --no-newline --type image/png": "\x89PNG",
+				"wl-paste --no-newline --type text":      "ignored",
+			},
+			expected: &Content{Data: []byte("\x89PNG"), Ext: "png"},
+		},
+		{
+			name: "wayland text",
+			goos: "linux",
+			env:  wayland,
+			outputs: map[string]string{
+				"wl-paste --list-types":             "text/plain;charset=utf-8\nUTF8_STRING\n",
+				"wl-paste --no-newline --type text": "hello",
+			},
+			expected: &Content{Data: []byte("hello"), Ext: "txt"},
+		},
+		{
+			name: "x11 image",
+			goos: "linux",
+			env:  x11,
+			outputs: map[string]string{
+				"xclip -selection clipboard -target TARGETS -out":   "TARGETS\nimage/png\n",
+				"xclip -selection clipboard -target image/png -out": "\x89PNG",
+			},
+			expected: &Content{Data: []byte("\x89PNG"), Ext: "png"},
+		},
+		{
+			name: "x11 text",
+			goos: "linux",
+			env:  x11,
+			outputs: map[string]string{
+				"xclip -selection clipboard -target TARGETS -out":     "TARGETS\nUTF8_STRING\n",
+				"xclip -selection clipboard -target UTF8_STRING -out": "hello",
+			},
+			expected: &Content{Data: []byte("hello"), Ext: "txt"},
+		},
+		{
+			name:    "empty",
+			goos:    "linux",
+			env:     wayland,
+			outputs: map[string]string{"wl-paste --list-types": ""},
+			wantErr: ErrEmpty,
+		},
+		{
+			name:     "macOS text",
+			goos:     "darwin",
+			outputs:  map[string]string{"pbpaste": "hello"},
+			expected: &Content{Data: []byte("hello"), Ext: "txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newReader(tt.goos, func(key string) string { return tt.env[key] }, fakeTools(tt.outputs))
+			content, err := reader.Read()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Read() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if string(content.Data) != string(tt.expected.Data) || content.Ext != tt.expected.Ext {
+				t.Errorf("Read() = %q (%s), want %q (%s)", content.Data, content.Ext, tt.expected.Data, tt.expected.Ext)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package config
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -17,6 +16,42 @@ type Config struct {
 	Bucket          string `json:"bucket"`
 	Endpoint        string `json:"endpoint"`
 	PublicUrl       string `json:"public_url"`
+
+	// KeyTemplate builds object keys, see package keytemplate. Empty means
+	// the file name.
+	KeyTemplate string `json:"key_template,omitempty"`
+
+	// OnConflict is the collision policy used when the key already exists.
+	OnConflict string `json:"on_conflict,omitempty"`
+
+	// LinkMode is "public" or "presigned"; LinkExpiry is how long presigned
+	// links stay valid, e.g. "24h" or "7d".
+	LinkMode   string `json:"link_mode,omitempty"`
+	LinkExpiry string `json:"link_expiry,omitempty"`
+
+	// CopyURL is "on" or "off": whether the upload UI copies the URL to the
+	// clipboard. Empty means on.
+	CopyURL string `json:"copy_url,omitempty"`
+
+	// PasteName is the key template that names clipboard uploads.
+	PasteName string `json:"paste_name,omitempty"`
+
+	// MaxAttempts is how often an upload request is tried before the
+	// upload fails, e.g. "5". Empty means the default.
+	MaxAttempts string `json:"max_attempts,omitempty"`
+
+	// RetryBase and RetryMaxDelay bound the backoff between attempts, e.g.
+	// "1s" and "1m". RetryOn lists the kinds of errors that are retried,
+	// e.g. "network,throttle". Empty means the defaults.
+	RetryBase     string `json:"retry_base,omitempty"`
+	RetryMaxDelay string `json:"retry_max_delay,omitempty"`
+	RetryOn       string `json:"retry_on,omitempty"`
+
+	// SpeedLimit caps the upload and download speed, e.g. "5MB/s". Empty means no
+	// limit.
+	SpeedLimit string `json:"speed_limit,omitempty"`
+
+	Headers *HeaderDefaults `json:"headers,omitempty"`
 }
 
 func configPath() (string, error) {
@@ -27,6 +62,20 @@ func configPath() (string, error) {
 	return filepath.Join(home, ".termup.json"), nil
 }
 
+// StateDir returns the directory termup keeps local state in (upload
+// progress, history), creating it if needed.
+func StateDir() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".termup")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
 func Exists() (bool, error) {
 	path, err := configPath()
 	if err != nil {
@@ -40,45 +89,177 @@ func Exists() (bool, error) {
 	return err == nil, err
 }
 
-func Load() (*Config, error) {
-	path, err := configPath()
-	if err != nil {
-		return nil, err
+const DefaultPublicUrl = "https://your-bucket.s3.amazonaws.com/"
+
+// Source tells where a configuration value came from.
+type Source string
+
+const (
+	SourceUnset   Source = "unset"
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "environment"
+)
+
+// Setting describes one resolved configuration value.
+type Setting struct {
+	Name   string
+	EnvVar string
+	Value  string
+	Secret bool
+	Source Source
+}
+
+type field struct {
+	name     string
+	envVar   string
+	secret   bool
+	required bool
+	value    func(*Config) *string
+}
+
+var fields = []field{
+	{"access_key_id", "S3_ACCESS_KEY_ID", false, true, func(c *Config) *string { return &c.AccessKeyID }},
+	{"secret_access_key", "S3_SECRET_ACCESS_KEY", true, true, func(c *Config) *string { return &c.SecretAccessKey }},
+	{"bucket", "S3_BUCKET", false, true, func(c *Config) *string { return &c.Bucket }},
+	{"endpoint", "S3_ENDPOINT", false, true, func(c *Config) *string { return &c.Endpoint }},
+	{"public_url", "S3_PUBLIC_URL", false, false, func(c *Config) *string { return &c.PublicUrl }},
+	{"key_template", "TERMUP_KEY_TEMPLATE", false, false, func(c *Config) *string { return &c.KeyTemplate }},
+	{"on_conflict", "TERMUP_ON_CONFLICT", false, false, func(c *Config) *string { return &c.OnConflict }},
+	{"link_mode", "TERMUP_LINK_MODE", false, false, func(c *Config) *string { return &c.LinkMode }},
+	{"link_expiry", "TERMUP_LINK_EXPIRY", false, false, func(c *Config) *string { return &c.LinkExpiry }},
+	{"copy_url", "TERMUP_COPY_URL", false, false, func(c *Config) *string { return &c.CopyURL }},
+	{"paste_name", "TERMUP_PASTE_NAME", false, false, func(c *Config) *string { return &c.PasteName }},
+	{"max_attempts", "TERMUP_MAX_ATTEMPTS", false, false, func(c *Config) *string { return &c.MaxAttempts }},
+	{"retry_base", "TERMUP_RETRY_BASE", false, false, func(c *Config) *string { return &c.RetryBase }},
+	{"retry_max_delay", "TERMUP_RETRY_MAX_DELAY", false, false, func(c *Config) *string { return &c.RetryMaxDelay }},
+	{"retry_on", "TERMUP_RETRY_ON", false, false, func(c *Config) *string { return &c.RetryOn }},
+	{"speed_limit", "TERMUP_SPEED_LIMIT", false, false, func(c *Config) *string { return &c.SpeedLimit }},
+}
+
+func findField(name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
 	}
+	return field{}, false
+}
 
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// SetValue changes one setting of a profile in the config file. An empty
+// value clears it. The secret access key is changed with the setup UI
+// instead.
+func SetValue(profile, name, value string) error {
+	f, ok := findField(name)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+	if f.secret {
+		return fmt.Errorf("%s cannot be set directly, use 'upl relogin'", name)
+	}
+	if f.required && value == "" {
+		return fmt.Errorf("%s cannot be empty", name)
 	}
-	defer file.Close()
 
-	var cfg Config
-	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
-		return nil, err
+	file, err := LoadFile()
+	if err != nil {
+		return err
 	}
+	cfg, err := file.Profile(file.ActiveProfile(profile))
+	if err != nil {
+		return err
+	}
+
+	*f.value(cfg) = value
+	return SaveFile(file)
+}
 
-	if cfg.PublicUrl == "" {
-		cfg.PublicUrl = "https://your-bucket.s3.amazonaws.com/"
+// EnvComplete reports whether the environment alone provides every required
+// setting, so that no config file is needed.
+func EnvComplete() bool {
+	for _, f := range fields {
+		if f.required && os.Getenv(f.envVar) == "" {
+			return false
+		}
 	}
+	return true
+}
 
-	return &cfg, nil
+func Load() (*Config, error) {
+	return LoadProfile("")
 }
 
-func Save(cfg *Config) error {
-	path, err := configPath()
-	if err != nil {
-		return err
+// LoadProfile loads the named profile, or the active profile if name is
+// empty, with environment overrides applied.
+func LoadProfile(name string) (*Config, error) {
+	cfg, _, err := LoadWithSources(name)
+	return cfg, err
+}
+
+// LoadWithSources resolves the configuration of a profile field by field.
+// Environment variables take precedence over the profile in the config file,
+// which takes precedence over built-in defaults. The config file may be
+// missing if the environment provides all required settings.
+func LoadWithSources(profile string) (*Config, []Setting, error) {
+	var fileCfg Config
+	fileFound := false
+	secretSource := SourceFile
+
+	file, err := LoadFile()
+	if err == nil {
+		name := file.ActiveProfile(profile)
+		cfg, err := file.Profile(name)
+		if err == nil {
+			fileCfg = *cfg
+			fileFound = true
+		} else if !EnvComplete() {
+			return nil, nil, err
+		}
+
+		if fileFound && os.Getenv("S3_SECRET_ACCESS_KEY") == "" {
+			fileCfg.SecretAccessKey, secretSource, err = file.Secret(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read secret for profile %q: %w", name, err)
+			}
+		}
+	} else if !os.IsNotExist(err) || !EnvComplete() {
+		return nil, nil, err
 	}
 
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+	defaults := Config{PublicUrl: DefaultPublicUrl}
+
+	cfg := fileCfg
+	settings := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		value, source := "", SourceUnset
+		if v := *f.value(&defaults); v != "" {
+			value, source = v, SourceDefault
+		}
+		if v := *f.value(&fileCfg); fileFound && v != "" {
+			value, source = v, SourceFile
+			if f.secret {
+				source = secretSource
+			}
+		}
+		if v := os.Getenv(f.envVar); v != "" {
+			value, source = v, SourceEnv
+		}
+
+		*f.value(&cfg) = value
+		settings = append(settings, Setting{
+			Name:   f.name,
+			EnvVar: f.envVar,
+			Value:  value,
+			Secret: f.secret,
+			Source: source,
+		})
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(cfg)
+	return &cfg, settings, nil
+}
+
+func Save(cfg *Config) error {
+	return SaveProfile("", cfg)
 }
 
 func PromptForConfig() (*Config, error) {
@@ -96,11 +277,11 @@ func PromptForConfig() (*Config, error) {
 	fmt.Print("Enter Endpoint: ")
 	endpoint, _ := reader.ReadString('\n')
 
-	fmt.Print("Enter Public URL (default: https://your-bucket.s3.amazonaws.com/): ")
+	fmt.Printf("Enter Public URL (default: %s): ", DefaultPublicUrl)
 	PublicUrl, _ := reader.ReadString('\n')
 	PublicUrl = strings.TrimSpace(PublicUrl)
 	if PublicUrl == "" {
-		PublicUrl = "https://your-bucket.s3.amazonaws.com/"
+		PublicUrl = DefaultPublicUrl
 	}
 
 	return &Config{
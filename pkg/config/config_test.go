@@ -3,8 +3,19 @@ package config
 import (
 	"os"
 	"testing"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/nizar0x1f/termup/pkg/secrets"
 )
 
+func init() {
+	// Tests point HOME at temporary directories and must never touch the
+	// real keyring.
+	homedir.DisableCache = true
+	os.Setenv(secrets.BackendEnvVar, string(secrets.BackendFile))
+	os.Setenv(secrets.PassphraseEnvVar, "test-passphrase")
+}
+
 func TestSimpleConfig(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "testconfig")
 	if err != nil {
@@ -36,3 +47,153 @@ func TestSimpleConfig(t *testing.T) {
 		t.Errorf("AccessKeyID mismatch: got %v, want %v", loadedCfg.AccessKeyID, cfg.AccessKeyID)
 	}
 }
+
+func TestLoadWithSources(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("S3_BUCKET", "env-bucket")
+
+	if err := Save(&Config{
+		AccessKeyID:     "file-access-key",
+		SecretAccessKey: "file-secret-key",
+		Bucket:          "file-bucket",
+		Endpoint:        "https://file.example.com",
+	}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	cfg, settings, err := LoadWithSources("")
+	if err != nil {
+		t.Fatalf("LoadWithSources() error = %v", err)
+	}
+
+	if cfg.Bucket != "env-bucket" {
+		t.Errorf("Bucket = %q, want env override", cfg.Bucket)
+	}
+	if cfg.AccessKeyID != "file-access-key" {
+		t.Errorf("AccessKeyID = %q, want value from file", cfg.AccessKeyID)
+	}
+
+	expected := map[string]Source{
+		"access_key_id": SourceFile,
+		"bucket":        SourceEnv,
+		"public_url":    SourceDefault,
+	}
+	for _, setting := range settings {
+		if want, ok := expected[setting.Name]; ok && setting.Source != want {
+			t.Errorf("%s source = %s, want %s", setting.Name, setting.Source, want)
+		}
+	}
+}
+
+func TestLoadFromEnvOnly(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() without file or environment should fail")
+	}
+
+	t.Setenv("S3_ACCESS_KEY_ID", "env-access-key")
+	t.Setenv("S3_SECRET_ACCESS_KEY", "env-secret-key")
+	t.Setenv("S3_BUCKET", "env-bucket")
+	t.Setenv("S3_ENDPOINT", "https://env.example.com")
+
+	if !EnvComplete() {
+		t.Fatal("EnvComplete() = false with all required variables set")
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Endpoint != "https://env.example.com" || cfg.PublicUrl != DefaultPublicUrl {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+
+	if err := Save(&Config{
+		AccessKeyID:     "file-access-key",
+		SecretAccessKey: "file-secret-key",
+		Bucket:          "file-bucket",
+		Endpoint:        "https://file.example.com",
+	}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if err := SetValue("", "key_template", "{{.Date}}/{{.Name}}"); err != nil {
+		t.Fatalf("SetValue() error = %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.KeyTemplate != "{{.Date}}/{{.Name}}" {
+		t.Errorf("KeyTemplate = %q", cfg.KeyTemplate)
+	}
+	if cfg.SecretAccessKey != "file-secret-key" {
+		t.Errorf("SecretAccessKey = %q, want it kept", cfg.SecretAccessKey)
+	}
+
+	for _, name := range []string{"secret_access_key", "nope"} {
+		if err := SetValue("", name, "x"); err == nil {
+			t.Errorf("SetValue(%q) succeeded, want error", name)
+		}
+	}
+	if err := SetValue("", "bucket", ""); err == nil {
+		t.Error("clearing a required setting succeeded, want error")
+	}
+}
+
+func TestHeaderDefaults(t *testing.T) {
+	defaults := &HeaderDefaults{
+		Headers: Headers{
+			CacheControl: "max-age=60",
+			Metadata:     map[string]string{"team": "infra"},
+		},
+		Extensions: map[string]Headers{
+			"pdf": {ContentDisposition: "attachment", Metadata: map[string]string{"kind": "doc"}},
+		},
+	}
+
+	pdf := defaults.For("reports/Q3.PDF")
+	if pdf.ContentDisposition != "attachment" || pdf.CacheControl != "max-age=60" {
+		t.Errorf("For(pdf) = %+v", pdf)
+	}
+	if pdf.Metadata["team"] != "infra" || pdf.Metadata["kind"] != "doc" {
+		t.Errorf("For(pdf) metadata = %v", pdf.Metadata)
+	}
+
+	png := defaults.For("photo.png").Merge(Headers{CacheControl: "no-cache"})
+	if png.ContentDisposition != "" || png.CacheControl != "no-cache" {
+		t.Errorf("For(png) = %+v", png)
+	}
+	if len(defaults.Metadata) != 1 {
+		t.Errorf("Merge changed the defaults: %v", defaults.Metadata)
+	}
+
+	var none *HeaderDefaults
+	if h := none.For("a.txt"); h.ContentType != "" {
+		t.Errorf("nil defaults gave %+v", h)
+	}
+}
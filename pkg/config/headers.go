@@ -0,0 +1,66 @@
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// Headers are HTTP headers and metadata objects are uploaded with. Empty
+// fields are left to the next source: per-extension rules override the
+// profile defaults, and command line options override both.
+type Headers struct {
+	ContentType        string            `json:"content_type,omitempty"`
+	ContentDisposition string            `json:"content_disposition,omitempty"`
+	CacheControl       string            `json:"cache_control,omitempty"`
+	ContentLanguage    string            `json:"content_language,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// HeaderDefaults are the headers of a profile, with rules keyed by file
+// extension without the dot, e.g. "pdf".
+type HeaderDefaults struct {
+	Headers
+	Extensions map[string]Headers `json:"extensions,omitempty"`
+}
+
+// Merge returns h with the non-empty fields of other applied on top.
+func (h Headers) Merge(other Headers) Headers {
+	if other.ContentType != "" {
+		h.ContentType = other.ContentType
+	}
+	if other.ContentDisposition != "" {
+		h.ContentDisposition = other.ContentDisposition
+	}
+	if other.CacheControl != "" {
+		h.CacheControl = other.CacheControl
+	}
+	if other.ContentLanguage != "" {
+		h.ContentLanguage = other.ContentLanguage
+	}
+	if len(other.Metadata) > 0 {
+		metadata := make(map[string]string, len(h.Metadata)+len(other.Metadata))
+		for k, v := range h.Metadata {
+			metadata[k] = v
+		}
+		for k, v := range other.Metadata {
+			metadata[k] = v
+		}
+		h.Metadata = metadata
+	}
+	return h
+}
+
+// For returns the headers for an object named name: the defaults merged
+// with the rule for its extension. d may be nil.
+func (d *HeaderDefaults) For(name string) Headers {
+	if d == nil {
+		return Headers{}
+	}
+
+	headers := d.Headers
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	if rule, ok := d.Extensions[ext]; ok && ext != "" {
+		headers = headers.Merge(rule)
+	}
+	return headers
+}
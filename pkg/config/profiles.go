@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/nizar0x1f/termup/pkg/secrets"
+)
+
+const DefaultProfileName = "default"
+
+// ProfileEnvVar selects the active profile when no profile is given
+// explicitly.
+const ProfileEnvVar = "TERMUP_PROFILE"
+
+// File is the on-disk layout of ~/.termup.json: a set of named profiles and
+// the one used when none is selected.
+type File struct {
+	DefaultProfile string             `json:"default_profile"`
+	SecretBackend  secrets.Backend    `json:"secret_backend,omitempty"`
+	Profiles       map[string]*Config `json:"profiles"`
+
+	removed      []string
+	store        secrets.Store
+	storeBackend secrets.Backend
+}
+
+// LoadFile reads the config file. Files written before profiles existed hold
+// a single flat config, which is loaded as the "default" profile. Plaintext
+// secrets left from older versions are moved to the secret store.
+func LoadFile() (*File, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	if f.Profiles == nil {
+		var legacy Config
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, err
+		}
+		f.Profiles = map[string]*Config{DefaultProfileName: &legacy}
+		f.DefaultProfile = DefaultProfileName
+	}
+
+	restrictPermissions(path)
+	migrateSecrets(&f)
+
+	return &f, nil
+}
+
+// loadFileOrEmpty is LoadFile, but returns an empty File if there is no
+// config file yet.
+func loadFileOrEmpty() (*File, error) {
+	f, err := LoadFile()
+	if os.IsNotExist(err) {
+		return &File{Profiles: map[string]*Config{}}, nil
+	}
+	return f, err
+}
+
+// SaveFile writes the config file with owner-only permissions, keeping
+// secrets in the configured secret store unless plaintext was chosen.
+func SaveFile(f *File) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	out, err := f.storeSecrets()
+	if err != nil {
+		return fmt.Errorf("failed to store secrets: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	restrictPermissions(path)
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// ActiveProfile resolves which profile to use: the explicit name if given,
+// then $TERMUP_PROFILE, then the file's default profile.
+func (f *File) ActiveProfile(name string) string {
+	if name != "" {
+		return name
+	}
+	if env := os.Getenv(ProfileEnvVar); env != "" {
+		return env
+	}
+	if f != nil && f.DefaultProfile != "" {
+		return f.DefaultProfile
+	}
+	return DefaultProfileName
+}
+
+func (f *File) Profile(name string) (*Config, error) {
+	cfg, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	return cfg, nil
+}
+
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetProfile adds or replaces a profile. The first profile added becomes the
+// default.
+func (f *File) SetProfile(name string, cfg *Config) {
+	if f.Profiles == nil {
+		f.Profiles = map[string]*Config{}
+	}
+	f.Profiles[name] = cfg
+	if f.DefaultProfile == "" || len(f.Profiles) == 1 {
+		f.DefaultProfile = name
+	}
+}
+
+func (f *File) RemoveProfile(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	delete(f.Profiles, name)
+	f.removed = append(f.removed, name)
+
+	if f.DefaultProfile == name {
+		f.DefaultProfile = ""
+		if names := f.ProfileNames(); len(names) > 0 {
+			f.DefaultProfile = names[0]
+		}
+	}
+	return nil
+}
+
+func (f *File) UseProfile(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	f.DefaultProfile = name
+	return nil
+}
+
+// SaveProfile stores cfg as the named profile, leaving other profiles
+// untouched. An empty name saves to the active profile.
+func SaveProfile(name string, cfg *Config) error {
+	f, err := loadFileOrEmpty()
+	if err != nil {
+		return err
+	}
+
+	f.SetProfile(f.ActiveProfile(name), cfg)
+	return SaveFile(f)
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLegacyConfigLoadsAsDefaultProfile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+
+	legacy := `{"access_key_id": "legacy-key", "secret_access_key": "legacy-secret", "bucket": "legacy-bucket", "endpoint": "https://legacy.example.com"}`
+	if err := os.WriteFile(filepath.Join(tmpDir, ".termup.json"), []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadFile()
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if f.DefaultProfile != DefaultProfileName {
+		t.Errorf("DefaultProfile = %q, want %q", f.DefaultProfile, DefaultProfileName)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Bucket != "legacy-bucket" {
+		t.Errorf("Bucket = %q, want legacy-bucket", cfg.Bucket)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+	t.Setenv(ProfileEnvVar, "")
+
+	if err := SaveProfile("r2", &Config{Bucket: "public-assets"}); err != nil {
+		t.Fatalf("SaveProfile() error = %v", err)
+	}
+	if err := SaveProfile("minio", &Config{Bucket: "internal-builds"}); err != nil {
+		t.Fatalf("SaveProfile() error = %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Bucket != "public-assets" {
+		t.Errorf("first profile should be the default, got bucket %q", cfg.Bucket)
+	}
+
+	cfg, err = LoadProfile("minio")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if cfg.Bucket != "internal-builds" {
+		t.Errorf("Bucket = %q, want internal-builds", cfg.Bucket)
+	}
+
+	t.Setenv(ProfileEnvVar, "minio")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Bucket != "internal-builds" {
+		t.Errorf("%s should select the profile, got bucket %q", ProfileEnvVar, cfg.Bucket)
+	}
+	t.Setenv(ProfileEnvVar, "")
+
+	if _, err := LoadProfile("missing"); err == nil {
+		t.Error("LoadProfile() of a missing profile should fail")
+	}
+
+	f, err := LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.UseProfile("minio"); err != nil {
+		t.Fatalf("UseProfile() error = %v", err)
+	}
+	if err := f.RemoveProfile("minio"); err != nil {
+		t.Fatalf("RemoveProfile() error = %v", err)
+	}
+	if f.DefaultProfile != "r2" {
+		t.Errorf("DefaultProfile = %q after removing the default, want r2", f.DefaultProfile)
+	}
+	if err := f.RemoveProfile("minio"); err == nil {
+		t.Error("RemoveProfile() of a missing profile should fail")
+	}
+}
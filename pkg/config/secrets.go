@@ -0,0 +1,168 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"runtime"
+
+	"github.com/nizar0x1f/termup/pkg/secrets"
+)
+
+func (f *File) secretStore() (secrets.Store, error) {
+	if f.store != nil && f.storeBackend == f.SecretBackend {
+		return f.store, nil
+	}
+
+	dir, err := StateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := secrets.Open(f.SecretBackend, dir)
+	if err != nil {
+		return nil, err
+	}
+	f.store, f.storeBackend = store, f.SecretBackend
+	return store, nil
+}
+
+// Secret returns the secret access key of a profile from wherever it is
+// kept, and where that is.
+func (f *File) Secret(name string) (string, Source, error) {
+	cfg, err := f.Profile(name)
+	if err != nil {
+		return "", SourceUnset, err
+	}
+	if cfg.SecretAccessKey != "" {
+		return cfg.SecretAccessKey, SourceFile, nil
+	}
+
+	store, err := f.secretStore()
+	if err != nil || store == nil {
+		return "", SourceUnset, err
+	}
+
+	secret, err := store.Get(name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return "", SourceUnset, nil
+	}
+	if err != nil {
+		return "", SourceUnset, err
+	}
+	return secret, Source(store.Name()), nil
+}
+
+// storeSecrets moves plaintext secrets of all profiles into the secret store
+// and removes the secrets of deleted profiles from it. It returns the file
+// as it should be written to disk.
+func (f *File) storeSecrets() (*File, error) {
+	out := &File{
+		DefaultProfile: f.DefaultProfile,
+		SecretBackend:  f.SecretBackend,
+		Profiles:       make(map[string]*Config, len(f.Profiles)),
+	}
+	for name, cfg := range f.Profiles {
+		c := *cfg
+		out.Profiles[name] = &c
+	}
+
+	store, err := f.secretStore()
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return out, nil
+	}
+
+	for name, cfg := range out.Profiles {
+		if cfg.SecretAccessKey == "" {
+			continue
+		}
+		if err := store.Set(name, cfg.SecretAccessKey); err != nil {
+			return nil, err
+		}
+		cfg.SecretAccessKey = ""
+	}
+
+	for _, name := range f.removed {
+		if err := store.Delete(name); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+			return nil, err
+		}
+	}
+	f.removed = nil
+
+	return out, nil
+}
+
+func (f *File) hasPlaintextSecrets() bool {
+	if f.SecretBackend == secrets.BackendPlaintext {
+		return false
+	}
+	for _, cfg := range f.Profiles {
+		if cfg.SecretAccessKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateSecrets rewrites a config file that still holds plaintext secrets
+// from before secret backends existed. It is best effort: when no secret
+// store can be opened (for example the encrypted file without a terminal to
+// ask for a passphrase) the file is left as it is.
+func migrateSecrets(f *File) {
+	if !f.hasPlaintextSecrets() {
+		return
+	}
+	if backend, err := secrets.Resolve(f.SecretBackend); err != nil || backend == secrets.BackendPlaintext {
+		return
+	}
+	_ = SaveFile(f)
+}
+
+// restrictPermissions makes sure an existing config file is only readable
+// by its owner.
+func restrictPermissions(path string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err == nil && info.Mode().Perm()&0o077 != 0 {
+		_ = os.Chmod(path, 0o600)
+	}
+}
+
+// SetSecretBackend moves the secrets of every profile to backend.
+func SetSecretBackend(backend secrets.Backend) error {
+	f, err := LoadFile()
+	if err != nil {
+		return err
+	}
+
+	oldStore, err := f.secretStore()
+	if err != nil {
+		return err
+	}
+
+	for name, cfg := range f.Profiles {
+		secret, _, err := f.Secret(name)
+		if err != nil {
+			return err
+		}
+		cfg.SecretAccessKey = secret
+	}
+
+	f.SecretBackend = backend
+	if err := SaveFile(f); err != nil {
+		return err
+	}
+
+	newStore, err := f.secretStore()
+	if err != nil || oldStore == nil || (newStore != nil && newStore.Name() == oldStore.Name()) {
+		return err
+	}
+	for name := range f.Profiles {
+		_ = oldStore.Delete(name)
+	}
+	return nil
+}
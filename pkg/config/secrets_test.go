@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/secrets"
+)
+
+func TestSaveKeepsSecretOutOfConfigFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+
+	if err := Save(&Config{AccessKeyID: "key", SecretAccessKey: "very-secret", Bucket: "bucket"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	path := filepath.Join(tmpDir, ".termup.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "very-secret") {
+		t.Errorf("config file contains the secret in plain text: %s", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("config file permissions = %o, want 600", perm)
+	}
+
+	cfg, settings, err := LoadWithSources("")
+	if err != nil {
+		t.Fatalf("LoadWithSources() error = %v", err)
+	}
+	if cfg.SecretAccessKey != "very-secret" {
+		t.Errorf("SecretAccessKey = %q, want very-secret", cfg.SecretAccessKey)
+	}
+	for _, setting := range settings {
+		if setting.Name == "secret_access_key" && setting.Source != "encrypted file" {
+			t.Errorf("secret source = %q, want encrypted file", setting.Source)
+		}
+	}
+}
+
+func TestPlaintextSecretsMigrate(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+
+	path := filepath.Join(tmpDir, ".termup.json")
+	legacy := `{"access_key_id": "key", "secret_access_key": "legacy-secret", "bucket": "bucket", "endpoint": "https://example.com"}`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SecretAccessKey != "legacy-secret" {
+		t.Errorf("SecretAccessKey = %q, want legacy-secret", cfg.SecretAccessKey)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "legacy-secret") {
+		t.Errorf("secret was not migrated out of the config file: %s", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("config file permissions = %o, want 600", perm)
+	}
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() after migration error = %v", err)
+	}
+	if cfg.SecretAccessKey != "legacy-secret" {
+		t.Errorf("SecretAccessKey after migration = %q, want legacy-secret", cfg.SecretAccessKey)
+	}
+}
+
+func TestSetSecretBackendPlaintext(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Setenv("HOME", tmpDir)
+	t.Setenv(secrets.BackendEnvVar, "")
+
+	f := &File{SecretBackend: secrets.BackendFile}
+	f.SetProfile("r2", &Config{SecretAccessKey: "r2-secret"})
+	if err := SaveFile(f); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+
+	if err := SetSecretBackend(secrets.BackendPlaintext); err != nil {
+		t.Fatalf("SetSecretBackend() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ".termup.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "r2-secret") {
+		t.Errorf("plaintext backend should keep the secret in the config file: %s", data)
+	}
+
+	cfg, err := LoadProfile("r2")
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if cfg.SecretAccessKey != "r2-secret" {
+		t.Errorf("SecretAccessKey = %q, want r2-secret", cfg.SecretAccessKey)
+	}
+}
@@ -0,0 +1,228 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+const fileName = "history.jsonl"
+
+// Entry is one upload in the history.
+type Entry struct {
+	ID       int       `json:"id"`
+	Time     time.Time `json:"time"`
+	Profile  string    `json:"profile"`
+	Endpoint string    `json:"endpoint"`
+	Bucket   string    `json:"bucket"`
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+
+	// Checksum is the hex SHA-256 of the uploaded content.
+	Checksum string `json:"checksum,omitempty"`
+
+	URL string `json:"url"`
+
+	// ExpiresAt is when URL stops working if it is a presigned link.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Deleted is set once the object has been deleted with termup.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Presigned reports whether the entry's URL is a presigned link.
+func (e *Entry) Presigned() bool {
+	return e.ExpiresAt != nil
+}
+
+// Expired reports whether the entry's presigned link no longer works.
+func (e *Entry) Expired(now time.Time) bool {
+	return e.ExpiresAt != nil && now.After(*e.ExpiresAt)
+}
+
+func path() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load returns all entries, oldest first.
+func Load() ([]Entry, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// A line cut short by a crash should not hide the rest.
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// Add appends entries to the history, numbering them after the last entry.
+func Add(entries ...Entry) error {
+	existing, err := Load()
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].ID + 1
+	}
+
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	for _, entry := range entries {
+		entry.ID = next
+		next++
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write history: %w", err)
+		}
+	}
+	return file.Close()
+}
+
+// Get returns the entry with id.
+func Get(id int) (*Entry, error) {
+	entries, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no history entry %d", id)
+}
+
+// Update changes the entry with id and rewrites the history.
+func Update(id int, change func(*Entry)) error {
+	entries, err := Load()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			change(&entries[i])
+			return write(entries)
+		}
+	}
+	return fmt.Errorf("no history entry %d", id)
+}
+
+// Clear removes all entries.
+func Clear() error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func write(entries []Entry) error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return os.Rename(tmp, p)
+}
+
+// Filter selects history entries. Zero fields match everything.
+type Filter struct {
+	// Query matches keys and URLs, case-insensitively.
+	Query   string
+	Profile string
+	Bucket  string
+	Since   time.Time
+	Limit   int
+}
+
+// Apply returns the entries that match f, newest first.
+func (f Filter) Apply(entries []Entry) []Entry {
+	query := strings.ToLower(f.Query)
+
+	var matched []Entry
+	for _, entry := range entries {
+		switch {
+		case f.Profile != "" && entry.Profile != f.Profile:
+		case f.Bucket != "" && entry.Bucket != f.Bucket:
+		case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		case query != "" && !strings.Contains(strings.ToLower(entry.Key), query) &&
+			!strings.Contains(strings.ToLower(entry.URL), query):
+		default:
+			matched = append(matched, entry)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Time.After(matched[j].Time)
+	})
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[:f.Limit]
+	}
+	return matched
+}
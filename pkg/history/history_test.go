@@ -0,0 +1,96 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+func init() {
+	// Tests point HOME at temporary directories.
+	homedir.DisableCache = true
+}
+
+func TestAddLoadUpdate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	entries, err := Load()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Load() on empty history = %v, %v", entries, err)
+	}
+
+	now := time.Now()
+	if err := Add(Entry{Time: now, Key: "a.png"}, Entry{Time: now, Key: "b.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(Entry{Time: now, Key: "c.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[2].ID != 3 || entries[2].Key != "c.png" {
+		t.Fatalf("Load() = %+v", entries)
+	}
+
+	if err := Update(2, func(e *Entry) { e.Deleted = true }); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := Get(2)
+	if err != nil || !entry.Deleted {
+		t.Errorf("Get(2) = %+v, %v, want deleted", entry, err)
+	}
+	if _, err := Get(9); err == nil {
+		t.Error("Get(9) succeeded, want error")
+	}
+
+	if err := Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := Load(); len(entries) != 0 {
+		t.Errorf("entries after Clear() = %+v", entries)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: 1, Time: base, Profile: "work", Bucket: "docs", Key: "reports/q1.pdf"},
+		{ID: 2, Time: base.Add(time.Hour), Profile: "home", Bucket: "pics", Key: "cat.png"},
+		{ID: 3, Time: base.Add(2 * time.Hour), Profile: "work", Bucket: "docs", Key: "reports/Q2.pdf"},
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []int
+	}{
+		{name: "all, newest first", expected: []int{3, 2, 1}},
+		{name: "query ignores case", filter: Filter{Query: "q2"}, expected: []int{3}},
+		{name: "profile", filter: Filter{Profile: "work"}, expected: []int{3, 1}},
+		{name: "bucket", filter: Filter{Bucket: "pics"}, expected: []int{2}},
+		{name: "since", filter: Filter{Since: base.Add(30 * time.Minute)}, expected: []int{3, 2}},
+		{name: "limit", filter: Filter{Limit: 1}, expected: []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.filter.Apply(entries)
+			ids := make([]int, len(got))
+			for i, entry := range got {
+				ids[i] = entry.ID
+			}
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("Apply() = %v, want %v", ids, tt.expected)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Fatalf("Apply() = %v, want %v", ids, tt.expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package keytemplate
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default keeps the file name, or the relative path for files uploaded as
+// part of a directory.
+const Default = "{{.Path}}"
+
+// Template builds object keys from a text/template such as
+// "{{.Date}}/{{.Rand8}}-{{.Name}}".
+type Template struct {
+	raw  string
+	tmpl *template.Template
+}
+
+// Parse parses a key template and checks that it only uses known
+// placeholders.
+func Parse(s string) (*Template, error) {
+	tmpl, err := template.New("key").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key template: %w", err)
+	}
+
+	t := &Template{raw: s, tmpl: tmpl}
+	if _, err := t.render(&Data{Path: "dir/example.txt", dryRun: true}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Render builds the key for a file. rel is the key the file would get
+// without a template (its name, or its path inside an uploaded directory);
+// localPath is read for {{.Hash}} and may be empty for streams.
+func (t *Template) Render(localPath, rel string) (string, error) {
+	return t.render(&Data{Path: rel, localPath: localPath, now: time.Now()})
+}
+
+func (t *Template) render(data *Data) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid key template: %w", err)
+	}
+
+	key := strings.TrimLeft(path.Clean("/"+b.String()), "/")
+	if key == "" || strings.HasSuffix(b.String(), "/") {
+		return "", fmt.Errorf("key template %q produced an invalid key %q", t.raw, b.String())
+	}
+	return key, nil
+}
+
+// Data holds the values available to a key template.
+type Data struct {
+	Path string
+
+	localPath string
+	now       time.Time
+	hash      string
+	dryRun    bool
+}
+
+func (d *Data) Name() string {
+	return path.Base(d.Path)
+}
+
+func (d *Data) Stem() string {
+	return strings.TrimSuffix(d.Name(), path.Ext(d.Name()))
+}
+
+func (d *Data) Ext() string {
+	return strings.TrimPrefix(path.Ext(d.Path), ".")
+}
+
+func (d *Data) Dir() string {
+	if dir := path.Dir(d.Path); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+func (d *Data) Date() string  { return d.now.Format("2006-01-02") }
+func (d *Data) Time() string  { return d.now.Format("150405") }
+func (d *Data) Year() string  { return d.now.Format("2006") }
+func (d *Data) Month() string { return d.now.Format("01") }
+func (d *Data) Day() string   { return d.now.Format("02") }
+func (d *Data) Unix() int64   { return d.now.Unix() }
+
+func (d *Data) User() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		// Windows usernames include the domain.
+		name := u.Username
+		if i := strings.LastIndexAny(name, `\/`); i >= 0 {
+			name = name[i+1:]
+		}
+		return name
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+func (d *Data) Host() string {
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "unknown"
+}
+
+func (d *Data) Rand8() (string, error)  { return randomHex(4) }
+func (d *Data) Rand16() (string, error) { return randomHex(8) }
+
+func (d *Data) Hash() (string, error) {
+	if d.dryRun {
+		return strings.Repeat("0", sha256.Size*2), nil
+	}
+	if d.hash != "" {
+		return d.hash, nil
+	}
+	if d.localPath == "" {
+		return "", errors.New("{{.Hash}} is not available when uploading from stdin")
+	}
+
+	file, err := os.Open(d.localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", d.localPath, err)
+	}
+	d.hash = hex.EncodeToString(h.Sum(nil))
+	return d.hash, nil
+}
+
+func (d *Data) Hash8() (string, error) {
+	hash, err := d.Hash()
+	if err != nil {
+		return "", err
+	}
+	return hash[:8], nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
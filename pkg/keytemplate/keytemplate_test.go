@@ -0,0 +1,67 @@
+package keytemplate
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "shot.png")
+	if err := os.WriteFile(file, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().Format("2006-01-02")
+
+	tests := []struct {
+		name     string
+		template string
+		rel      string
+		expected string
+	}{
+		{name: "default", template: Default, rel: "site/img/shot.png", expected: `^site/img/shot\.png$`},
+		{name: "name and date", template: "{{.Date}}/{{.Name}}", rel: "img/shot.png", expected: "^" + date + `/shot\.png$`},
+		{name: "random", template: "{{.Rand8}}-{{.Name}}", rel: "shot.png", expected: `^[0-9a-f]{8}-shot\.png$`},
+		{name: "stem and ext", template: "{{.Stem}}-copy.{{.Ext}}", rel: "shot.png", expected: `^shot-copy\.png$`},
+		{name: "content hash", template: "{{.Hash8}}.{{.Ext}}", rel: "shot.png", expected: `^2cf24dba\.png$`},
+		{name: "dir", template: "{{.Dir}}/{{.Rand8}}.{{.Ext}}", rel: "img/shot.png", expected: `^img/[0-9a-f]{8}\.png$`},
+		{name: "empty dir is cleaned", template: "{{.Dir}}/{{.Name}}", rel: "shot.png", expected: `^shot\.png$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			key, err := tmpl.Render(file, tt.rel)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if !regexp.MustCompile(tt.expected).MatchString(key) {
+				t.Errorf("Render() = %q, want match for %s", key, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{"{{.Nope}}", "{{.Name", "{{.Dir}}/", ""} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestHashWithoutFile(t *testing.T) {
+	tmpl, err := Parse("{{.Hash}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tmpl.Render("", "stdin"); err == nil {
+		t.Error("Render() without a file succeeded, want error")
+	}
+}
@@ -0,0 +1,451 @@
+package localfs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+// stateDir holds object metadata, unfinished multipart uploads and files
+// being written, next to the buckets in the root directory.
+const stateDir = ".termup"
+
+const defaultPageSize = 1000
+
+// Backend keeps the objects of a bucket as files under root/bucket. It
+// implements s3storage.Backend, so everything termup does with a bucket
+// works on a local directory too.
+type Backend struct {
+	// PublicURL is the base of the URLs returned for objects. Empty means
+	// file:// URLs of the files themselves.
+	PublicURL string
+
+	root   string
+	bucket string
+
+	// mu serializes the checks and renames of conditional writes.
+	mu sync.Mutex
+}
+
+var _ s3storage.Backend = (*Backend)(nil)
+
+// New returns a backend for bucket inside the directory root, which is
+// created if needed.
+func New(root, bucket string) (*Backend, error) {
+	if bucket == "" || bucket == stateDir || strings.ContainsAny(bucket, `/\`) || bucket == "." || bucket == ".." {
+		return nil, fmt.Errorf("invalid bucket name %q", bucket)
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	b := &Backend{root: root, bucket: bucket}
+
+	for _, dir := range []string{b.dataDir(), b.tmpDir(), b.uploadsDir()} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// objectMeta is the sidecar record of an object's headers.
+type objectMeta struct {
+	ETag               string            `json:"etag"`
+	ContentType        string            `json:"content_type,omitempty"`
+	ContentDisposition string            `json:"content_disposition,omitempty"`
+	CacheControl       string            `json:"cache_control,omitempty"`
+	ContentLanguage    string            `json:"content_language,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+
+	// PartSizes lists the part sizes of objects written by a multipart
+	// upload.
+	PartSizes []int64 `json:"part_sizes,omitempty"`
+}
+
+func newObjectMeta(etag string, opts *s3storage.WriteOptions) *objectMeta {
+	meta := &objectMeta{ETag: etag}
+	if opts != nil {
+		meta.ContentType = opts.ContentType
+		meta.ContentDisposition = opts.ContentDisposition
+		meta.CacheControl = opts.CacheControl
+		meta.ContentLanguage = opts.ContentLanguage
+		meta.Metadata = opts.Metadata
+	}
+	return meta
+}
+
+func (b *Backend) dataDir() string    { return filepath.Join(b.root, b.bucket) }
+func (b *Backend) metaDir() string    { return filepath.Join(b.root, stateDir, b.bucket, "objects") }
+func (b *Backend) uploadsDir() string { return filepath.Join(b.root, stateDir, b.bucket, "uploads") }
+func (b *Backend) tmpDir() string     { return filepath.Join(b.root, stateDir, "tmp") }
+
+// objectPath returns the file of key, rejecting keys that would escape the
+// bucket directory.
+func (b *Backend) objectPath(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") || strings.Contains(key, `\`) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid key %q", key)
+		}
+	}
+	return filepath.Join(b.dataDir(), filepath.FromSlash(key)), nil
+}
+
+func (b *Backend) metaPath(key string) string {
+	return filepath.Join(b.metaDir(), filepath.FromSlash(key)+".json")
+}
+
+func (b *Backend) Endpoint() string { return "file://" + filepath.ToSlash(b.root) }
+func (b *Backend) Bucket() string   { return b.bucket }
+
+func (b *Backend) URL(key string) string {
+	if b.PublicURL != "" {
+		return strings.TrimSuffix(b.PublicURL, "/") + "/" + key
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(b.dataDir(), filepath.FromSlash(key)))}
+	return u.String()
+}
+
+func (b *Backend) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", errors.New("presigned links are not supported by local storage")
+}
+
+func (b *Backend) Head(ctx context.Context, key string) (*s3storage.ObjectInfo, error) {
+	p, err := b.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && stat.IsDir()) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := b.readMeta(key, p)
+	if err != nil {
+		return nil, err
+	}
+	return &s3storage.ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ETag:         meta.ETag,
+		LastModified: stat.ModTime(),
+		ContentType:  meta.ContentType,
+		Metadata:     meta.Metadata,
+
+		ContentDisposition: meta.ContentDisposition,
+		CacheControl:       meta.CacheControl,
+		ContentLanguage:    meta.ContentLanguage,
+	}, nil
+}
+
+// readMeta returns the metadata of key, computing the ETag of files that
+// were put into the directory by other means.
+func (b *Backend) readMeta(key, file string) (*objectMeta, error) {
+	data, err := os.ReadFile(b.metaPath(key))
+	if err == nil {
+		var meta objectMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("invalid metadata for %s: %w", key, err)
+		}
+		return &meta, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return &objectMeta{ETag: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func (b *Backend) PartSize(ctx context.Context, key string, number int32) (int64, error) {
+	info, err := b.Head(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if info == nil {
+		return 0, fmt.Errorf("%w: %s", s3storage.ErrObjectNotFound, key)
+	}
+	p, _ := b.objectPath(key)
+	meta, err := b.readMeta(key, p)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(meta.PartSizes) == 0 && number == 1 {
+		return info.Size, nil
+	}
+	if number < 1 || int(number) > len(meta.PartSizes) {
+		return 0, fmt.Errorf("%s has no part %d", key, number)
+	}
+	return meta.PartSizes[number-1], nil
+}
+
+func (b *Backend) List(ctx context.Context, opts *s3storage.ListOptions) (*s3storage.Listing, error) {
+	var keys []string
+	err := filepath.WalkDir(b.dataDir(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.dataDir(), p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, opts.Prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	pageSize := int(opts.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	listing := &s3storage.Listing{}
+	var last string
+	for _, key := range keys {
+		entry := key
+		if !opts.Recursive {
+			if i := strings.Index(key[len(opts.Prefix):], "/"); i >= 0 {
+				entry = key[:len(opts.Prefix)+i+1]
+			}
+		}
+		// Token is the last key or prefix of the previous page.
+		if entry <= opts.Token || entry == last {
+			continue
+		}
+		if len(listing.Objects)+len(listing.Prefixes) == pageSize {
+			listing.NextToken = last
+			break
+		}
+		last = entry
+
+		if strings.HasSuffix(entry, "/") {
+			listing.Prefixes = append(listing.Prefixes, entry)
+			continue
+		}
+		info, err := b.Head(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			listing.Objects = append(listing.Objects, *info)
+		}
+	}
+	return listing, nil
+}
+
+func (b *Backend) Get(ctx context.Context, key string, offset, length int64, etag string) (io.ReadCloser, error) {
+	info, err := b.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("%w: %s", s3storage.ErrObjectNotFound, key)
+	}
+	if etag != "" && etag != info.ETag {
+		return nil, fmt.Errorf("%w: %s has changed", s3storage.ErrPreconditionFailed, key)
+	}
+
+	p, _ := b.objectPath(key)
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	return &sectionFile{SectionReader: io.NewSectionReader(file, offset, length), file: file}, nil
+}
+
+type sectionFile struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (f *sectionFile) Close() error {
+	return f.file.Close()
+}
+
+func (b *Backend) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, opts *s3storage.WriteOptions) (string, error) {
+	p, err := b.objectPath(key)
+	if err != nil {
+		return "", err
+	}
+
+	h := md5.New()
+	tmp, err := b.writeTemp(io.TeeReader(ctxReader{ctx, body}, h))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	etag := hex.EncodeToString(h.Sum(nil))
+	if err := b.commit(key, p, tmp, newObjectMeta(etag, opts), opts != nil && opts.IfNoneMatch); err != nil {
+		return "", err
+	}
+	return etag, nil
+}
+
+// writeTemp copies r to a new file in the temporary directory.
+func (b *Backend) writeTemp(r io.Reader) (string, error) {
+	file, err := os.CreateTemp(b.tmpDir(), "object-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// commit moves the finished file tmp into place as key along with its
+// metadata.
+func (b *Backend) commit(key, p, tmp string, meta *objectMeta, ifNoneMatch bool) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ifNoneMatch {
+		if _, err := os.Stat(p); err == nil {
+			return fmt.Errorf("%w: %s exists", s3storage.ErrPreconditionFailed, key)
+		}
+	}
+
+	metaPath := b.metaPath(key)
+	for _, dir := range []string{filepath.Dir(p), filepath.Dir(metaPath)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(metaPath+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		os.Remove(metaPath + ".tmp")
+		return fmt.Errorf("failed to save %s: %w", key, err)
+	}
+	return os.Rename(metaPath+".tmp", metaPath)
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	p, err := b.objectPath(key)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(b.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	removeEmptyParents(filepath.Dir(p), b.dataDir())
+	removeEmptyParents(filepath.Dir(b.metaPath(key)), b.metaDir())
+	return nil
+}
+
+// removeEmptyParents removes dir and its parents up to, but not including,
+// stop as long as they are empty.
+func removeEmptyParents(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (b *Backend) Copy(ctx context.Context, src, dst string) (string, error) {
+	srcPath, err := b.objectPath(src)
+	if err != nil {
+		return "", err
+	}
+	dstPath, err := b.objectPath(dst)
+	if err != nil {
+		return "", err
+	}
+	meta, err := b.readMeta(src, srcPath)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(srcPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", s3storage.ErrObjectNotFound, src)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	tmp, err := b.writeTemp(ctxReader{ctx, file})
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	if err := b.commit(dst, dstPath, tmp, meta, false); err != nil {
+		return "", err
+	}
+	return meta.ETag, nil
+}
+
+// ctxReader stops reading once ctx is cancelled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
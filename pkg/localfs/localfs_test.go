@@ -0,0 +1,173 @@
+package localfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+func newBackend(t *testing.T) *Backend {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	backend, err := New(t.TempDir(), "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	backend := newBackend(t)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 2*s3storage.MiB/16)
+	src := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, threshold := range map[string]int64{"single": 0, "multipart": s3storage.MiB} {
+		t.Run(name, func(t *testing.T) {
+			key := "data/" + name + ".bin"
+			result, err := s3storage.UploadFile(ctx, backend, src, &s3storage.UploadOptions{
+				Key:                key,
+				MultipartThreshold: threshold,
+				PartSize:           s3storage.MinPartSize,
+				Metadata:           map[string]string{"Team": "infra"},
+			}, func(int64) {})
+			if err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+
+			info, err := s3storage.Stat(ctx, backend, key)
+			if err != nil {
+				t.Fatalf("Stat() error = %v", err)
+			}
+			if info.Size != int64(len(content)) || info.ETag != result.ETag || info.Metadata["team"] != "infra" {
+				t.Errorf("Stat() = %+v, want size %d and ETag %s", info, len(content), result.ETag)
+			}
+
+			dest := filepath.Join(t.TempDir(), "out.bin")
+			downloaded, err := s3storage.Download(ctx, backend, key, dest, &s3storage.DownloadOptions{
+				MultipartThreshold: s3storage.MiB,
+				PartSize:           s3storage.MinPartSize,
+			}, func(int64) {})
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if downloaded.Checksum != "md5" {
+				t.Errorf("Download() checksum = %q, want md5", downloaded.Checksum)
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Error("downloaded content differs from the upload")
+			}
+		})
+	}
+}
+
+func TestMultipartETag(t *testing.T) {
+	backend := newBackend(t)
+	ctx := context.Background()
+
+	id, err := backend.CreateMultipart(ctx, "a.bin", &s3storage.WriteOptions{ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []s3storage.Part
+	for i, data := range []string{"hello ", "world"} {
+		etag, err := backend.UploadPart(ctx, "a.bin", id, int32(i+1), strings.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("UploadPart() error = %v", err)
+		}
+		parts = append(parts, s3storage.Part{Number: int32(i + 1), ETag: etag})
+	}
+
+	listed, err := backend.ListParts(ctx, "a.bin", id)
+	if err != nil || len(listed) != 2 || listed[1].ETag != parts[1].ETag {
+		t.Fatalf("ListParts() = %+v, %v", listed, err)
+	}
+
+	etag, err := backend.CompleteMultipart(ctx, "a.bin", id, parts, true)
+	if err != nil {
+		t.Fatalf("CompleteMultipart() error = %v", err)
+	}
+	// The MD5 of the part MD5s, as computed by S3.
+	first, second := md5.Sum([]byte("hello ")), md5.Sum([]byte("world"))
+	want := md5.Sum(append(first[:], second[:]...))
+	if expected := hex.EncodeToString(want[:]) + "-2"; etag != expected {
+		t.Errorf("CompleteMultipart() ETag = %q, want %q", etag, expected)
+	}
+	if size, err := backend.PartSize(ctx, "a.bin", 2); err != nil || size != 5 {
+		t.Errorf("PartSize() = %d, %v, want 5", size, err)
+	}
+
+	if _, err := backend.ListParts(ctx, "a.bin", id); !errors.Is(err, s3storage.ErrUploadNotFound) {
+		t.Errorf("ListParts() after completion error = %v, want ErrUploadNotFound", err)
+	}
+	if _, err := backend.Put(ctx, "a.bin", strings.NewReader("x"), 1, &s3storage.WriteOptions{IfNoneMatch: true}); !errors.Is(err, s3storage.ErrPreconditionFailed) {
+		t.Errorf("conditional Put() error = %v, want ErrPreconditionFailed", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	backend := newBackend(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"a.txt", "shots/1.png", "shots/2.png", "shots/old/3.png", "z.txt"} {
+		if _, err := backend.Put(ctx, key, strings.NewReader(key), int64(len(key)), &s3storage.WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := s3storage.ListPage(ctx, backend, &s3storage.ListOptions{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Key != "a.txt" || len(page.Prefixes) != 1 || page.Prefixes[0] != "shots/" || page.NextToken == "" {
+		t.Errorf("ListPage() = %+v", page)
+	}
+
+	all, err := s3storage.List(ctx, backend, &s3storage.ListOptions{Prefix: "shots/", Recursive: true, PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, object := range all.Objects {
+		keys = append(keys, object.Key)
+	}
+	if got := strings.Join(keys, ","); got != "shots/1.png,shots/2.png,shots/old/3.png" {
+		t.Errorf("List() keys = %s", got)
+	}
+
+	if _, err := s3storage.Move(ctx, backend, "shots/old/3.png", "archive/3.png", nil); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(backend.dataDir(), "shots", "old")); !os.IsNotExist(err) {
+		t.Error("empty directory was left behind")
+	}
+	if err := s3storage.Delete(ctx, backend, "missing"); !errors.Is(err, s3storage.ErrObjectNotFound) {
+		t.Errorf("Delete() error = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	backend := newBackend(t)
+	for _, key := range []string{"", "../escape", "a//b", "/abs", "dir/"} {
+		if _, err := backend.Put(context.Background(), key, strings.NewReader("x"), 1, &s3storage.WriteOptions{}); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package localfs
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+const uploadFile = "upload.json"
+
+// upload is the record of an unfinished multipart upload. Its parts are
+// files named by part number next to it.
+type upload struct {
+	Key       string                 `json:"key"`
+	Initiated time.Time              `json:"initiated"`
+	Options   s3storage.WriteOptions `json:"options"`
+}
+
+func (b *Backend) uploadDir(uploadID string) (string, error) {
+	if uploadID == "" || strings.ContainsAny(uploadID, `/\.`) {
+		return "", fmt.Errorf("%w: %s", s3storage.ErrUploadNotFound, uploadID)
+	}
+	return filepath.Join(b.uploadsDir(), uploadID), nil
+}
+
+func (b *Backend) readUpload(key, uploadID string) (string, *upload, error) {
+	dir, err := b.uploadDir(uploadID)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, uploadFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil, fmt.Errorf("%w: %s", s3storage.ErrUploadNotFound, uploadID)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	var u upload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return "", nil, fmt.Errorf("invalid multipart upload %s: %w", uploadID, err)
+	}
+	if u.Key != key {
+		return "", nil, fmt.Errorf("%w: %s", s3storage.ErrUploadNotFound, uploadID)
+	}
+	return dir, &u, nil
+}
+
+func (b *Backend) CreateMultipart(ctx context.Context, key string, opts *s3storage.WriteOptions) (string, error) {
+	if _, err := b.objectPath(key); err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	u := upload{Key: key, Initiated: time.Now().UTC()}
+	if opts != nil {
+		u.Options = *opts
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(b.uploadsDir(), uploadID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, uploadFile), data, 0o644); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+func (b *Backend) UploadPart(ctx context.Context, key, uploadID string, number int32, body io.ReadSeeker, size int64) (string, error) {
+	return b.writePart(ctx, key, uploadID, number, body)
+}
+
+func (b *Backend) CopyPart(ctx context.Context, key, uploadID string, number int32, src string, offset, size int64) (string, error) {
+	body, err := b.Get(ctx, src, offset, size, "")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	return b.writePart(ctx, key, uploadID, number, body)
+}
+
+func (b *Backend) writePart(ctx context.Context, key, uploadID string, number int32, r io.Reader) (string, error) {
+	if number < 1 || number > s3storage.MaxParts {
+		return "", fmt.Errorf("invalid part number %d", number)
+	}
+	dir, _, err := b.readUpload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	h := md5.New()
+	tmp, err := b.writeTemp(io.TeeReader(ctxReader{ctx, r}, h))
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, strconv.Itoa(int(number)))); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (b *Backend) ListParts(ctx context.Context, key, uploadID string) ([]s3storage.Part, error) {
+	dir, _, err := b.readUpload(key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []s3storage.Part
+	for _, entry := range entries {
+		number, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		etag, size, err := hashFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, s3storage.Part{Number: int32(number), ETag: etag, Size: size})
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	return parts, nil
+}
+
+func hashFile(p string) (string, int64, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	h := md5.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// CompleteMultipart joins the parts into the object. Its ETag is computed
+// like S3's: the MD5 of the part MD5s followed by the number of parts.
+func (b *Backend) CompleteMultipart(ctx context.Context, key, uploadID string, parts []s3storage.Part, ifNoneMatch bool) (string, error) {
+	p, err := b.objectPath(key)
+	if err != nil {
+		return "", err
+	}
+	dir, u, err := b.readUpload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return "", errors.New("a multipart upload needs at least one part")
+	}
+
+	file, err := os.CreateTemp(b.tmpDir(), "object-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	all := md5.New()
+	sizes := make([]int64, 0, len(parts))
+	for i, part := range parts {
+		if i > 0 && part.Number <= parts[i-1].Number {
+			return "", errors.New("parts must be in ascending order")
+		}
+		size, sum, err := appendPart(ctx, file, filepath.Join(dir, strconv.Itoa(int(part.Number))))
+		if err != nil {
+			return "", fmt.Errorf("invalid part %d: %w", part.Number, err)
+		}
+		if hex.EncodeToString(sum) != strings.Trim(part.ETag, `"`) {
+			return "", fmt.Errorf("invalid part %d: ETag does not match", part.Number)
+		}
+		all.Write(sum)
+		sizes = append(sizes, size)
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	meta := newObjectMeta(fmt.Sprintf("%s-%d", hex.EncodeToString(all.Sum(nil)), len(parts)), &u.Options)
+	meta.PartSizes = sizes
+	if err := b.commit(key, p, file.Name(), meta, ifNoneMatch); err != nil {
+		return "", err
+	}
+
+	_ = os.RemoveAll(dir)
+	return meta.ETag, nil
+}
+
+func appendPart(ctx context.Context, w io.Writer, p string) (int64, []byte, error) {
+	part, err := os.Open(p)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer part.Close()
+
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(w, h), ctxReader{ctx, part})
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, h.Sum(nil), nil
+}
+
+func (b *Backend) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	dir, _, err := b.readUpload(key, uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (b *Backend) ListMultipartUploads(ctx context.Context) ([]s3storage.MultipartUpload, error) {
+	entries, err := os.ReadDir(b.uploadsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	var uploads []s3storage.MultipartUpload
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(b.uploadsDir(), entry.Name(), uploadFile))
+		if err != nil {
+			continue
+		}
+		var u upload
+		if err := json.Unmarshal(data, &u); err != nil {
+			continue
+		}
+		uploads = append(uploads, s3storage.MultipartUpload{
+			Key:       u.Key,
+			UploadID:  entry.Name(),
+			Initiated: u.Initiated,
+		})
+	}
+	return uploads, nil
+}
@@ -0,0 +1,107 @@
+package s3server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// streamingUnsignedTrailer marks bodies sent in aws-chunked encoding with
+// the checksum in a trailer, as the SDKs do when they checksum uploads
+// without signing the payload.
+const streamingUnsignedTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+
+// chunkedReader decodes an aws-chunked body:
+//
+//	<hex size>\r\n<data>\r\n ... 0\r\n<trailer headers>\r\n
+type chunkedReader struct {
+	r         *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+func newChunkedReader(r io.Reader) *chunkedReader {
+	return &chunkedReader{r: bufio.NewReader(r)}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.remaining == 0 {
+		if err := c.nextChunk(); err != nil {
+			return 0, err
+		}
+		if c.done {
+			return 0, io.EOF
+		}
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	if err == nil && c.remaining == 0 {
+		err = c.readCRLF()
+	}
+	return n, err
+}
+
+// nextChunk reads the header of the next chunk, or the trailer after the
+// last one.
+func (c *chunkedReader) nextChunk() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	sizeField, _, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(sizeField, 16, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("invalid chunk size %q", sizeField)
+	}
+	if size > 0 {
+		c.remaining = size
+		return nil
+	}
+
+	// The checksums in the trailer are not verified.
+	for {
+		line, err := c.readLine()
+		if err == io.ErrUnexpectedEOF || (err == nil && line == "") {
+			c.done = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *chunkedReader) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err == io.EOF {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func (c *chunkedReader) readCRLF() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if line != "" {
+		return errors.New("missing end of chunk")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package s3server
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+// apiError is an S3 error response.
+type apiError struct {
+	Status  int    `xml:"-"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	errAccessDenied                      = &apiError{http.StatusForbidden, "AccessDenied", "Access Denied"}
+	errInvalidAccessKeyID                = &apiError{http.StatusForbidden, "InvalidAccessKeyId", "The access key ID you provided does not exist in our records."}
+	errSignatureDoesNotMatch             = &apiError{http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."}
+	errAuthorizationHeaderMalformed      = &apiError{http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header is malformed."}
+	errAuthorizationQueryParametersError = &apiError{http.StatusBadRequest, "AuthorizationQueryParametersError", "The presigned URL is malformed."}
+	errRequestTimeTooSkewed              = &apiError{http.StatusForbidden, "RequestTimeTooSkewed", "The difference between the request time and the server's time is too large."}
+	errExpiredRequest                    = &apiError{http.StatusForbidden, "AccessDenied", "Request has expired."}
+	errContentSHA256Mismatch             = &apiError{http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed."}
+	errNoSuchKey                         = &apiError{http.StatusNotFound, "NoSuchKey", "The specified key does not exist."}
+	errNoSuchUpload                      = &apiError{http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist."}
+	errPreconditionFailed                = &apiError{http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the preconditions you specified did not hold."}
+	errInvalidRange                      = &apiError{http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable."}
+	errInvalidBucketName                 = &apiError{http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid."}
+	errInvalidArgument                   = &apiError{http.StatusBadRequest, "InvalidArgument", "Invalid argument."}
+	errIncompleteBody                    = &apiError{http.StatusBadRequest, "IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header."}
+	errMalformedXML                      = &apiError{http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed."}
+	errNotImplemented                    = &apiError{http.StatusNotImplemented, "NotImplemented", "A header or operation you provided is not implemented."}
+)
+
+// toAPIError maps backend errors to S3 error responses.
+func toAPIError(err error) *apiError {
+	var apiErr *apiError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, s3storage.ErrObjectNotFound):
+		return errNoSuchKey
+	case errors.Is(err, s3storage.ErrUploadNotFound):
+		return errNoSuchUpload
+	case errors.Is(err, s3storage.ErrPreconditionFailed):
+		return errPreconditionFailed
+	}
+	return &apiError{http.StatusInternalServerError, "InternalError", err.Error()}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err *apiError) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(err.Status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		*apiError
+		Resource string `xml:"Resource"`
+	}{apiError: err, Resource: r.URL.Path})
+}
@@ -0,0 +1,636 @@
+// Package s3server serves a directory over a subset of the S3 API, enough
+// for termup to upload, download, list, copy and delete objects without a
+// cloud account.
+package s3server
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/localfs"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+var bucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// Server handles path-style S3 requests, e.g. PUT /bucket/key. Each bucket
+// is a directory kept by localfs and is created on first use. Requests must
+// be signed with Signature Version 4 using the server's credentials.
+type Server struct {
+	dir   string
+	creds Credentials
+
+	mu      sync.Mutex
+	buckets map[string]*localfs.Backend
+}
+
+// New returns a server for the buckets in dir.
+func New(dir string, creds Credentials) *Server {
+	return &Server{dir: dir, creds: creds, buckets: make(map[string]*localfs.Backend)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.creds.verify(r, time.Now()); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		writeError(w, r, errNotImplemented)
+		return
+	}
+	backend, apiErr := s.backend(bucket)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	var err error
+	switch {
+	case key == "":
+		err = s.serveBucket(w, r, backend)
+	case !validKey(key):
+		err = errInvalidArgument
+	default:
+		err = s.serveObject(w, r, backend, key)
+	}
+	if err != nil {
+		writeError(w, r, toAPIError(err))
+	}
+}
+
+// validKey reports whether key can be stored as a file: keys such as
+// "a/../b" or "dir/" are valid in S3 but not here.
+func validKey(key string) bool {
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return !strings.Contains(key, `\`)
+}
+
+func (s *Server) backend(bucket string) (*localfs.Backend, *apiError) {
+	if !bucketName.MatchString(bucket) {
+		return nil, errInvalidBucketName
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if backend, ok := s.buckets[bucket]; ok {
+		return backend, nil
+	}
+	backend, err := localfs.New(s.dir, bucket)
+	if err != nil {
+		return nil, toAPIError(err)
+	}
+	s.buckets[bucket] = backend
+	return backend, nil
+}
+
+func (s *Server) serveBucket(w http.ResponseWriter, r *http.Request, backend *localfs.Backend) error {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodHead, r.Method == http.MethodPut:
+		return nil
+	case r.Method == http.MethodGet && query.Has("uploads"):
+		return listMultipartUploads(w, r, backend)
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		return listObjects(w, r, backend)
+	}
+	return errNotImplemented
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet:
+		if query.Has("uploadId") {
+			return listParts(w, r, backend, key)
+		}
+		return getObject(w, r, backend, key)
+	case http.MethodHead:
+		return getObject(w, r, backend, key)
+	case http.MethodPut:
+		switch {
+		case query.Has("uploadId") && r.Header.Get("X-Amz-Copy-Source") != "":
+			return copyPart(w, r, backend, key)
+		case query.Has("uploadId"):
+			return uploadPart(w, r, backend, key)
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			return copyObject(w, r, backend, key)
+		}
+		return putObject(w, r, backend, key)
+	case http.MethodPost:
+		if query.Has("uploads") {
+			return createMultipart(w, r, backend, key)
+		}
+		if query.Has("uploadId") {
+			return completeMultipart(w, r, backend, key)
+		}
+	case http.MethodDelete:
+		if query.Has("uploadId") {
+			if err := backend.AbortMultipart(r.Context(), key, query.Get("uploadId")); err != nil {
+				return err
+			}
+		} else if err := backend.Delete(r.Context(), key); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return errNotImplemented
+}
+
+func getObject(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	info, err := backend.Head(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return errNoSuchKey
+	}
+	if match := r.Header.Get("If-Match"); match != "" && strings.Trim(match, `"`) != info.ETag {
+		return errPreconditionFailed
+	}
+
+	offset, length := int64(0), info.Size
+	status := http.StatusOK
+	if number := r.URL.Query().Get("partNumber"); number != "" {
+		n, err := strconv.ParseInt(number, 10, 32)
+		if err != nil {
+			return errInvalidArgument
+		}
+		// Objects have parts in the order they were uploaded, so part n
+		// starts after the sizes of the parts before it.
+		for i := int32(1); i < int32(n); i++ {
+			size, err := backend.PartSize(r.Context(), key, i)
+			if err != nil {
+				return errInvalidArgument
+			}
+			offset += size
+		}
+		if length, err = backend.PartSize(r.Context(), key, int32(n)); err != nil {
+			return errInvalidArgument
+		}
+		status = http.StatusPartialContent
+	} else if spec := r.Header.Get("Range"); spec != "" {
+		var ok bool
+		if offset, length, ok = parseRange(spec, info.Size); !ok {
+			return errInvalidRange
+		}
+		status = http.StatusPartialContent
+	}
+
+	header := w.Header()
+	header.Set("ETag", `"`+info.ETag+`"`)
+	header.Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("Content-Length", strconv.FormatInt(length, 10))
+	if info.ContentType != "" {
+		header.Set("Content-Type", info.ContentType)
+	} else {
+		header.Set("Content-Type", "binary/octet-stream")
+	}
+	for name, value := range map[string]string{
+		"Content-Disposition": info.ContentDisposition,
+		"Cache-Control":       info.CacheControl,
+		"Content-Language":    info.ContentLanguage,
+	} {
+		if value != "" {
+			header.Set(name, value)
+		}
+	}
+	for name, value := range info.Metadata {
+		header.Set("X-Amz-Meta-"+name, value)
+	}
+	if status == http.StatusPartialContent {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, info.Size))
+	}
+
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return nil
+	}
+	body, err := backend.Get(r.Context(), key, offset, length, info.ETag)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	w.WriteHeader(status)
+	_, _ = io.Copy(w, body)
+	return nil
+}
+
+// parseRange reads a single byte range header such as bytes=0-99,
+// bytes=100- or bytes=-100.
+func parseRange(spec string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(spec, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		n = min(n, size)
+		return size - n, n, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		end = min(end, size-1)
+	}
+	return start, end - start + 1, true
+}
+
+func putObject(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	body, size, err := requestBody(r)
+	if err != nil {
+		return err
+	}
+	opts := writeOptions(r)
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if match != "*" {
+			return errNotImplemented
+		}
+		opts.IfNoneMatch = true
+	}
+
+	etag, err := backend.Put(r.Context(), key, body, size, opts)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+	return nil
+}
+
+func copyObject(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	src, err := copySource(r, backend)
+	if err != nil {
+		return err
+	}
+	if info, err := backend.Head(r.Context(), src); err != nil {
+		return err
+	} else if info == nil {
+		return errNoSuchKey
+	}
+
+	etag, err := backend.Copy(r.Context(), src, key)
+	if err != nil {
+		return err
+	}
+	return writeXML(w, struct {
+		XMLName      xml.Name  `xml:"CopyObjectResult"`
+		ETag         string    `xml:"ETag"`
+		LastModified time.Time `xml:"LastModified"`
+	}{ETag: `"` + etag + `"`, LastModified: time.Now().UTC()})
+}
+
+// copySource returns the key named by the X-Amz-Copy-Source header, which
+// must be in the same bucket.
+func copySource(r *http.Request, backend *localfs.Backend) (string, error) {
+	source, err := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"))
+	if err != nil {
+		return "", errInvalidArgument
+	}
+	bucket, key, ok := strings.Cut(source, "/")
+	if !ok || key == "" {
+		return "", errInvalidArgument
+	}
+	if bucket != backend.Bucket() {
+		return "", errNotImplemented
+	}
+	return key, nil
+}
+
+func createMultipart(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	id, err := backend.CreateMultipart(r.Context(), key, writeOptions(r))
+	if err != nil {
+		return err
+	}
+	return writeXML(w, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}{Bucket: backend.Bucket(), Key: key, UploadID: id})
+}
+
+func partNumber(r *http.Request) (int32, error) {
+	n, err := strconv.ParseInt(r.URL.Query().Get("partNumber"), 10, 32)
+	if err != nil || n < 1 || n > s3storage.MaxParts {
+		return 0, errInvalidArgument
+	}
+	return int32(n), nil
+}
+
+func uploadPart(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	number, err := partNumber(r)
+	if err != nil {
+		return err
+	}
+	body, size, err := requestBody(r)
+	if err != nil {
+		return err
+	}
+
+	etag, err := backend.UploadPart(r.Context(), key, r.URL.Query().Get("uploadId"), number, body, size)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+	return nil
+}
+
+func copyPart(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	number, err := partNumber(r)
+	if err != nil {
+		return err
+	}
+	src, err := copySource(r, backend)
+	if err != nil {
+		return err
+	}
+	info, err := backend.Head(r.Context(), src)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return errNoSuchKey
+	}
+
+	offset, size := int64(0), info.Size
+	if spec := r.Header.Get("X-Amz-Copy-Source-Range"); spec != "" {
+		var ok bool
+		if offset, size, ok = parseRange(spec, info.Size); !ok {
+			return errInvalidRange
+		}
+	}
+
+	etag, err := backend.CopyPart(r.Context(), key, r.URL.Query().Get("uploadId"), number, src, offset, size)
+	if err != nil {
+		return err
+	}
+	return writeXML(w, struct {
+		XMLName      xml.Name  `xml:"CopyPartResult"`
+		ETag         string    `xml:"ETag"`
+		LastModified time.Time `xml:"LastModified"`
+	}{ETag: `"` + etag + `"`, LastModified: time.Now().UTC()})
+}
+
+func completeMultipart(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	var request struct {
+		Parts []struct {
+			PartNumber int32  `xml:"PartNumber"`
+			ETag       string `xml:"ETag"`
+		} `xml:"Part"`
+	}
+	body, _, err := requestBody(r)
+	if err != nil {
+		return err
+	}
+	if err := xml.NewDecoder(body).Decode(&request); err != nil {
+		return errMalformedXML
+	}
+
+	parts := make([]s3storage.Part, 0, len(request.Parts))
+	for _, part := range request.Parts {
+		parts = append(parts, s3storage.Part{Number: part.PartNumber, ETag: part.ETag})
+	}
+	ifNoneMatch := r.Header.Get("If-None-Match") == "*"
+
+	etag, err := backend.CompleteMultipart(r.Context(), key, r.URL.Query().Get("uploadId"), parts, ifNoneMatch)
+	if err != nil {
+		if errors.Is(err, s3storage.ErrUploadNotFound) || errors.Is(err, s3storage.ErrPreconditionFailed) {
+			return err
+		}
+		return &apiError{http.StatusBadRequest, "InvalidPart", err.Error()}
+	}
+	return writeXML(w, struct {
+		XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+		Location string   `xml:"Location"`
+		Bucket   string   `xml:"Bucket"`
+		Key      string   `xml:"Key"`
+		ETag     string   `xml:"ETag"`
+	}{Location: r.URL.Path, Bucket: backend.Bucket(), Key: key, ETag: `"` + etag + `"`})
+}
+
+type xmlPart struct {
+	PartNumber int32  `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+	Size       int64  `xml:"Size"`
+}
+
+func listParts(w http.ResponseWriter, r *http.Request, backend *localfs.Backend, key string) error {
+	id := r.URL.Query().Get("uploadId")
+	parts, err := backend.ListParts(r.Context(), key, id)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		XMLName     xml.Name  `xml:"ListPartsResult"`
+		Bucket      string    `xml:"Bucket"`
+		Key         string    `xml:"Key"`
+		UploadID    string    `xml:"UploadId"`
+		IsTruncated bool      `xml:"IsTruncated"`
+		Parts       []xmlPart `xml:"Part"`
+	}{Bucket: backend.Bucket(), Key: key, UploadID: id}
+	for _, part := range parts {
+		result.Parts = append(result.Parts, xmlPart{part.Number, `"` + part.ETag + `"`, part.Size})
+	}
+	return writeXML(w, result)
+}
+
+type xmlUpload struct {
+	Key       string    `xml:"Key"`
+	UploadID  string    `xml:"UploadId"`
+	Initiated time.Time `xml:"Initiated"`
+}
+
+func listMultipartUploads(w http.ResponseWriter, r *http.Request, backend *localfs.Backend) error {
+	uploads, err := backend.ListMultipartUploads(r.Context())
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		XMLName     xml.Name    `xml:"ListMultipartUploadsResult"`
+		Bucket      string      `xml:"Bucket"`
+		IsTruncated bool        `xml:"IsTruncated"`
+		Uploads     []xmlUpload `xml:"Upload"`
+	}{Bucket: backend.Bucket()}
+	for _, upload := range uploads {
+		result.Uploads = append(result.Uploads, xmlUpload{upload.Key, upload.UploadID, upload.Initiated})
+	}
+	return writeXML(w, result)
+}
+
+type xmlObject struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int64     `xml:"Size"`
+	StorageClass string    `xml:"StorageClass"`
+}
+
+type xmlPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+func listObjects(w http.ResponseWriter, r *http.Request, backend *localfs.Backend) error {
+	query := r.URL.Query()
+	delimiter := query.Get("delimiter")
+	if delimiter != "" && delimiter != "/" {
+		return errNotImplemented
+	}
+	maxKeys := 1000
+	if value := query.Get("max-keys"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return errInvalidArgument
+		}
+		maxKeys = min(n, 1000)
+	}
+
+	result := struct {
+		XMLName               xml.Name    `xml:"ListBucketResult"`
+		Name                  string      `xml:"Name"`
+		Prefix                string      `xml:"Prefix"`
+		Delimiter             string      `xml:"Delimiter,omitempty"`
+		MaxKeys               int         `xml:"MaxKeys"`
+		KeyCount              int         `xml:"KeyCount"`
+		IsTruncated           bool        `xml:"IsTruncated"`
+		ContinuationToken     string      `xml:"ContinuationToken,omitempty"`
+		NextContinuationToken string      `xml:"NextContinuationToken,omitempty"`
+		Contents              []xmlObject `xml:"Contents"`
+		CommonPrefixes        []xmlPrefix `xml:"CommonPrefixes"`
+	}{
+		Name:              backend.Bucket(),
+		Prefix:            query.Get("prefix"),
+		Delimiter:         delimiter,
+		MaxKeys:           maxKeys,
+		ContinuationToken: query.Get("continuation-token"),
+	}
+
+	if maxKeys > 0 {
+		listing, err := backend.List(r.Context(), &s3storage.ListOptions{
+			Prefix:    result.Prefix,
+			Recursive: delimiter == "",
+			PageSize:  int32(maxKeys),
+			Token:     max(result.ContinuationToken, query.Get("start-after")),
+		})
+		if err != nil {
+			return err
+		}
+		for _, object := range listing.Objects {
+			result.Contents = append(result.Contents, xmlObject{object.Key, object.LastModified.UTC(), `"` + object.ETag + `"`, object.Size, "STANDARD"})
+		}
+		for _, prefix := range listing.Prefixes {
+			result.CommonPrefixes = append(result.CommonPrefixes, xmlPrefix{prefix})
+		}
+		result.KeyCount = len(listing.Objects) + len(listing.Prefixes)
+		result.IsTruncated = listing.NextToken != ""
+		result.NextContinuationToken = listing.NextToken
+	}
+	return writeXML(w, result)
+}
+
+// writeOptions reads the headers an object is written with.
+func writeOptions(r *http.Request) *s3storage.WriteOptions {
+	opts := &s3storage.WriteOptions{
+		ContentType:        r.Header.Get("Content-Type"),
+		ContentDisposition: r.Header.Get("Content-Disposition"),
+		CacheControl:       r.Header.Get("Cache-Control"),
+		ContentLanguage:    r.Header.Get("Content-Language"),
+	}
+	for name, values := range r.Header {
+		if key := s3storage.MetadataKey(name); key != strings.ToLower(name) {
+			if opts.Metadata == nil {
+				opts.Metadata = make(map[string]string)
+			}
+			opts.Metadata[key] = values[0]
+		}
+	}
+	return opts
+}
+
+// requestBody returns the payload of r and its size, decoding aws-chunked
+// uploads and checking the body against a signed payload hash.
+func requestBody(r *http.Request) (*bodyReader, int64, error) {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	switch {
+	case payloadHash == streamingUnsignedTrailer:
+		size, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			return nil, 0, errInvalidArgument
+		}
+		return &bodyReader{r: newChunkedReader(r.Body), remaining: size}, size, nil
+	case strings.HasPrefix(payloadHash, "STREAMING-"):
+		return nil, 0, errNotImplemented
+	case r.ContentLength < 0:
+		return nil, 0, &apiError{http.StatusLengthRequired, "MissingContentLength", "You must provide the Content-Length HTTP header."}
+	case payloadHash == unsignedPayload || payloadHash == "":
+		return &bodyReader{r: r.Body, remaining: r.ContentLength}, r.ContentLength, nil
+	}
+	return &bodyReader{r: newHashCheckReader(r.Body, payloadHash), remaining: r.ContentLength}, r.ContentLength, nil
+}
+
+// bodyReader fails if the body is shorter or longer than announced. It
+// never seeks; the Seek method only satisfies the backend interface.
+type bodyReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, errIncompleteBody
+	}
+	if err == io.EOF && b.remaining > 0 {
+		return n, errIncompleteBody
+	}
+	return n, err
+}
+
+func (b *bodyReader) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("request bodies cannot seek")
+}
+
+func writeXML(w http.ResponseWriter, v any) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+	return nil
+}
@@ -0,0 +1,42 @@
+package s3server
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		spec           string
+		offset, length int64
+		ok             bool
+	}{
+		{"bytes=0-99", 0, 100, true},
+		{"bytes=100-", 100, 900, true},
+		{"bytes=-100", 900, 100, true},
+		{"bytes=900-2000", 900, 100, true},
+		{"bytes=1000-", 0, 0, false},
+		{"bytes=5-1", 0, 0, false},
+		{"bytes=0-1,5-6", 0, 0, false},
+		{"items=0-1", 0, 0, false},
+	}
+	for _, tt := range tests {
+		offset, length, ok := parseRange(tt.spec, 1000)
+		if offset != tt.offset || length != tt.length || ok != tt.ok {
+			t.Errorf("parseRange(%q) = %d, %d, %v, want %d, %d, %v", tt.spec, offset, length, ok, tt.offset, tt.length, tt.ok)
+		}
+	}
+}
+
+func TestChunkedReader(t *testing.T) {
+	body := "5\r\nhello\r\n6\r\n world\r\n0\r\nx-amz-checksum-crc32:DUoRhQ==\r\n\r\n"
+	got, err := io.ReadAll(newChunkedReader(strings.NewReader(body)))
+	if err != nil || string(got) != "hello world" {
+		t.Errorf("ReadAll() = %q, %v, want hello world", got, err)
+	}
+
+	if _, err := io.ReadAll(newChunkedReader(strings.NewReader("5\r\nhel"))); err == nil {
+		t.Error("ReadAll() of a truncated body succeeded, want an error")
+	}
+}
@@ -0,0 +1,269 @@
+package s3server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat  = "20060102T150405Z"
+
+	// maxClockSkew is how far the time a request was signed at may be from
+	// the server's clock.
+	maxClockSkew = 15 * time.Minute
+
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// Credentials are the access keys requests must be signed with.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// signature holds the parts of a Signature Version 4 signed request.
+type signature struct {
+	accessKey     string
+	scope         string
+	signedHeaders []string
+	signature     string
+	amzDate       time.Time
+	payloadHash   string
+	query         url.Values
+}
+
+// verify checks the Signature Version 4 signature of r, given either in the
+// Authorization header or, for presigned URLs, in the query string.
+func (c Credentials) verify(r *http.Request, now time.Time) *apiError {
+	var (
+		sig *signature
+		err *apiError
+	)
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		sig, err = parsePresigned(r, now)
+	} else {
+		sig, err = parseAuthorization(r, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	if sig.accessKey != c.AccessKeyID {
+		return errInvalidAccessKeyID
+	}
+
+	canonical := canonicalRequest(r, sig)
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		sig.amzDate.Format(amzDateFormat),
+		sig.scope,
+		hexSHA256([]byte(canonical)),
+	}, "\n")
+
+	scope := strings.Split(sig.scope, "/")
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), scope[0])
+	for _, part := range scope[1:] {
+		key = hmacSHA256(key, part)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return errSignatureDoesNotMatch
+	}
+	return nil
+}
+
+// parseAuthorization reads a header of the form
+//
+//	AWS4-HMAC-SHA256 Credential=AKID/20240101/auto/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=...
+func parseAuthorization(r *http.Request, now time.Time) (*signature, *apiError) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return nil, errAccessDenied
+	}
+	rest, ok := strings.CutPrefix(auth, sigV4Algorithm+" ")
+	if !ok {
+		return nil, errAuthorizationHeaderMalformed
+	}
+
+	fields := make(map[string]string)
+	for _, field := range strings.Split(rest, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, errAuthorizationHeaderMalformed
+		}
+		fields[name] = value
+	}
+
+	sig := &signature{
+		signedHeaders: strings.Split(fields["SignedHeaders"], ";"),
+		signature:     fields["Signature"],
+		payloadHash:   r.Header.Get("X-Amz-Content-Sha256"),
+	}
+	if err := sig.setCredential(fields["Credential"]); err != nil {
+		return nil, err
+	}
+
+	date, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, errAuthorizationHeaderMalformed
+	}
+	sig.amzDate = date
+	if date.Sub(now).Abs() > maxClockSkew {
+		return nil, errRequestTimeTooSkewed
+	}
+	if sig.payloadHash == "" {
+		return nil, errAuthorizationHeaderMalformed
+	}
+	return sig, nil
+}
+
+// parsePresigned reads the X-Amz-* query parameters of a presigned URL.
+func parsePresigned(r *http.Request, now time.Time) (*signature, *apiError) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != sigV4Algorithm {
+		return nil, errAuthorizationQueryParametersError
+	}
+
+	sig := &signature{
+		signedHeaders: strings.Split(query.Get("X-Amz-SignedHeaders"), ";"),
+		signature:     query.Get("X-Amz-Signature"),
+		payloadHash:   unsignedPayload,
+		query:         query,
+	}
+	if err := sig.setCredential(query.Get("X-Amz-Credential")); err != nil {
+		return nil, err
+	}
+
+	date, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, errAuthorizationQueryParametersError
+	}
+	sig.amzDate = date
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 {
+		return nil, errAuthorizationQueryParametersError
+	}
+	if now.Before(date.Add(-maxClockSkew)) {
+		return nil, errRequestTimeTooSkewed
+	}
+	if now.After(date.Add(time.Duration(expires) * time.Second)) {
+		return nil, errExpiredRequest
+	}
+	return sig, nil
+}
+
+func (s *signature) setCredential(credential string) *apiError {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[3] != "s3" || parts[4] != "aws4_request" {
+		return errAuthorizationHeaderMalformed
+	}
+	s.accessKey = parts[0]
+	s.scope = strings.Join(parts[1:], "/")
+	return nil
+}
+
+func canonicalRequest(r *http.Request, sig *signature) string {
+	path, rawQuery, _ := strings.Cut(r.RequestURI, "?")
+	if path == "" {
+		path = "/"
+	}
+
+	query, _ := url.ParseQuery(rawQuery)
+	query.Del("X-Amz-Signature")
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+
+	var headers strings.Builder
+	for _, name := range sig.signedHeaders {
+		var value string
+		if name == "host" {
+			value = r.Host
+		} else {
+			values := r.Header.Values(name)
+			for i := range values {
+				values[i] = strings.Join(strings.Fields(values[i]), " ")
+			}
+			value = strings.Join(values, ",")
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		path,
+		strings.Join(pairs, "&"),
+		headers.String(),
+		strings.Join(sig.signedHeaders, ";"),
+		sig.payloadHash,
+	}, "\n")
+}
+
+// uriEncode escapes everything except the unreserved characters of RFC 3986,
+// as Signature Version 4 requires.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashCheckReader fails at the end of the body if its SHA-256 is not the
+// one the request was signed with.
+type hashCheckReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected string
+}
+
+func newHashCheckReader(r io.Reader, expected string) *hashCheckReader {
+	return &hashCheckReader{r: r, hash: sha256.New(), expected: expected}
+}
+
+func (h *hashCheckReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(h.hash.Sum(nil)) != h.expected {
+		return n, errContentSHA256Mismatch
+	}
+	return n, err
+}
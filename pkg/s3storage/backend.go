@@ -0,0 +1,112 @@
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Backend is the storage service objects are kept in. Uploads, downloads,
+// copies and the other operations of this package are built on it; S3Backend
+// talks to S3-compatible services, and other implementations can keep
+// objects elsewhere.
+type Backend interface {
+	// Endpoint and Bucket identify where objects are stored. They tell
+	// apart the local state of resumable transfers.
+	Endpoint() string
+	Bucket() string
+
+	// URL returns the public URL of key.
+	URL(key string) string
+
+	// Presign returns a GET URL for key that is valid for expiry.
+	Presign(ctx context.Context, key string, expiry time.Duration) (string, error)
+
+	// Head describes the object stored under key, or returns nil if there
+	// is none.
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+
+	// PartSize returns the size of part number of an object that was
+	// uploaded in parts.
+	PartSize(ctx context.Context, key string, number int32) (int64, error)
+
+	// List returns one page of the objects under opts.Prefix.
+	List(ctx context.Context, opts *ListOptions) (*Listing, error)
+
+	// Get reads length bytes of the object from offset. A non-empty etag
+	// makes it fail with ErrPreconditionFailed if the object has changed.
+	Get(ctx context.Context, key string, offset, length int64, etag string) (io.ReadCloser, error)
+
+	// Put writes an object in one request and returns its ETag.
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64, opts *WriteOptions) (string, error)
+
+	Delete(ctx context.Context, key string) error
+
+	// Copy copies an object of at most MaxCopySize inside the bucket and
+	// returns the ETag of the copy.
+	Copy(ctx context.Context, src, dst string) (string, error)
+
+	// CreateMultipart starts a multipart upload and returns its ID.
+	CreateMultipart(ctx context.Context, key string, opts *WriteOptions) (string, error)
+
+	// UploadPart uploads part number and returns its ETag.
+	UploadPart(ctx context.Context, key, uploadID string, number int32, body io.ReadSeeker, size int64) (string, error)
+
+	// CopyPart uploads size bytes of src from offset as part number.
+	CopyPart(ctx context.Context, key, uploadID string, number int32, src string, offset, size int64) (string, error)
+
+	// ListParts returns the parts uploaded so far, or ErrUploadNotFound if
+	// the upload is gone.
+	ListParts(ctx context.Context, key, uploadID string) ([]Part, error)
+
+	// CompleteMultipart assembles the object from parts and returns its
+	// ETag. ifNoneMatch makes it fail with ErrPreconditionFailed if the key
+	// exists.
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part, ifNoneMatch bool) (string, error)
+
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+
+	// ListMultipartUploads returns the unfinished multipart uploads in the
+	// bucket.
+	ListMultipartUploads(ctx context.Context) ([]MultipartUpload, error)
+}
+
+// WriteOptions are the headers and conditions an object is written with.
+type WriteOptions struct {
+	ContentType        string
+	ContentDisposition string
+	CacheControl       string
+	ContentLanguage    string
+
+	// Metadata holds user metadata with normalized keys, see MetadataKey.
+	Metadata map[string]string
+
+	// IfNoneMatch makes the write fail with ErrPreconditionFailed if the
+	// key exists.
+	IfNoneMatch bool
+}
+
+// Part is an uploaded part of a multipart upload.
+type Part struct {
+	Number int32
+	ETag   string
+	Size   int64
+}
+
+// MultipartUpload is an unfinished multipart upload.
+type MultipartUpload struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+}
+
+var (
+	// ErrPreconditionFailed is returned when a conditional request finds
+	// the object in another state than required.
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	// ErrUploadNotFound is returned for multipart uploads that were
+	// completed or aborted.
+	ErrUploadNotFound = errors.New("multipart upload not found")
+)
@@ -0,0 +1,205 @@
+package s3storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// CollisionPolicy decides what happens when an object already exists under
+// the key of an upload.
+type CollisionPolicy string
+
+const (
+	CollisionFail      CollisionPolicy = "fail"
+	CollisionOverwrite CollisionPolicy = "overwrite"
+	CollisionRename    CollisionPolicy = "rename"
+	CollisionSkip      CollisionPolicy = "skip"
+	CollisionSkipSame  CollisionPolicy = "skip-if-same"
+
+	// CollisionAsk calls UploadOptions.OnCollision to decide.
+	CollisionAsk CollisionPolicy = "ask"
+)
+
+var CollisionPolicies = []CollisionPolicy{
+	CollisionFail, CollisionOverwrite, CollisionRename, CollisionSkip, CollisionSkipSame, CollisionAsk,
+}
+
+func ParseCollisionPolicy(s string) (CollisionPolicy, error) {
+	for _, policy := range CollisionPolicies {
+		if string(policy) == s {
+			return policy, nil
+		}
+	}
+
+	names := make([]string, len(CollisionPolicies))
+	for i, policy := range CollisionPolicies {
+		names[i] = string(policy)
+	}
+	return "", fmt.Errorf("unknown collision policy %q (expected %s)", s, strings.Join(names, ", "))
+}
+
+// maxRenames bounds the number of suffixes tried by CollisionRename.
+const maxRenames = 1000
+
+// ObjectExistsError is returned when the key of an upload is taken and the
+// collision policy does not allow replacing it.
+type ObjectExistsError struct {
+	Key string
+
+	// Different is set by CollisionSkipSame when the existing object has
+	// other content.
+	Different bool
+}
+
+func (e *ObjectExistsError) Error() string {
+	if e.Different {
+		return fmt.Sprintf("object %s already exists with different content", e.Key)
+	}
+	return fmt.Sprintf("object %s already exists", e.Key)
+}
+
+// contentCheck reports whether an existing object holds the content being
+// uploaded. It is nil when that cannot be known, as for streams.
+type contentCheck func(existing *ObjectInfo) (bool, error)
+
+// resolveCollision applies the collision policy to key. It returns the key
+// to upload to, or the existing object if the upload should be skipped.
+// conditional is set when the key was found free, so the write can be made
+// with If-None-Match to catch uploads racing with this one.
+func resolveCollision(ctx context.Context, backend Backend, key string, opts *UploadOptions, same contentCheck) (finalKey string, skip *ObjectInfo, conditional bool, err error) {
+	policy := opts.Collision
+	if policy == "" || policy == CollisionOverwrite {
+		return key, nil, false, nil
+	}
+
+	existing, err := backend.Head(ctx, key)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("failed to check for an existing object: %w", err)
+	}
+	if existing == nil {
+		return key, nil, true, nil
+	}
+
+	if policy == CollisionAsk {
+		policy = CollisionFail
+		if opts.OnCollision != nil {
+			policy = opts.OnCollision(existing)
+		}
+	}
+
+	switch policy {
+	case CollisionOverwrite:
+		return key, nil, false, nil
+
+	case CollisionSkip:
+		return key, existing, false, nil
+
+	case CollisionSkipSame:
+		if same == nil {
+			return "", nil, false, fmt.Errorf("%w, and its content cannot be compared", &ObjectExistsError{Key: key})
+		}
+		ok, err := same(existing)
+		if err != nil {
+			return "", nil, false, err
+		}
+		if !ok {
+			return "", nil, false, &ObjectExistsError{Key: key, Different: true}
+		}
+		return key, existing, false, nil
+
+	case CollisionRename:
+		for i := 1; i <= maxRenames; i++ {
+			candidate := renameKey(key, i)
+			existing, err := backend.Head(ctx, candidate)
+			if err != nil {
+				return "", nil, false, fmt.Errorf("failed to check for an existing object: %w", err)
+			}
+			if existing == nil {
+				return candidate, nil, true, nil
+			}
+		}
+		return "", nil, false, fmt.Errorf("no free key found for %s after %d attempts", key, maxRenames)
+	}
+
+	return "", nil, false, &ObjectExistsError{Key: key}
+}
+
+// renameKey adds a numbered suffix before the extension: "a/b.png" becomes
+// "a/b-1.png".
+func renameKey(key string, n int) string {
+	dir, name := path.Split(key)
+	ext := path.Ext(name)
+	return dir + strings.TrimSuffix(name, ext) + "-" + strconv.Itoa(n) + ext
+}
+
+// isConditionalUnsupported reports whether a write failed because the
+// provider does not implement If-None-Match.
+func isConditionalUnsupported(err error) bool {
+	return isHTTPStatus(err, http.StatusNotImplemented) || isErrorCode(err, "NotImplemented")
+}
+
+func isPreconditionFailed(err error) bool {
+	return isHTTPStatus(err, http.StatusPreconditionFailed) || isErrorCode(err, "PreconditionFailed")
+}
+
+// sameFileContent compares a local file with an existing object: by the
+// sha256 metadata if the object has it, otherwise by its ETag, which is the
+// MD5 of the content for single-part uploads and the MD5 of the part MD5s
+// for multipart uploads.
+func sameFileContent(file *os.File, size int64, opts *UploadOptions) contentCheck {
+	return func(existing *ObjectInfo) (bool, error) {
+		if existing.Size != size {
+			return false, nil
+		}
+
+		if sum := existing.Metadata["sha256"]; sum != "" {
+			h := sha256.New()
+			if _, err := io.Copy(h, io.NewSectionReader(file, 0, size)); err != nil {
+				return false, fmt.Errorf("failed to hash file: %w", err)
+			}
+			return hex.EncodeToString(h.Sum(nil)) == sum, nil
+		}
+
+		etag := existing.ETag
+		if _, count, ok := strings.Cut(etag, "-"); ok {
+			n, err := strconv.Atoi(count)
+			if err != nil || n <= 0 {
+				return false, nil
+			}
+			partSize := opts.partSize(size)
+			if int64(n) != (size+partSize-1)/partSize {
+				return false, nil
+			}
+			local, err := multipartETag(file, size, partSize)
+			return local == etag, err
+		}
+
+		h := md5.New()
+		if _, err := io.Copy(h, io.NewSectionReader(file, 0, size)); err != nil {
+			return false, fmt.Errorf("failed to hash file: %w", err)
+		}
+		return hex.EncodeToString(h.Sum(nil)) == etag, nil
+	}
+}
+
+func multipartETag(file io.ReaderAt, size, partSize int64) (string, error) {
+	parts := splitParts(size, partSize)
+	all := md5.New()
+	for _, part := range parts {
+		h := md5.New()
+		if _, err := io.Copy(h, io.NewSectionReader(file, part.offset, part.size)); err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+		all.Write(h.Sum(nil))
+	}
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(all.Sum(nil)), len(parts)), nil
+}
@@ -0,0 +1,129 @@
+package s3storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+func TestRenameKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		n        int
+		expected string
+	}{
+		{"photo.png", 1, "photo-1.png"},
+		{"shots/photo.png", 2, "shots/photo-2.png"},
+		{"README", 3, "README-3"},
+		{"dir.d/file", 1, "dir.d/file-1"},
+	}
+
+	for _, tt := range tests {
+		if got := renameKey(tt.key, tt.n); got != tt.expected {
+			t.Errorf("renameKey(%q, %d) = %q, want %q", tt.key, tt.n, got, tt.expected)
+		}
+	}
+}
+
+func TestParseCollisionPolicy(t *testing.T) {
+	for _, policy := range CollisionPolicies {
+		if got, err := ParseCollisionPolicy(string(policy)); err != nil || got != policy {
+			t.Errorf("ParseCollisionPolicy(%q) = %q, %v", policy, got, err)
+		}
+	}
+	if _, err := ParseCollisionPolicy("replace"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+// headServer answers HeadObject requests for the keys in objects with their
+// ETag and 404 for everything else.
+func headServer(t *testing.T, objects map[string]string) Backend {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		etag, ok := objects[key]
+		if r.Method != http.MethodHead || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", `"`+etag+`"`)
+		w.Header().Set("Content-Length", "5")
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	backend, err := NewS3Backend(context.Background(), &config.Config{
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		Bucket:          "bucket",
+		Endpoint:        server.URL,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend
+}
+
+func TestResolveCollision(t *testing.T) {
+	sum := md5.Sum([]byte("hello"))
+	backend := headServer(t, map[string]string{
+		"a.txt":   hex.EncodeToString(sum[:]),
+		"a-1.txt": "other",
+	})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tests := []struct {
+		name        string
+		key         string
+		policy      CollisionPolicy
+		wantKey     string
+		wantSkip    bool
+		conditional bool
+		wantErr     bool
+	}{
+		{name: "overwrite does not check", key: "a.txt", policy: CollisionOverwrite, wantKey: "a.txt"},
+		{name: "free key", key: "b.txt", policy: CollisionFail, wantKey: "b.txt", conditional: true},
+		{name: "fail", key: "a.txt", policy: CollisionFail, wantErr: true},
+		{name: "ask without callback fails", key: "a.txt", policy: CollisionAsk, wantErr: true},
+		{name: "rename", key: "a.txt", policy: CollisionRename, wantKey: "a-2.txt", conditional: true},
+		{name: "skip", key: "a-1.txt", policy: CollisionSkip, wantKey: "a-1.txt", wantSkip: true},
+		{name: "skip if same", key: "a.txt", policy: CollisionSkipSame, wantKey: "a.txt", wantSkip: true},
+		{name: "skip if same with other content", key: "a-1.txt", policy: CollisionSkipSame, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &UploadOptions{Collision: tt.policy}
+			key, existing, conditional, err := resolveCollision(context.Background(), backend, tt.key, opts, sameFileContent(file, 5, opts))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveCollision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if key != tt.wantKey || (existing != nil) != tt.wantSkip || conditional != tt.conditional {
+				t.Errorf("resolveCollision() = %q, skip %v, conditional %v", key, existing != nil, conditional)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package s3storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MaxCopySize is the largest object CopyObject can copy in one request;
+// larger objects are copied part by part.
+const MaxCopySize = 5 * 1024 * MiB
+
+type CopyOptions struct {
+	// Overwrite replaces an existing object at the destination. Otherwise
+	// the copy fails with an ObjectExistsError.
+	Overwrite bool
+}
+
+// Copy copies the object at src to dst inside the bucket without
+// downloading it. Metadata and headers are kept.
+func Copy(ctx context.Context, backend Backend, src, dst string, opts *CopyOptions) (*ObjectInfo, error) {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	return copyObject(ctx, backend, src, dst, opts)
+}
+
+// Move copies src to dst and then deletes src.
+func Move(ctx context.Context, backend Backend, src, dst string, opts *CopyOptions) (*ObjectInfo, error) {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	info, err := copyObject(ctx, backend, src, dst, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := Delete(ctx, backend, src); err != nil {
+		return nil, fmt.Errorf("copied to %s but could not remove the original: %w", dst, err)
+	}
+	return info, nil
+}
+
+func copyObject(ctx context.Context, backend Backend, src, dst string, opts *CopyOptions) (*ObjectInfo, error) {
+	if src == dst {
+		return nil, fmt.Errorf("source and destination are the same key: %s", src)
+	}
+
+	source, err := Stat(ctx, backend, src)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Overwrite {
+		existing, err := backend.Head(ctx, dst)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, &ObjectExistsError{Key: dst}
+		}
+	}
+
+	var etag string
+	if source.Size > MaxCopySize {
+		etag, err = copyMultipart(ctx, backend, source, dst)
+	} else {
+		etag, err = backend.Copy(ctx, src, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
+	}
+
+	copied := *source
+	copied.Key = dst
+	copied.ETag = etag
+	return &copied, nil
+}
+
+// copyMultipart copies objects too large for CopyObject part by part.
+func copyMultipart(ctx context.Context, backend Backend, source *ObjectInfo, dst string) (string, error) {
+	uploadID, err := backend.CreateMultipart(ctx, dst, &WriteOptions{
+		ContentType:        source.ContentType,
+		ContentDisposition: source.ContentDisposition,
+		CacheControl:       source.CacheControl,
+		ContentLanguage:    source.ContentLanguage,
+		Metadata:           source.Metadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart copy: %w", err)
+	}
+
+	parts := splitParts(source.Size, adjustPartSize(MaxCopySize/10, source.Size))
+	completed := make([]Part, 0, len(parts))
+	for _, part := range parts {
+		etag, err := backend.CopyPart(ctx, dst, uploadID, part.number, source.Key, part.offset, part.size)
+		if err != nil {
+			abortMultipart(ctx, backend, dst, uploadID)
+			return "", fmt.Errorf("failed to copy part %d: %w", part.number, err)
+		}
+		completed = append(completed, Part{Number: part.number, ETag: etag, Size: part.size})
+	}
+
+	etag, err := completeMultipart(ctx, backend, dst, uploadID, completed, false)
+	if err != nil {
+		abortMultipart(ctx, backend, dst, uploadID)
+		return "", err
+	}
+	return etag, nil
+}
+
+// copySource is the x-amz-copy-source value for key: the bucket and the
+// URL-escaped key.
+func copySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,424 @@
+package s3storage
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+// partialSuffix is added to the destination of a download until it is
+// complete.
+const partialSuffix = ".part"
+
+type DownloadOptions struct {
+	// PartSize is the size of each ranged GET. Zero means DefaultPartSize.
+	PartSize int64
+
+	// Concurrency is the number of ranges downloaded at once. Zero means
+	// DefaultConcurrency.
+	Concurrency int
+
+	// MultipartThreshold is the object size from which the download is
+	// split into ranges. Zero means DefaultMultipartThreshold.
+	MultipartThreshold int64
+
+	// Limiter caps the download rate of all ranges together. Nil means no
+	// limit.
+	Limiter *Limiter
+}
+
+func (o *DownloadOptions) partSize(size int64) int64 {
+	if size < o.multipartThreshold() {
+		return max(size, 1)
+	}
+	partSize := o.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	return adjustPartSize(partSize, size)
+}
+
+func (o *DownloadOptions) concurrency() int {
+	if o.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return o.Concurrency
+}
+
+func (o *DownloadOptions) multipartThreshold() int64 {
+	if o.MultipartThreshold <= 0 {
+		return DefaultMultipartThreshold
+	}
+	return o.MultipartThreshold
+}
+
+// DownloadResult describes a downloaded object.
+type DownloadResult struct {
+	Key  string
+	Path string
+	Size int64
+	ETag string
+
+	// Resumed is the number of bytes kept from an earlier, interrupted
+	// download.
+	Resumed int64
+
+	// Checksum names what the download was verified against: "sha256" for
+	// the sha256 metadata, "md5" for the ETag, or empty if the object had
+	// neither in a usable form.
+	Checksum string
+}
+
+// ChecksumMismatchError is returned when a downloaded file does not match
+// the object's checksum. The file is removed.
+type ChecksumMismatchError struct {
+	Key      string
+	Checksum string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("downloaded %s does not match its %s checksum", e.Key, e.Checksum)
+}
+
+// DownloadState is the on-disk record of an unfinished download.
+type DownloadState struct {
+	Path      string    `json:"path"`
+	Endpoint  string    `json:"endpoint"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	ETag      string    `json:"etag"`
+	Size      int64     `json:"size"`
+	PartSize  int64     `json:"part_size"`
+	Parts     []int32   `json:"parts"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	path string
+	mu   sync.Mutex
+}
+
+func (s *DownloadState) addPart(number int32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Parts = append(s.Parts, number)
+	return s.save()
+}
+
+func (s *DownloadState) save() error {
+	s.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *DownloadState) remove() {
+	_ = os.Remove(s.path)
+}
+
+func downloadStatePath(endpoint, bucket, key, dest string) (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "downloads")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{endpoint, bucket, key, dest}, "\x00")))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// loadDownloadState returns the state to continue a download with. The
+// partial file is kept only if it was written for the same version of the
+// object with the same part size.
+func loadDownloadState(backend Backend, info *ObjectInfo, dest, partial string, partSize int64) (*DownloadState, error) {
+	path, err := downloadStatePath(backend.Endpoint(), backend.Bucket(), info.Key, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh := &DownloadState{
+		Path:      dest,
+		Endpoint:  backend.Endpoint(),
+		Bucket:    backend.Bucket(),
+		Key:       info.Key,
+		ETag:      info.ETag,
+		Size:      info.Size,
+		PartSize:  partSize,
+		CreatedAt: time.Now(),
+		path:      path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fresh, nil
+	}
+	var state DownloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fresh, nil
+	}
+	state.path = path
+
+	stat, err := os.Stat(partial)
+	if err != nil || stat.Size() != info.Size || state.ETag != info.ETag ||
+		state.Size != info.Size || state.PartSize != partSize {
+		return fresh, nil
+	}
+	return &state, nil
+}
+
+// Download saves the object stored under key to dest. Large objects are
+// fetched as concurrent ranged GETs into dest plus ".part", which is renamed
+// to dest once the content has been verified. An interrupted download is
+// continued by downloading to the same dest again.
+func Download(ctx context.Context, backend Backend, key, dest string, opts *DownloadOptions, progressCallback ProgressCallback) (*DownloadResult, error) {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := backend.Head(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+
+	partSize := opts.partSize(info.Size)
+	partial := dest + partialSuffix
+
+	state, err := loadDownloadState(backend, info, dest, partial, partSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	if len(state.Parts) == 0 {
+		if err := file.Truncate(info.Size); err != nil {
+			return nil, fmt.Errorf("failed to create file: %w", err)
+		}
+	}
+
+	resumed, err := downloadParts(ctx, backend, info, file, state, opts.concurrency(), opts.Limiter, progressCallback)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := verifyDownload(ctx, backend, info, file)
+	if err != nil {
+		file.Close()
+		_ = os.Remove(partial)
+		state.remove()
+		return nil, err
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(partial, dest); err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+	state.remove()
+
+	return &DownloadResult{
+		Key:      key,
+		Path:     dest,
+		Size:     info.Size,
+		ETag:     info.ETag,
+		Resumed:  resumed,
+		Checksum: checksum,
+	}, nil
+}
+
+// downloadParts fetches the parts not yet recorded in state and returns the
+// number of bytes that were already there.
+func downloadParts(parent context.Context, backend Backend, info *ObjectInfo, file *os.File, state *DownloadState, concurrency int, limiter *Limiter, progressCallback ProgressCallback) (int64, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	done := make(map[int32]bool)
+	for _, number := range state.Parts {
+		done[number] = true
+	}
+
+	parts := splitParts(info.Size, state.PartSize)
+	var resumed int64
+	for _, part := range parts {
+		if done[part.number] {
+			resumed += part.size
+		}
+	}
+
+	tracker := &progressTracker{callback: progressCallback}
+	tracker.add(resumed)
+
+	// Single-range downloads are quick to redo and are not recorded.
+	multipart := len(parts) > 1
+
+	jobs := make(chan uploadPart)
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range jobs {
+				err := getRange(ctx, backend, info, file, part, limiter, tracker)
+				if err == nil && multipart {
+					err = state.addPart(part.number)
+				}
+
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to download part %d: %w", part.number, err)
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for _, part := range parts {
+		if ctx.Err() != nil {
+			break
+		}
+		if done[part.number] {
+			continue
+		}
+		jobs <- part
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return resumed, firstErr
+	}
+	return resumed, ctx.Err()
+}
+
+// getRange downloads one part into its place in file. It fails if the
+// object was replaced during the download.
+func getRange(ctx context.Context, backend Backend, info *ObjectInfo, file *os.File, part uploadPart, limiter *Limiter, tracker *progressTracker) error {
+	body, err := backend.Get(ctx, info.Key, part.offset, part.size, info.ETag)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return fmt.Errorf("%s changed during the download", info.Key)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	w := &offsetWriter{file: file, offset: part.offset, tracker: tracker}
+	n, err := io.Copy(w, limiter.limitReader(ctx, io.LimitReader(body, part.size)))
+	if err != nil {
+		tracker.add(-n)
+		return err
+	}
+	if n != part.size {
+		tracker.add(-n)
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+type offsetWriter struct {
+	file    *os.File
+	offset  int64
+	tracker *progressTracker
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	w.tracker.add(int64(n))
+	return n, err
+}
+
+// verifyDownload checks file against the object's sha256 metadata, or else
+// its ETag. Multipart ETags are recomputed with the size of the object's
+// first part. It returns the kind of checksum used, or "" if there was none
+// to check, such as the ETags of encrypted objects.
+func verifyDownload(ctx context.Context, backend Backend, info *ObjectInfo, file *os.File) (string, error) {
+	if sum := info.Metadata["sha256"]; sum != "" {
+		if hashFile(sha256.New(), file, info.Size) != sum {
+			return "", &ChecksumMismatchError{Key: info.Key, Checksum: "sha256"}
+		}
+		return "sha256", nil
+	}
+
+	etag, count, multipart := strings.Cut(info.ETag, "-")
+	if len(etag) != 32 {
+		return "", nil
+	}
+	if !multipart {
+		if hashFile(md5.New(), file, info.Size) != etag {
+			return "", &ChecksumMismatchError{Key: info.Key, Checksum: "md5"}
+		}
+		return "md5", nil
+	}
+
+	partSize, err := backend.PartSize(ctx, info.Key, 1)
+	if err != nil {
+		// Not every provider can describe single parts.
+		return "", nil
+	}
+	if partSize <= 0 || fmt.Sprint((info.Size+partSize-1)/partSize) != count {
+		return "", nil
+	}
+
+	local, err := multipartETag(file, info.Size, partSize)
+	if err != nil {
+		return "", err
+	}
+	if local != info.ETag {
+		return "", &ChecksumMismatchError{Key: info.Key, Checksum: "md5"}
+	}
+	return "md5", nil
+}
+
+func hashFile(h hash.Hash, file io.ReaderAt, size int64) string {
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, size)); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
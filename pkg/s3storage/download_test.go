@@ -0,0 +1,152 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+// objectServer serves one object at /bucket/<key> with Range and If-Match
+// support and counts the GET requests.
+func objectServer(t *testing.T, key string, content []byte, etag string) (*S3Backend, *atomic.Int32) {
+	t.Helper()
+
+	var gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bucket/"+key {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		w.Header().Set("ETag", `"`+etag+`"`)
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	backend, err := NewS3Backend(context.Background(), &config.Config{
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		Bucket:          "bucket",
+		Endpoint:        server.URL,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend, &gets
+}
+
+func TestDownload(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := md5.Sum(content)
+	backend, gets := objectServer(t, "data.bin", content, hex.EncodeToString(sum[:]))
+
+	opts := &DownloadOptions{PartSize: 1, MultipartThreshold: 1000}
+	dest := filepath.Join(t.TempDir(), "data.bin")
+
+	var last int64
+	result, err := Download(context.Background(), backend, "data.bin", dest, opts, func(n int64) { last = n })
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("downloaded content differs")
+	}
+	if result.Checksum != "md5" {
+		t.Errorf("Checksum = %q, want md5", result.Checksum)
+	}
+	if last != int64(len(content)) {
+		t.Errorf("progress = %d, want %d", last, len(content))
+	}
+	// The part size is raised to the 5 MiB minimum, so 10 KB is one range.
+	if n := gets.Load(); n != 1 {
+		t.Errorf("GET requests = %d, want 1", n)
+	}
+	if _, err := os.Stat(dest + partialSuffix); !os.IsNotExist(err) {
+		t.Error("partial file was not removed")
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	content := bytes.Repeat([]byte("abcdefghij"), MiB)
+	sum := md5.Sum(content)
+	etag := hex.EncodeToString(sum[:])
+	backend, gets := objectServer(t, "big.bin", content, etag)
+
+	opts := &DownloadOptions{PartSize: MinPartSize, MultipartThreshold: MinPartSize}
+	dest := filepath.Join(t.TempDir(), "big.bin")
+
+	// The first part is already on disk from an interrupted download.
+	partial := make([]byte, len(content))
+	copy(partial, content[:MinPartSize])
+	if err := os.WriteFile(dest+partialSuffix, partial, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	statePath, err := downloadStatePath(backend.Endpoint(), backend.Bucket(), "big.bin", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &DownloadState{ETag: etag, Size: int64(len(content)), PartSize: MinPartSize, Parts: []int32{1}, path: statePath}
+	if err := state.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Download(context.Background(), backend, "big.bin", dest, opts, nil)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if result.Resumed != MinPartSize {
+		t.Errorf("Resumed = %d, want %d", result.Resumed, MinPartSize)
+	}
+	if n, want := gets.Load(), int32(len(splitParts(int64(len(content)), MinPartSize))-1); n != want {
+		t.Errorf("GET requests = %d, want %d", n, want)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("downloaded content differs")
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Error("download state was not removed")
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	backend, _ := objectServer(t, "a.txt", []byte("hello"), strings.Repeat("0", 32))
+	dest := filepath.Join(t.TempDir(), "a.txt")
+
+	_, err := Download(context.Background(), backend, "a.txt", dest, nil, nil)
+	if _, ok := err.(*ChecksumMismatchError); !ok {
+		t.Fatalf("Download() error = %v, want ChecksumMismatchError", err)
+	}
+	for _, path := range []string{dest, dest + partialSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was left behind", path)
+		}
+	}
+}
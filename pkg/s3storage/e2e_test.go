@@ -0,0 +1,435 @@
+package s3storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+	"github.com/nizar0x1f/termup/pkg/s3server"
+	"github.com/nizar0x1f/termup/pkg/s3storage"
+)
+
+// These tests run the S3 code paths against s3server, the local
+// S3-compatible server behind upl serve-local.
+
+var testCredentials = s3server.Credentials{AccessKeyID: "test-key", SecretAccessKey: "test-secret"}
+
+func newLocalS3(t *testing.T, creds s3server.Credentials) *s3storage.S3Backend {
+	t.Helper()
+	return newLocalS3Handler(t, creds, func(h http.Handler) http.Handler { return h })
+}
+
+// newLocalS3Handler is newLocalS3 with the server's handler wrapped by
+// wrap, to inject failures.
+func newLocalS3Handler(t *testing.T, creds s3server.Credentials, wrap func(http.Handler) http.Handler) *s3storage.S3Backend {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	server := httptest.NewServer(wrap(s3server.New(t.TempDir(), testCredentials)))
+	t.Cleanup(server.Close)
+
+	backend, err := s3storage.NewS3Backend(context.Background(), &config.Config{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		Bucket:          "files",
+		Endpoint:        server.URL,
+		PublicUrl:       server.URL + "/files/",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend
+}
+
+func TestLocalS3UploadAndDownload(t *testing.T) {
+	backend := newLocalS3(t, testCredentials)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 6*s3storage.MiB/16)
+	src := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		threshold int64
+	}{
+		{"single", 0},
+		{"multipart", s3storage.MinPartSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "data/" + tt.name + ".bin"
+			result, err := s3storage.UploadFile(ctx, backend, src, &s3storage.UploadOptions{
+				Key:                key,
+				MultipartThreshold: tt.threshold,
+				PartSize:           s3storage.MinPartSize,
+				Metadata:           map[string]string{"team": "infra"},
+			}, nil)
+			if err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+
+			info, err := s3storage.Stat(ctx, backend, key)
+			if err != nil {
+				t.Fatalf("Stat() error = %v", err)
+			}
+			if info.Size != int64(len(content)) || info.ETag != result.ETag || info.Metadata["team"] != "infra" {
+				t.Errorf("Stat() = %+v, want size %d and ETag %s", info, len(content), result.ETag)
+			}
+
+			dest := filepath.Join(t.TempDir(), "out.bin")
+			downloaded, err := s3storage.Download(ctx, backend, key, dest, &s3storage.DownloadOptions{
+				MultipartThreshold: s3storage.MinPartSize,
+				PartSize:           s3storage.MinPartSize,
+			}, nil)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if downloaded.Checksum == "" {
+				t.Error("Download() was not verified")
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Error("downloaded content differs from the upload")
+			}
+		})
+	}
+}
+
+func TestLocalS3Stream(t *testing.T) {
+	backend := newLocalS3(t, testCredentials)
+	ctx := context.Background()
+
+	content := strings.Repeat("line of log output\n", 1000)
+	if _, err := s3storage.UploadStream(ctx, backend, strings.NewReader(content), &s3storage.UploadOptions{Key: "logs/app.log"}, nil); err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+
+	if _, err := s3storage.UploadStream(ctx, backend, strings.NewReader("again"), &s3storage.UploadOptions{
+		Key:       "logs/app.log",
+		Collision: s3storage.CollisionFail,
+	}, nil); err == nil {
+		t.Error("UploadStream() over an existing key succeeded, want an error")
+	}
+
+	url, err := s3storage.Presign(ctx, backend, "logs/app.log", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != content {
+		t.Errorf("GET presigned URL = %d with %d bytes, want the object", resp.StatusCode, len(body))
+	}
+
+	resp, err = http.Get(backend.URL("logs/app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unsigned GET = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestLocalS3Objects(t *testing.T) {
+	backend := newLocalS3(t, testCredentials)
+	ctx := context.Background()
+
+	for _, key := range []string{"a.txt", "shots/1.png", "shots/2.png", "shots/old/3.png"} {
+		if _, err := backend.Put(ctx, key, strings.NewReader(key), int64(len(key)), &s3storage.WriteOptions{}); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+
+	page, err := s3storage.ListPage(ctx, backend, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Key != "a.txt" || len(page.Prefixes) != 1 || page.Prefixes[0] != "shots/" {
+		t.Errorf("ListPage() = %+v", page)
+	}
+
+	all, err := s3storage.List(ctx, backend, &s3storage.ListOptions{Prefix: "shots/", Recursive: true, PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Objects) != 3 {
+		t.Errorf("List() returned %d objects, want 3", len(all.Objects))
+	}
+
+	if _, err := s3storage.Copy(ctx, backend, "a.txt", "b.txt", nil); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if _, err := s3storage.Copy(ctx, backend, "a.txt", "b.txt", nil); err == nil {
+		t.Error("Copy() onto an existing key succeeded, want an error")
+	}
+	if _, err := s3storage.Move(ctx, backend, "shots/old/3.png", "archive/3.png", nil); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if err := s3storage.Delete(ctx, backend, "b.txt"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s3storage.Stat(ctx, backend, "b.txt"); !errors.Is(err, s3storage.ErrObjectNotFound) {
+		t.Errorf("Stat() after Delete() error = %v, want ErrObjectNotFound", err)
+	}
+	if _, err := s3storage.Stat(ctx, backend, "shots/old/3.png"); !errors.Is(err, s3storage.ErrObjectNotFound) {
+		t.Errorf("Stat() after Move() error = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestLocalS3WrongCredentials(t *testing.T) {
+	backend := newLocalS3(t, s3server.Credentials{AccessKeyID: "test-key", SecretAccessKey: "wrong"})
+
+	_, err := s3storage.Stat(context.Background(), backend, "a.txt")
+	if !s3storage.IsAuthError(err) {
+		t.Errorf("Stat() error = %v, want an auth error", err)
+	}
+	_, err = backend.Put(context.Background(), "a.txt", strings.NewReader("x"), 1, &s3storage.WriteOptions{})
+	if !s3storage.IsAuthError(err) {
+		t.Errorf("Put() error = %v, want an auth error", err)
+	}
+}
+
+func TestLocalS3Cancel(t *testing.T) {
+	backend := newLocalS3(t, testCredentials)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4*s3storage.MinPartSize/16)
+	src := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := &s3storage.UploadOptions{
+		MultipartThreshold: s3storage.MinPartSize,
+		PartSize:           s3storage.MinPartSize,
+		Concurrency:        1,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := s3storage.UploadFile(ctx, backend, src, opts, func(uploaded int64) {
+		if uploaded > s3storage.MinPartSize {
+			cancel()
+		}
+	})
+	if !errors.Is(err, s3storage.ErrCancelled) || err.Error() != "upload was cancelled" {
+		t.Fatalf("UploadFile() error = %v, want ErrCancelled", err)
+	}
+
+	uploads, err := backend.ListMultipartUploads(context.Background())
+	if err != nil || len(uploads) != 1 {
+		t.Fatalf("ListMultipartUploads() = %+v, %v, want the upload kept", uploads, err)
+	}
+	pending, _ := s3storage.PendingUploads(backend)
+	if len(pending) != 1 || len(pending[0].Parts) == 0 {
+		t.Fatalf("PendingUploads() = %+v, want the finished parts saved", pending)
+	}
+
+	var first int64 = -1
+	if _, err := s3storage.UploadFile(context.Background(), backend, src, opts, func(uploaded int64) {
+		if first < 0 {
+			first = uploaded
+		}
+	}); err != nil {
+		t.Fatalf("UploadFile() after cancelling error = %v", err)
+	}
+	if first < s3storage.MinPartSize {
+		t.Errorf("second upload started at %d bytes, want it to resume after the saved parts", first)
+	}
+
+	dest := filepath.Join(t.TempDir(), "out.bin")
+	if _, err := s3storage.Download(context.Background(), backend, "big.bin", dest, nil, nil); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+		t.Error("resumed upload differs from the file")
+	}
+	if pending, _ := s3storage.PendingUploads(backend); len(pending) != 0 {
+		t.Errorf("PendingUploads() = %d after the upload, want none", len(pending))
+	}
+}
+
+func TestLocalS3FailWithoutParts(t *testing.T) {
+	backend := newLocalS3Handler(t, testCredentials, failPart("1", 1))
+
+	src := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(src, bytes.Repeat([]byte("x"), 2*s3storage.MinPartSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s3storage.UploadFile(context.Background(), backend, src, &s3storage.UploadOptions{
+		MultipartThreshold: s3storage.MinPartSize,
+		PartSize:           s3storage.MinPartSize,
+		Concurrency:        1,
+		Retry:              &s3storage.RetryPolicy{MaxAttempts: 1},
+	}, func(int64) {})
+	if err == nil {
+		t.Fatal("UploadFile() succeeded, want the failed first part to end it")
+	}
+
+	// Nothing can be resumed, so nothing is kept.
+	if uploads, err := backend.ListMultipartUploads(context.Background()); err != nil || len(uploads) != 0 {
+		t.Errorf("ListMultipartUploads() = %+v, %v, want the upload aborted", uploads, err)
+	}
+	if pending, _ := s3storage.PendingUploads(backend); len(pending) != 0 {
+		t.Errorf("PendingUploads() = %d, want none", len(pending))
+	}
+}
+
+// failPart answers the first failures uploads of part with 503 Service
+// Unavailable.
+func failPart(part string, failures int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		var mu sync.Mutex
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			fail := r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == part && failures > 0
+			if fail {
+				failures--
+			}
+			mu.Unlock()
+			if fail {
+				_, _ = io.Copy(io.Discard, r.Body)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestLocalS3Retry(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 3*s3storage.MinPartSize)
+	src := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(src, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		retries     []s3storage.PartRetry
+		wantErr     bool
+	}{
+		{"no failures", 0, 3, nil, false},
+		{"retried part", 2, 3, []s3storage.PartRetry{{Part: 2, Attempts: 3}}, false},
+		{"out of attempts", 3, 3, nil, true},
+		{"retries disabled", 1, 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newLocalS3Handler(t, testCredentials, failPart("2", tt.failures))
+
+			var events []s3storage.RetryEvent
+			var uploaded int64
+			result, err := s3storage.UploadFile(context.Background(), backend, src, &s3storage.UploadOptions{
+				MultipartThreshold: s3storage.MinPartSize,
+				PartSize:           s3storage.MinPartSize,
+				Concurrency:        1,
+				Retry:              &s3storage.RetryPolicy{MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond},
+				OnRetry:            func(e s3storage.RetryEvent) { events = append(events, e) },
+			}, func(n int64) { uploaded = n })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(result.Retries, tt.retries) {
+				t.Errorf("Retries = %+v, want %+v", result.Retries, tt.retries)
+			}
+			if len(events) != tt.failures {
+				t.Errorf("OnRetry was called %d times, want %d", len(events), tt.failures)
+			}
+			for i, e := range events {
+				if e.Part != 2 || e.Attempt != i+2 || e.MaxAttempts != tt.maxAttempts {
+					t.Errorf("RetryEvent = %+v, want part 2 attempt %d/%d", e, i+2, tt.maxAttempts)
+				}
+			}
+			if uploaded != int64(len(content)) {
+				t.Errorf("progress = %d, want %d", uploaded, len(content))
+			}
+			if info, err := s3storage.Stat(context.Background(), backend, "big.bin"); err != nil || info.Size != int64(len(content)) {
+				t.Errorf("Stat() = %+v, %v, want the whole file", info, err)
+			}
+		})
+	}
+}
+
+func TestLocalS3Limit(t *testing.T) {
+	backend := newLocalS3(t, testCredentials)
+
+	src := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(src, bytes.Repeat([]byte("x"), 3*s3storage.MinPartSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err := s3storage.UploadFile(context.Background(), backend, src, &s3storage.UploadOptions{
+		MultipartThreshold: s3storage.MinPartSize,
+		PartSize:           s3storage.MinPartSize,
+		Concurrency:        3,
+		Limiter:            s3storage.NewLimiter(30 * s3storage.MiB),
+	}, func(int64) {})
+	if err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	// 15 MiB at 30 MiB/s, shared by three parts in flight.
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("UploadFile() took %v, want at least 450ms", elapsed)
+	}
+}
+
+func TestLocalS3DownloadLimit(t *testing.T) {
+	backend := newLocalS3(t, testCredentials)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("x"), 3*s3storage.MinPartSize)
+	if _, err := s3storage.UploadStream(ctx, backend, bytes.NewReader(content), &s3storage.UploadOptions{Key: "big.bin"}, nil); err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "big.bin")
+	start := time.Now()
+	_, err := s3storage.Download(ctx, backend, "big.bin", dest, &s3storage.DownloadOptions{
+		MultipartThreshold: s3storage.MinPartSize,
+		PartSize:           s3storage.MinPartSize,
+		Concurrency:        3,
+		Limiter:            s3storage.NewLimiter(30 * s3storage.MiB),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	// 15 MiB at 30 MiB/s, shared by three ranges in flight.
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("Download() took %v, want at least 450ms", elapsed)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("downloaded content differs from the upload")
+	}
+}
@@ -0,0 +1,61 @@
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// ErrCancelled is returned by uploads whose context was cancelled. A
+// cancelled multipart upload of a file can be resumed; one of a stream is
+// aborted.
+var ErrCancelled = errors.New("upload was cancelled")
+
+// cancelled returns ErrCancelled in place of err if ctx was cancelled,
+// which is what made the upload fail then.
+func cancelled(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return ErrCancelled
+	}
+	return err
+}
+
+// IsAuthError reports whether the service rejected the credentials or
+// denied access.
+func IsAuthError(err error) bool {
+	return isHTTPStatus(err, http.StatusUnauthorized) ||
+		isHTTPStatus(err, http.StatusForbidden) ||
+		isErrorCode(err, "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "InvalidToken", "ExpiredToken")
+}
+
+// IsNetworkError reports whether err is a failure to reach the service,
+// such as a DNS error, a refused connection or a timeout.
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isHTTPStatus(err error, status int) bool {
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == status
+}
+
+func isErrorCode(err error, codes ...string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.ErrorCode() == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package s3storage
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// detectContentType guesses the MIME type of an object from the extension
+// of name, falling back to sniffing the start of its content.
+func detectContentType(name string, content io.ReaderAt) string {
+	if ext := path.Ext(name); ext != "" {
+		if contentType := mime.TypeByExtension(strings.ToLower(ext)); contentType != "" {
+			return contentType
+		}
+	}
+
+	buf := make([]byte, sniffLen)
+	n, err := content.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// contentDisposition expands "inline" and "attachment" into a header that
+// carries filename; other values are used as given.
+func contentDisposition(value, filename string) string {
+	switch value {
+	case "inline", "attachment":
+		if filename == "" {
+			return value
+		}
+		if formatted := mime.FormatMediaType(value, map[string]string{"filename": filename}); formatted != "" {
+			return formatted
+		}
+	}
+	return value
+}
+
+// writeOptions returns the headers and conditions an upload writes its
+// object with.
+func (o *UploadOptions) writeOptions() *WriteOptions {
+	w := &WriteOptions{
+		ContentType:        o.ContentType,
+		ContentDisposition: contentDisposition(o.ContentDisposition, o.filename),
+		CacheControl:       o.CacheControl,
+		ContentLanguage:    o.ContentLanguage,
+		IfNoneMatch:        o.conditional,
+	}
+	if len(o.Metadata) > 0 {
+		w.Metadata = make(map[string]string, len(o.Metadata))
+		for key, value := range o.Metadata {
+			w.Metadata[MetadataKey(key)] = value
+		}
+	}
+	return w
+}
+
+// MetadataKey normalizes a user metadata key: S3 stores them lowercase and
+// adds the x-amz-meta- prefix itself.
+func MetadataKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return strings.TrimPrefix(key, "x-amz-meta-")
+}
@@ -0,0 +1,58 @@
+package s3storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"report.pdf", "", "application/pdf"},
+		{"PHOTO.PNG", "", "image/png"},
+		{"notes", "just some text", "text/plain; charset=utf-8"},
+		{"image.unknownext", "\x89PNG\r\n\x1a\n", "image/png"},
+		{"blob", "\x00\x01\x02", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		if got := detectContentType(tt.name, strings.NewReader(tt.content)); got != tt.expected {
+			t.Errorf("detectContentType(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		value    string
+		filename string
+		expected string
+	}{
+		{"", "a.pdf", ""},
+		{"inline", "a.pdf", "inline; filename=a.pdf"},
+		{"attachment", "my report.pdf", `attachment; filename="my report.pdf"`},
+		{"attachment", "résumé.pdf", "attachment; filename*=utf-8''r%C3%A9sum%C3%A9.pdf"},
+		{`attachment; filename="x.bin"`, "a.pdf", `attachment; filename="x.bin"`},
+	}
+
+	for _, tt := range tests {
+		if got := contentDisposition(tt.value, tt.filename); got != tt.expected {
+			t.Errorf("contentDisposition(%q, %q) = %q, want %q", tt.value, tt.filename, got, tt.expected)
+		}
+	}
+}
+
+func TestMetadataKey(t *testing.T) {
+	for input, expected := range map[string]string{
+		"Team":             "team",
+		"x-amz-meta-Owner": "owner",
+		" build-id ":       "build-id",
+	} {
+		if got := MetadataKey(input); got != expected {
+			t.Errorf("MetadataKey(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
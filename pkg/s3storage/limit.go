@@ -0,0 +1,133 @@
+package s3storage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// limitChunk is the most a limited reader reads at once, which keeps the
+// waits short and lets a new rate take effect quickly.
+const limitChunk = 32 * 1024
+
+// Limiter caps the combined rate of the transfers that share it, such as the
+// parts of a multipart upload, the files of a batch or the ranges of a
+// download, with a token bucket. The rate can be changed while transfers are
+// running.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter allowing bytesPerSecond. Zero means no
+// limit.
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	return &Limiter{rate: max(bytesPerSecond, 0), last: time.Now()}
+}
+
+// Rate returns the limit in bytes per second, or zero if there is none.
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// SetRate changes the limit; zero removes it.
+func (l *Limiter) SetRate(bytesPerSecond int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = max(bytesPerSecond, 0)
+	l.tokens = min(l.tokens, l.burst())
+}
+
+// burst is how much the bucket holds: a tenth of a second's worth, so
+// that a paused upload cannot catch up in one go.
+func (l *Limiter) burst() float64 {
+	return max(float64(l.rate)/10, limitChunk)
+}
+
+func (l *Limiter) refill(now time.Time) {
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*float64(l.rate), l.burst())
+	l.last = now
+}
+
+// wait takes n bytes from the bucket, waiting for them if it is empty.
+// Waiters queue up by going into debt, each sleeping until its share is
+// paid off.
+func (l *Limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	l.refill(now)
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedReader reads from reader no faster than limiter allows.
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *Limiter
+}
+
+// limitedReadSeeker is a limitedReader that can be rewound, for request
+// bodies.
+type limitedReadSeeker struct {
+	limitedReader
+	seeker io.Seeker
+}
+
+// limit wraps body so that reading it is rate limited, unless l is nil.
+func (l *Limiter) limit(ctx context.Context, body io.ReadSeeker) io.ReadSeeker {
+	if l == nil {
+		return body
+	}
+	return &limitedReadSeeker{limitedReader{ctx: ctx, reader: body, limiter: l}, body}
+}
+
+// limitReader is limit for readers that cannot seek, such as response
+// bodies.
+func (l *Limiter) limitReader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, reader: r, limiter: l}
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > limitChunk {
+		p = p[:limitChunk]
+	}
+	n, err := lr.reader.Read(p)
+	if waitErr := lr.limiter.wait(lr.ctx, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+func (lr *limitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return lr.seeker.Seek(offset, whence)
+}
@@ -0,0 +1,72 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    int64
+		readers int
+		minTime time.Duration
+	}{
+		{"unlimited", 0, 1, 0},
+		{"one reader", 4 * MiB, 1, 150 * time.Millisecond},
+		{"shared", 8 * MiB, 4, 350 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewLimiter(tt.rate)
+			start := time.Now()
+
+			done := make(chan error, tt.readers)
+			for i := 0; i < tt.readers; i++ {
+				go func() {
+					r := limiter.limit(context.Background(), bytes.NewReader(make([]byte, MiB)))
+					_, err := io.Copy(io.Discard, r)
+					done <- err
+				}()
+			}
+			for i := 0; i < tt.readers; i++ {
+				if err := <-done; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if elapsed := time.Since(start); elapsed < tt.minTime {
+				t.Errorf("reading %d MiB at %d B/s took %v, want at least %v", tt.readers, tt.rate, elapsed, tt.minTime)
+			}
+		})
+	}
+}
+
+func TestLimiterSetRate(t *testing.T) {
+	limiter := NewLimiter(64 * 1024)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		limiter.SetRate(0)
+	}()
+	// At the initial rate this would take 16 seconds.
+	start := time.Now()
+	_, err := io.Copy(io.Discard, limiter.limit(ctx, bytes.NewReader(make([]byte, MiB))))
+	if err != nil || time.Since(start) > 5*time.Second {
+		t.Errorf("Copy() = %v after %v, want the limit lifted", err, time.Since(start))
+	}
+	if limiter.Rate() != 0 {
+		t.Errorf("Rate() = %d, want 0", limiter.Rate())
+	}
+
+	limiter.SetRate(1024)
+	cancel()
+	if _, err := io.Copy(io.Discard, limiter.limit(ctx, bytes.NewReader(make([]byte, MiB)))); err != context.Canceled {
+		t.Errorf("Copy() with a cancelled context error = %v, want context.Canceled", err)
+	}
+}
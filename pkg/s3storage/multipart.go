@@ -0,0 +1,256 @@
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	MiB = 1024 * 1024
+
+	DefaultPartSize           = 16 * MiB
+	DefaultConcurrency        = 4
+	DefaultMultipartThreshold = 64 * MiB
+
+	// MinPartSize and MaxParts are the S3 limits for multipart uploads.
+	MinPartSize = 5 * MiB
+	MaxParts    = 10000
+
+	// abortTimeout bounds the request that aborts a multipart upload.
+	abortTimeout = 30 * time.Second
+)
+
+func (o *UploadOptions) partSize(size int64) int64 {
+	partSize := o.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	return adjustPartSize(partSize, size)
+}
+
+func (o *UploadOptions) concurrency() int {
+	if o.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return o.Concurrency
+}
+
+func (o *UploadOptions) multipartThreshold() int64 {
+	if o.MultipartThreshold <= 0 {
+		return DefaultMultipartThreshold
+	}
+	return o.MultipartThreshold
+}
+
+func adjustPartSize(partSize, size int64) int64 {
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if size > partSize*MaxParts {
+		partSize = (size + MaxParts - 1) / MaxParts
+		partSize = (partSize + MiB - 1) / MiB * MiB
+	}
+	return partSize
+}
+
+type uploadPart struct {
+	number int32
+	offset int64
+	size   int64
+}
+
+func splitParts(size, partSize int64) []uploadPart {
+	var parts []uploadPart
+	for offset := int64(0); offset < size; offset += partSize {
+		n := partSize
+		if offset+n > size {
+			n = size - offset
+		}
+		parts = append(parts, uploadPart{
+			number: int32(len(parts) + 1),
+			offset: offset,
+			size:   n,
+		})
+	}
+	return parts
+}
+
+// progressTracker combines the progress of concurrently uploaded parts into
+// one byte count and serializes calls to the callback.
+type progressTracker struct {
+	mu       sync.Mutex
+	total    int64
+	callback ProgressCallback
+}
+
+func (t *progressTracker) add(delta int64) {
+	if t == nil || t.callback == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total += delta
+	t.callback(t.total)
+}
+
+type partReader struct {
+	reader  *io.SectionReader
+	read    int64
+	tracker *progressTracker
+}
+
+func (pr *partReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	pr.read += int64(n)
+	pr.tracker.add(int64(n))
+	return n, err
+}
+
+func (pr *partReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := pr.reader.Seek(offset, whence)
+	if err == nil {
+		pr.tracker.add(pos - pr.read)
+		pr.read = pos
+	}
+	return pos, err
+}
+
+func uploadMultipart(parent context.Context, backend Backend, key string, file *os.File, size int64, opts *UploadOptions, progressCallback ProgressCallback) (string, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	partSize := opts.partSize(size)
+
+	state, err := loadResumeState(ctx, backend, key, file, size, partSize)
+	if err != nil {
+		return "", err
+	}
+
+	if state.UploadID == "" {
+		uploadID, err := backend.CreateMultipart(ctx, key, opts.writeOptions())
+		if err != nil {
+			return "", fmt.Errorf("failed to start multipart upload: %w", err)
+		}
+		state.UploadID = uploadID
+		if err := state.save(); err != nil {
+			abortMultipart(ctx, backend, key, uploadID)
+			return "", fmt.Errorf("failed to save upload state: %w", err)
+		}
+	}
+
+	done := make(map[int32]bool)
+	for _, part := range state.Parts {
+		done[part.Number] = true
+	}
+
+	tracker := &progressTracker{callback: progressCallback}
+	tracker.add(state.Uploaded())
+
+	jobs := make(chan uploadPart)
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < opts.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range jobs {
+				body := opts.Limiter.limit(ctx, &partReader{
+					reader:  io.NewSectionReader(file, part.offset, part.size),
+					tracker: tracker,
+				})
+				etag, err := opts.retries.do(ctx, part.number, body, func() (string, error) {
+					return backend.UploadPart(ctx, key, state.UploadID, part.number, body, part.size)
+				})
+				if err == nil {
+					err = state.addPart(ResumePart{
+						Number: part.number,
+						ETag:   etag,
+						Size:   part.size,
+					})
+				}
+
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to upload part %d: %w", part.number, err)
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for _, part := range splitParts(size, partSize) {
+		if ctx.Err() != nil {
+			break
+		}
+		if done[part.number] {
+			continue
+		}
+		jobs <- part
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = parent.Err()
+	}
+	// The upload is left in place when it fails or is cancelled so that it
+	// can be resumed, unless it has no finished part to resume from.
+	if firstErr != nil {
+		if len(state.Parts) == 0 {
+			abortMultipart(ctx, backend, key, state.UploadID)
+			state.remove()
+		}
+		return "", firstErr
+	}
+
+	sort.Slice(state.Parts, func(i, j int) bool {
+		return state.Parts[i].Number < state.Parts[j].Number
+	})
+
+	parts := make([]Part, 0, len(state.Parts))
+	for _, part := range state.Parts {
+		parts = append(parts, Part{Number: part.Number, ETag: part.ETag, Size: part.Size})
+	}
+
+	etag, err := completeMultipart(parent, backend, key, state.UploadID, parts, opts.conditional)
+	if err != nil {
+		abortMultipart(ctx, backend, key, state.UploadID)
+		state.remove()
+		return "", err
+	}
+
+	state.remove()
+	return etag, nil
+}
+
+func completeMultipart(ctx context.Context, backend Backend, key, uploadID string, parts []Part, ifNoneMatch bool) (string, error) {
+	etag, err := backend.CompleteMultipart(ctx, key, uploadID, parts, ifNoneMatch)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return "", &ObjectExistsError{Key: key}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return etag, nil
+}
+
+// abortMultipart aborts an upload even if ctx has been cancelled, since
+// cleaning up is what a cancelled upload still has to do.
+func abortMultipart(ctx context.Context, backend Backend, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+	defer cancel()
+	_ = backend.AbortMultipart(ctx, key, uploadID)
+}
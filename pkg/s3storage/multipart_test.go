@@ -0,0 +1,87 @@
+package s3storage
+
+import (
+	"testing"
+)
+
+func TestAdjustPartSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		partSize int64
+		size     int64
+		expected int64
+	}{
+		{
+			name:     "default part size",
+			partSize: DefaultPartSize,
+			size:     1024 * MiB,
+			expected: DefaultPartSize,
+		},
+		{
+			name:     "below minimum",
+			partSize: MiB,
+			size:     100 * MiB,
+			expected: MinPartSize,
+		},
+		{
+			name:     "too many parts",
+			partSize: MinPartSize,
+			size:     100000 * MiB,
+			expected: 10 * MiB,
+		},
+		{
+			name:     "rounded up to whole MiB",
+			partSize: MinPartSize,
+			size:     MaxParts*MinPartSize + 1,
+			expected: 6 * MiB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := adjustPartSize(tt.partSize, tt.size)
+			if result != tt.expected {
+				t.Errorf("adjustPartSize(%d, %d) = %d, want %d", tt.partSize, tt.size, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSplitParts(t *testing.T) {
+	parts := splitParts(25*MiB, 10*MiB)
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+
+	var total int64
+	for i, part := range parts {
+		if part.number != int32(i+1) {
+			t.Errorf("part %d has number %d", i, part.number)
+		}
+		if part.offset != total {
+			t.Errorf("part %d offset = %d, want %d", i, part.offset, total)
+		}
+		total += part.size
+	}
+
+	if total != 25*MiB {
+		t.Errorf("parts cover %d bytes, want %d", total, 25*MiB)
+	}
+	if parts[2].size != 5*MiB {
+		t.Errorf("last part size = %d, want %d", parts[2].size, 5*MiB)
+	}
+}
+
+func TestProgressTrackerCombinesParts(t *testing.T) {
+	var last int64
+	tracker := &progressTracker{callback: func(uploaded int64) { last = uploaded }}
+
+	tracker.add(100)
+	tracker.add(50)
+	tracker.add(-50)
+	tracker.add(200)
+
+	if last != 300 {
+		t.Errorf("combined progress = %d, want 300", last)
+	}
+}
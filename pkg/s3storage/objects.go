@@ -0,0 +1,115 @@
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+// ObjectInfo describes an object in the bucket.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+	Metadata     map[string]string
+
+	ContentDisposition string
+	CacheControl       string
+	ContentLanguage    string
+}
+
+// ErrObjectNotFound is returned for keys that do not exist in the bucket.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Stat describes the object stored under key.
+func Stat(ctx context.Context, backend Backend, key string) (*ObjectInfo, error) {
+	info, err := backend.Head(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return info, nil
+}
+
+// ListOptions select the objects returned by List and ListPage.
+type ListOptions struct {
+	Prefix string
+
+	// Recursive lists every key under Prefix. Otherwise keys are grouped at
+	// the next "/" like folders and returned as Prefixes.
+	Recursive bool
+
+	// PageSize is the number of keys requested per page. Zero lets the
+	// service decide, usually 1000.
+	PageSize int32
+
+	// Token continues a listing from the page that returned it.
+	Token string
+}
+
+// Listing is a page of objects and folder prefixes.
+type Listing struct {
+	Objects  []ObjectInfo
+	Prefixes []string
+
+	// NextToken fetches the next page; it is empty on the last page.
+	NextToken string
+}
+
+// ListPage returns one page of the objects under opts.Prefix.
+func ListPage(ctx context.Context, backend Backend, opts *ListOptions) (*Listing, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	listing, err := backend.List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	return listing, nil
+}
+
+// List returns all objects under opts.Prefix, reading every page.
+func List(ctx context.Context, backend Backend, opts *ListOptions) (*Listing, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+
+	all := &Listing{}
+	page := *opts
+	for {
+		listing, err := ListPage(ctx, backend, &page)
+		if err != nil {
+			return nil, err
+		}
+		all.Objects = append(all.Objects, listing.Objects...)
+		all.Prefixes = append(all.Prefixes, listing.Prefixes...)
+		if listing.NextToken == "" {
+			return all, nil
+		}
+		page.Token = listing.NextToken
+	}
+}
+
+// Delete removes the object stored under key. Deleting a key that does not
+// exist returns ErrObjectNotFound.
+func Delete(ctx context.Context, backend Backend, key string) error {
+	if _, err := Stat(ctx, backend, key); err != nil {
+		return err
+	}
+	if err := backend.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// PublicURL returns the public URL of key.
+func PublicURL(cfg *config.Config, key string) string {
+	return publicURL(cfg, key)
+}
@@ -0,0 +1,142 @@
+package s3storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+func TestCopySource(t *testing.T) {
+	tests := map[string]string{
+		"a.txt":               "bucket/a.txt",
+		"dir/my file.txt":     "bucket/dir/my%20file.txt",
+		"dir/100%/résumé.pdf": "bucket/dir/100%25/r%C3%A9sum%C3%A9.pdf",
+	}
+	for key, expected := range tests {
+		if got := copySource("bucket", key); got != expected {
+			t.Errorf("copySource(%q) = %q, want %q", key, got, expected)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	pages := map[string]string{
+		"": `<ListBucketResult>
+			<IsTruncated>true</IsTruncated>
+			<NextContinuationToken>page2</NextContinuationToken>
+			<CommonPrefixes><Prefix>shots/2025/</Prefix></CommonPrefixes>
+			<Contents><Key>shots/a.png</Key><Size>10</Size><ETag>"aa"</ETag><LastModified>2025-01-02T03:04:05.000Z</LastModified></Contents>
+		</ListBucketResult>`,
+		"page2": `<ListBucketResult>
+			<IsTruncated>false</IsTruncated>
+			<Contents><Key>shots/b.png</Key><Size>20</Size><ETag>"bb"</ETag><LastModified>2025-01-02T03:04:05.000Z</LastModified></Contents>
+		</ListBucketResult>`,
+	}
+
+	var delimiters []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("prefix") != "shots/" {
+			t.Errorf("prefix = %q, want shots/", q.Get("prefix"))
+		}
+		delimiters = append(delimiters, q.Get("delimiter"))
+		fmt.Fprint(w, pages[q.Get("continuation-token")])
+	}))
+	defer server.Close()
+
+	backend, err := NewS3Backend(context.Background(), &config.Config{
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		Bucket:          "bucket",
+		Endpoint:        server.URL,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ListPage(context.Background(), backend, &ListOptions{Prefix: "shots/"})
+	if err != nil {
+		t.Fatalf("ListPage() error = %v", err)
+	}
+	if page.NextToken != "page2" || len(page.Objects) != 1 || len(page.Prefixes) != 1 {
+		t.Errorf("ListPage() = %+v", page)
+	}
+
+	all, err := List(context.Background(), backend, &ListOptions{Prefix: "shots/", Recursive: true})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all.Objects) != 2 || all.Objects[1].Key != "shots/b.png" || all.Objects[1].Size != 20 || all.Objects[0].ETag != "aa" {
+		t.Errorf("List() objects = %+v", all.Objects)
+	}
+	if all.NextToken != "" {
+		t.Errorf("List() NextToken = %q, want empty", all.NextToken)
+	}
+	if delimiters[0] != "/" || delimiters[1] != "" {
+		t.Errorf("delimiters = %q, want [/ \"\" ...]", delimiters)
+	}
+}
+
+// copyBackend is a Backend holding one object, source, that records how a
+// multipart copy of it is started.
+type copyBackend struct {
+	Backend
+	source  *ObjectInfo
+	created *WriteOptions
+}
+
+func (b *copyBackend) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	if key == b.source.Key {
+		return b.source, nil
+	}
+	return nil, nil
+}
+
+func (b *copyBackend) CreateMultipart(ctx context.Context, key string, opts *WriteOptions) (string, error) {
+	b.created = opts
+	return "upload-1", nil
+}
+
+func (b *copyBackend) CopyPart(ctx context.Context, key, uploadID string, number int32, src string, offset, size int64) (string, error) {
+	return fmt.Sprintf("etag-%d", number), nil
+}
+
+func (b *copyBackend) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part, ifNoneMatch bool) (string, error) {
+	return "etag-copy", nil
+}
+
+func TestCopyMultipartKeepsHeaders(t *testing.T) {
+	backend := &copyBackend{source: &ObjectInfo{
+		Key:                "videos/raw.mov",
+		Size:               MaxCopySize + MiB,
+		ContentType:        "video/quicktime",
+		ContentDisposition: `attachment; filename="raw.mov"`,
+		CacheControl:       "private, max-age=60",
+		ContentLanguage:    "en",
+		Metadata:           map[string]string{"team": "media"},
+	}}
+
+	copied, err := Copy(context.Background(), backend, "videos/raw.mov", "archive/raw.mov", nil)
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if copied.ETag != "etag-copy" {
+		t.Fatalf("Copy() ETag = %q, want a multipart copy", copied.ETag)
+	}
+
+	want := &WriteOptions{
+		ContentType:        "video/quicktime",
+		ContentDisposition: `attachment; filename="raw.mov"`,
+		CacheControl:       "private, max-age=60",
+		ContentLanguage:    "en",
+		Metadata:           map[string]string{"team": "media"},
+	}
+	if !reflect.DeepEqual(backend.created, want) {
+		t.Errorf("CreateMultipart() options = %+v, want %+v", backend.created, want)
+	}
+}
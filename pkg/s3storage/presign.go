@@ -0,0 +1,37 @@
+package s3storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultLinkExpiry = 24 * time.Hour
+
+	// MaxLinkExpiry is the longest validity Signature Version 4 allows for
+	// presigned URLs.
+	MaxLinkExpiry = 7 * 24 * time.Hour
+)
+
+// Presign returns a presigned GET URL for key that is valid for expiry.
+func Presign(ctx context.Context, backend Backend, key string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > MaxLinkExpiry {
+		return "", fmt.Errorf("link expiry must be between 1s and %s, got %s", MaxLinkExpiry, expiry)
+	}
+
+	url, err := backend.Presign(ctx, key, expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign link: %w", err)
+	}
+	return url, nil
+}
+
+// objectURL is the link returned for an uploaded object: a presigned URL if
+// opts.PresignExpiry is set, the public URL otherwise.
+func objectURL(ctx context.Context, backend Backend, key string, opts *UploadOptions) (string, error) {
+	if opts.PresignExpiry > 0 {
+		return Presign(ctx, backend, key, opts.PresignExpiry)
+	}
+	return backend.URL(key), nil
+}
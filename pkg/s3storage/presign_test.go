@@ -0,0 +1,46 @@
+package s3storage
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+func TestPresign(t *testing.T) {
+	backend, err := NewS3Backend(context.Background(), &config.Config{
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		Bucket:          "bucket",
+		Endpoint:        "https://storage.example.com",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link, err := Presign(context.Background(), backend, "reports/q3.pdf", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Presign() error = %v", err)
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/bucket/reports/q3.pdf" {
+		t.Errorf("path = %q, want /bucket/reports/q3.pdf", u.Path)
+	}
+	if got := u.Query().Get("X-Amz-Expires"); got != "7200" {
+		t.Errorf("X-Amz-Expires = %q, want 7200", got)
+	}
+	if u.Query().Get("X-Amz-Signature") == "" {
+		t.Error("link is not signed")
+	}
+
+	for _, expiry := range []time.Duration{0, MaxLinkExpiry + time.Second} {
+		if _, err := Presign(context.Background(), backend, "a", expiry); err == nil {
+			t.Errorf("Presign() with expiry %s succeeded, want error", expiry)
+		}
+	}
+}
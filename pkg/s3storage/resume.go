@@ -0,0 +1,334 @@
+package s3storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+// DefaultResumeMaxAge is how long an unfinished multipart upload is kept
+// around for resuming before it is treated as stale.
+const DefaultResumeMaxAge = 7 * 24 * time.Hour
+
+const fingerprintSampleSize = MiB
+
+// ResumeState is the on-disk record of an unfinished multipart upload.
+type ResumeState struct {
+	FilePath    string       `json:"file_path"`
+	Endpoint    string       `json:"endpoint"`
+	Bucket      string       `json:"bucket"`
+	Key         string       `json:"key"`
+	UploadID    string       `json:"upload_id"`
+	PartSize    int64        `json:"part_size"`
+	Size        int64        `json:"size"`
+	Fingerprint string       `json:"fingerprint"`
+	Parts       []ResumePart `json:"parts"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	path string
+	mu   sync.Mutex
+}
+
+type ResumePart struct {
+	Number int32  `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+func (s *ResumeState) Uploaded() int64 {
+	var total int64
+	for _, part := range s.Parts {
+		total += part.Size
+	}
+	return total
+}
+
+func (s *ResumeState) addPart(part ResumePart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Parts = append(s.Parts, part)
+	return s.save()
+}
+
+func (s *ResumeState) save() error {
+	s.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *ResumeState) remove() {
+	_ = os.Remove(s.path)
+}
+
+func (s *ResumeState) matches(backend Backend) bool {
+	return s.Endpoint == backend.Endpoint() && s.Bucket == backend.Bucket()
+}
+
+func resumeDir() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "uploads")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func resumeStatePath(endpoint, bucket, key, filePath string) (string, error) {
+	dir, err := resumeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{endpoint, bucket, key, filePath}, "\x00")))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+func readResumeState(path string) (*ResumeState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var state ResumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid resume state %s: %w", path, err)
+	}
+	state.path = path
+	return &state, nil
+}
+
+// fingerprintFile identifies a file's contents cheaply from its size,
+// modification time and a hash of its first and last megabyte.
+func fingerprintFile(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:%d:", info.Size(), info.ModTime().UnixNano())
+
+	if _, err := io.Copy(h, io.NewSectionReader(file, 0, fingerprintSampleSize)); err != nil {
+		return "", err
+	}
+	if info.Size() > fingerprintSampleSize {
+		tail := info.Size() - fingerprintSampleSize
+		if _, err := io.Copy(h, io.NewSectionReader(file, tail, fingerprintSampleSize)); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadResumeState returns the state to continue the upload of file with, or
+// a fresh state if there is nothing usable to resume. Unusable state is
+// cleaned up, including its multipart upload on the bucket.
+func loadResumeState(ctx context.Context, backend Backend, key string, file *os.File, size, partSize int64) (*ResumeState, error) {
+	filePath, err := filepath.Abs(file.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := resumeStatePath(backend.Endpoint(), backend.Bucket(), key, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := fingerprintFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint file: %w", err)
+	}
+
+	fresh := &ResumeState{
+		FilePath:    filePath,
+		Endpoint:    backend.Endpoint(),
+		Bucket:      backend.Bucket(),
+		Key:         key,
+		PartSize:    partSize,
+		Size:        size,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+		path:        path,
+	}
+
+	state, err := readResumeState(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			_ = os.Remove(path)
+		}
+		return fresh, nil
+	}
+
+	if state.Fingerprint != fingerprint || state.PartSize != partSize || state.Size != size {
+		abortMultipart(ctx, backend, state.Key, state.UploadID)
+		state.remove()
+		return fresh, nil
+	}
+
+	uploaded, err := backend.ListParts(ctx, state.Key, state.UploadID)
+	if errors.Is(err, ErrUploadNotFound) {
+		state.remove()
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploaded parts: %w", err)
+	}
+
+	remote := make(map[int32]string, len(uploaded))
+	for _, part := range uploaded {
+		remote[part.Number] = part.ETag
+	}
+
+	var parts []ResumePart
+	for _, part := range state.Parts {
+		if etag, ok := remote[part.Number]; ok && etag == part.ETag {
+			parts = append(parts, part)
+		}
+	}
+	state.Parts = parts
+
+	return state, nil
+}
+
+// PendingUploads returns the unfinished uploads recorded for the bucket of
+// backend, oldest first.
+func PendingUploads(backend Backend) ([]*ResumeState, error) {
+	dir, err := resumeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var states []*ResumeState
+	for _, path := range paths {
+		state, err := readResumeState(path)
+		if err != nil {
+			continue
+		}
+		if state.matches(backend) {
+			states = append(states, state)
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].CreatedAt.Before(states[j].CreatedAt)
+	})
+
+	return states, nil
+}
+
+// CleanStaleUploads removes resume state for the bucket of backend whose
+// file is gone or has changed, or which has not been touched for maxAge, and
+// aborts the matching multipart uploads. It returns the removed states.
+func CleanStaleUploads(ctx context.Context, backend Backend, maxAge time.Duration) ([]*ResumeState, error) {
+	states, err := PendingUploads(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []*ResumeState
+	for _, state := range states {
+		if !state.isStale(maxAge) {
+			continue
+		}
+
+		abortMultipart(ctx, backend, state.Key, state.UploadID)
+		state.remove()
+		removed = append(removed, state)
+	}
+
+	return removed, nil
+}
+
+// Discard aborts the multipart upload of a pending upload and forgets its
+// progress.
+func (s *ResumeState) Discard(ctx context.Context, backend Backend) {
+	abortMultipart(ctx, backend, s.Key, s.UploadID)
+	s.remove()
+}
+
+func (s *ResumeState) isStale(maxAge time.Duration) bool {
+	if time.Since(s.UpdatedAt) > maxAge {
+		return true
+	}
+
+	file, err := os.Open(s.FilePath)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	fingerprint, err := fingerprintFile(file)
+	return err != nil || fingerprint != s.Fingerprint
+}
+
+// LeftoverUploads returns the multipart uploads on the bucket that were
+// started more than olderThan ago and are not tracked by local resume state.
+// They may belong to other machines or tools sharing the bucket.
+func LeftoverUploads(ctx context.Context, backend Backend, olderThan time.Duration) ([]MultipartUpload, error) {
+	states, err := PendingUploads(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := make(map[string]bool)
+	for _, state := range states {
+		tracked[state.UploadID] = true
+	}
+
+	uploads, err := backend.ListMultipartUploads(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list multipart uploads: %w", err)
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var leftover []MultipartUpload
+	for _, upload := range uploads {
+		if tracked[upload.UploadID] {
+			continue
+		}
+		if upload.Initiated.After(cutoff) {
+			continue
+		}
+		leftover = append(leftover, upload)
+	}
+
+	return leftover, nil
+}
+
+// AbortUpload aborts a multipart upload, such as one of LeftoverUploads.
+func AbortUpload(ctx context.Context, backend Backend, upload MultipartUpload) error {
+	if err := backend.AbortMultipart(ctx, upload.Key, upload.UploadID); err != nil {
+		return fmt.Errorf("failed to abort upload of %s: %w", upload.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+func init() {
+	// Tests point HOME at temporary directories.
+	homedir.DisableCache = true
+}
+
+func TestResumeStateRoundTrip(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testresume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", originalHome)
+
+	filePath := filepath.Join(tmpDir, "big.bin")
+	if err := os.WriteFile(filePath, make([]byte, 3*MiB), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := fingerprintFile(file)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Endpoint: "https://example.com", Bucket: "test-bucket"}
+	path, err := resumeStatePath(cfg.Endpoint, cfg.Bucket, "big.bin", filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := &ResumeState{
+		FilePath:    filePath,
+		Endpoint:    cfg.Endpoint,
+		Bucket:      cfg.Bucket,
+		Key:         "big.bin",
+		UploadID:    "upload-1",
+		PartSize:    MinPartSize,
+		Size:        3 * MiB,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+		path:        path,
+	}
+	if err := state.addPart(ResumePart{Number: 1, ETag: "\"etag-1\"", Size: MiB}); err != nil {
+		t.Fatalf("addPart() error = %v", err)
+	}
+
+	pending, err := PendingUploads(&S3Backend{cfg: cfg})
+	if err != nil {
+		t.Fatalf("PendingUploads() error = %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("expected 1 pending upload, got %d", len(pending))
+	}
+	if pending[0].UploadID != "upload-1" || pending[0].Uploaded() != MiB {
+		t.Errorf("unexpected state: %+v", pending[0])
+	}
+	if pending[0].isStale(DefaultResumeMaxAge) {
+		t.Error("unchanged file should not be stale")
+	}
+
+	other, err := PendingUploads(&S3Backend{cfg: &config.Config{Endpoint: cfg.Endpoint, Bucket: "other-bucket"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other) != 0 {
+		t.Errorf("expected no pending uploads for another bucket, got %d", len(other))
+	}
+
+	if err := os.WriteFile(filePath, []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !pending[0].isStale(DefaultResumeMaxAge) {
+		t.Error("changed file should be stale")
+	}
+}
+
+// resumeBackend is a Backend with just the multipart calls that resuming
+// needs. parts holds the uploaded parts by upload ID; an upload that is
+// missing from it is gone.
+type resumeBackend struct {
+	Backend
+	parts   map[string][]Part
+	uploads []MultipartUpload
+	aborted []string
+}
+
+func (b *resumeBackend) Endpoint() string { return "https://example.com" }
+func (b *resumeBackend) Bucket() string   { return "test-bucket" }
+
+func (b *resumeBackend) ListParts(ctx context.Context, key, uploadID string) ([]Part, error) {
+	parts, ok := b.parts[uploadID]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	return parts, nil
+}
+
+func (b *resumeBackend) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	b.aborted = append(b.aborted, uploadID)
+	delete(b.parts, uploadID)
+	return nil
+}
+
+func (b *resumeBackend) ListMultipartUploads(ctx context.Context) ([]MultipartUpload, error) {
+	return b.uploads, nil
+}
+
+// newResumeState writes a file of size bytes and returns a state for
+// uploading it as key to backend with two finished parts, which has not
+// been saved yet.
+func newResumeState(t *testing.T, backend Backend, key string, size int64) *ResumeState {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), key)
+	if err := os.WriteFile(filePath, bytes.Repeat([]byte("x"), int(size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fingerprint, err := fingerprintFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := resumeStatePath(backend.Endpoint(), backend.Bucket(), key, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ResumeState{
+		FilePath:    filePath,
+		Endpoint:    backend.Endpoint(),
+		Bucket:      backend.Bucket(),
+		Key:         key,
+		UploadID:    "upload-" + key,
+		PartSize:    MinPartSize,
+		Size:        size,
+		Fingerprint: fingerprint,
+		Parts: []ResumePart{
+			{Number: 1, ETag: `"etag-1"`, Size: MinPartSize},
+			{Number: 2, ETag: `"etag-2"`, Size: MinPartSize},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		path:      path,
+	}
+}
+
+// writeResumeState saves state as is, keeping its UpdatedAt.
+func writeResumeState(t *testing.T, state *ResumeState) {
+	t.Helper()
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(state.path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadResumeState(t *testing.T) {
+	const size = 3 * MinPartSize
+	finished := []Part{
+		{Number: 1, ETag: `"etag-1"`, Size: MinPartSize},
+		{Number: 2, ETag: `"etag-2"`, Size: MinPartSize},
+	}
+
+	tests := []struct {
+		name        string
+		change      func(*ResumeState)
+		remote      []Part
+		gone        bool
+		wantResumed bool
+		wantParts   []int32
+		wantAborted bool
+	}{
+		{
+			name:        "finished parts are skipped",
+			remote:      finished,
+			wantResumed: true,
+			wantParts:   []int32{1, 2},
+		},
+		{
+			name:        "part with another ETag is uploaded again",
+			remote:      []Part{finished[0], {Number: 2, ETag: `"other"`, Size: MinPartSize}},
+			wantResumed: true,
+			wantParts:   []int32{1},
+		},
+		{
+			name:        "part missing on the bucket is uploaded again",
+			remote:      finished[1:],
+			wantResumed: true,
+			wantParts:   []int32{2},
+		},
+		{
+			name:        "changed file starts over",
+			change:      func(s *ResumeState) { s.Fingerprint = "other" },
+			remote:      finished,
+			wantAborted: true,
+		},
+		{
+			name:        "changed size starts over",
+			change:      func(s *ResumeState) { s.Size = 2 * MinPartSize },
+			remote:      finished,
+			wantAborted: true,
+		},
+		{
+			name:        "changed part size starts over",
+			change:      func(s *ResumeState) { s.PartSize = 2 * MinPartSize },
+			remote:      finished,
+			wantAborted: true,
+		},
+		{
+			name: "upload aborted on the bucket starts over",
+			gone: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			backend := &resumeBackend{parts: map[string][]Part{}}
+			saved := newResumeState(t, backend, "big.bin", size)
+			if tt.change != nil {
+				tt.change(saved)
+			}
+			writeResumeState(t, saved)
+			if !tt.gone {
+				backend.parts[saved.UploadID] = tt.remote
+			}
+
+			file, err := os.Open(saved.FilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			state, err := loadResumeState(context.Background(), backend, "big.bin", file, size, MinPartSize)
+			if err != nil {
+				t.Fatalf("loadResumeState() error = %v", err)
+			}
+
+			if resumed := state.UploadID != ""; resumed != tt.wantResumed {
+				t.Fatalf("loadResumeState() resumed = %v, want %v", resumed, tt.wantResumed)
+			}
+			var parts []int32
+			for _, part := range state.Parts {
+				parts = append(parts, part.Number)
+			}
+			if !reflect.DeepEqual(parts, tt.wantParts) {
+				t.Errorf("loadResumeState() parts = %v, want %v", parts, tt.wantParts)
+			}
+			if aborted := len(backend.aborted) > 0; aborted != tt.wantAborted {
+				t.Errorf("aborted = %v, want %v", backend.aborted, tt.wantAborted)
+			}
+			if _, err := os.Stat(saved.path); tt.wantResumed != (err == nil) {
+				t.Errorf("state file exists = %v, want %v", err == nil, tt.wantResumed)
+			}
+		})
+	}
+}
+
+func TestCleanStaleUploads(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	backend := &resumeBackend{}
+	fresh := newResumeState(t, backend, "fresh.bin", MinPartSize)
+	writeResumeState(t, fresh)
+
+	tests := []struct {
+		key    string
+		change func(*ResumeState)
+	}{
+		{"old.bin", func(s *ResumeState) { s.UpdatedAt = time.Now().Add(-8 * 24 * time.Hour) }},
+		{"changed.bin", func(s *ResumeState) { s.Fingerprint = "other" }},
+		{"deleted.bin", func(s *ResumeState) { os.Remove(s.FilePath) }},
+	}
+
+	var wantAborted []string
+	for _, tt := range tests {
+		state := newResumeState(t, backend, tt.key, MinPartSize)
+		tt.change(state)
+		writeResumeState(t, state)
+		wantAborted = append(wantAborted, state.UploadID)
+	}
+
+	removed, err := CleanStaleUploads(context.Background(), backend, DefaultResumeMaxAge)
+	if err != nil {
+		t.Fatalf("CleanStaleUploads() error = %v", err)
+	}
+	if len(removed) != len(tests) {
+		t.Errorf("CleanStaleUploads() removed %d states, want %d", len(removed), len(tests))
+	}
+	sort.Strings(backend.aborted)
+	sort.Strings(wantAborted)
+	if !reflect.DeepEqual(backend.aborted, wantAborted) {
+		t.Errorf("aborted %v, want %v", backend.aborted, wantAborted)
+	}
+
+	pending, err := PendingUploads(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Key != "fresh.bin" {
+		t.Errorf("PendingUploads() = %v, want only fresh.bin", pending)
+	}
+}
+
+func TestLeftoverUploads(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	backend := &resumeBackend{}
+	tracked := newResumeState(t, backend, "tracked.bin", MinPartSize)
+	writeResumeState(t, tracked)
+
+	old := time.Now().Add(-48 * time.Hour)
+	leftover := MultipartUpload{Key: "leftover.bin", UploadID: "upload-leftover", Initiated: old}
+	backend.uploads = []MultipartUpload{
+		{Key: "tracked.bin", UploadID: tracked.UploadID, Initiated: old},
+		leftover,
+		{Key: "recent.bin", UploadID: "upload-recent", Initiated: time.Now().Add(-time.Hour)},
+	}
+
+	got, err := LeftoverUploads(context.Background(), backend, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("LeftoverUploads() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []MultipartUpload{leftover}) {
+		t.Errorf("LeftoverUploads() = %v, want [%v]", got, leftover)
+	}
+	if len(backend.aborted) != 0 {
+		t.Errorf("LeftoverUploads() aborted %v, want nothing aborted", backend.aborted)
+	}
+
+	if err := AbortUpload(context.Background(), backend, leftover); err != nil {
+		t.Fatalf("AbortUpload() error = %v", err)
+	}
+	if !reflect.DeepEqual(backend.aborted, []string{"upload-leftover"}) {
+		t.Errorf("aborted uploads %v, want [upload-leftover]", backend.aborted)
+	}
+}
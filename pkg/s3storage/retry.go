@@ -0,0 +1,250 @@
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts    = 5
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 20 * time.Second
+)
+
+// RetryPolicy decides how often a failed PutObject or UploadPart request is
+// sent again. Each part of a multipart upload is retried on its own, so one
+// failed part does not end the upload.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries per request, the first included.
+	// Zero means DefaultMaxAttempts, one disables retries.
+	MaxAttempts int
+
+	// BaseDelay is the backoff after the first failure; it doubles with
+	// each attempt up to MaxDelay. The wait is a random duration up to the
+	// backoff so that concurrent parts do not retry in lockstep. Zero means
+	// DefaultRetryBaseDelay and DefaultRetryMaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Retryable reports whether an error is worth another attempt. Nil
+	// means IsRetryable.
+	Retryable func(err error) bool
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p == nil || p.Retryable == nil {
+		return IsRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+// backoff returns the wait before the attempt after attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base, limit := DefaultRetryBaseDelay, DefaultRetryMaxDelay
+	if p != nil && p.BaseDelay > 0 {
+		base = p.BaseDelay
+	}
+	if p != nil && p.MaxDelay > 0 {
+		limit = p.MaxDelay
+	}
+
+	delay := limit
+	if attempt < 32 && base<<(attempt-1) < limit {
+		delay = base << (attempt - 1)
+	}
+	return rand.N(delay) + 1
+}
+
+// Kinds of retryable errors, for RetryOn.
+const (
+	RetryNetwork  = "network"
+	RetryTimeout  = "timeout"
+	RetryThrottle = "throttle"
+	RetryServer   = "server"
+)
+
+// RetryKinds lists the kinds of retryable errors, the ones RetryOn accepts.
+var RetryKinds = []string{RetryNetwork, RetryTimeout, RetryThrottle, RetryServer}
+
+// IsRetryable reports whether err is a failure that may go away when the
+// request is sent again: a network error, a timeout, throttling or a
+// server-side error. Cancellation, rejected credentials and other client
+// errors are not.
+func IsRetryable(err error) bool {
+	return retryKind(err) != ""
+}
+
+// RetryOn returns a RetryPolicy.Retryable that only retries the given kinds
+// of errors, each one of RetryKinds.
+func RetryOn(kinds []string) (func(err error) bool, error) {
+	retry := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		if !slices.Contains(RetryKinds, kind) {
+			return nil, fmt.Errorf("unknown kind of error %q, expected one of %s", kind, strings.Join(RetryKinds, ", "))
+		}
+		retry[kind] = true
+	}
+	return func(err error) bool {
+		return retry[retryKind(err)]
+	}, nil
+}
+
+// retryKind returns which of RetryKinds err is, or "" if it is not worth
+// another attempt.
+func retryKind(err error) string {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), IsAuthError(err):
+		return ""
+	case IsNetworkError(err), errors.Is(err, io.ErrUnexpectedEOF):
+		return RetryNetwork
+	case isHTTPStatus(err, http.StatusRequestTimeout), isErrorCode(err, "RequestTimeout"):
+		return RetryTimeout
+	case isHTTPStatus(err, http.StatusTooManyRequests), isErrorCode(err, "SlowDown", "Throttling", "ThrottlingException"):
+		return RetryThrottle
+	case isErrorCode(err, "InternalError", "ServiceUnavailable"):
+		return RetryServer
+	}
+	for _, status := range []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	} {
+		if isHTTPStatus(err, status) {
+			return RetryServer
+		}
+	}
+	return ""
+}
+
+// RetryEvent describes a failed request that is about to be sent again.
+type RetryEvent struct {
+	Key string
+
+	// Part is the part number, or zero for a single PutObject.
+	Part int32
+
+	// Attempt is the attempt about to be made, counting from one.
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error
+}
+
+// PartRetry records a request that succeeded only after being retried.
+type PartRetry struct {
+	// Part is the part number, or zero for a single PutObject.
+	Part     int32
+	Attempts int
+}
+
+// FormatRetries describes the requests of an upload that needed retries,
+// e.g. "part 3 took 2 attempts, part 7 took 3 attempts".
+func FormatRetries(retries []PartRetry) string {
+	summary := make([]string, len(retries))
+	for i, retry := range retries {
+		if retry.Part == 0 {
+			summary[i] = fmt.Sprintf("took %d attempts", retry.Attempts)
+		} else {
+			summary[i] = fmt.Sprintf("part %d took %d attempts", retry.Part, retry.Attempts)
+		}
+	}
+	return strings.Join(summary, ", ")
+}
+
+// retrier retries the requests of one upload and remembers which of them
+// needed it.
+type retrier struct {
+	key     string
+	policy  *RetryPolicy
+	onRetry func(RetryEvent)
+
+	mu      sync.Mutex
+	retries []PartRetry
+}
+
+func (o *UploadOptions) newRetrier(key string) *retrier {
+	return &retrier{key: key, policy: o.Retry, onRetry: o.OnRetry}
+}
+
+// do calls send until it succeeds, fails with an error that is not
+// retryable or runs out of attempts. body is rewound before each retry.
+func (r *retrier) do(ctx context.Context, part int32, body io.Seeker, send func() (string, error)) (string, error) {
+	if r == nil {
+		return send()
+	}
+
+	maxAttempts := r.policy.maxAttempts()
+	for attempt := 1; ; attempt++ {
+		etag, err := send()
+		if err == nil {
+			if attempt > 1 {
+				r.mu.Lock()
+				r.retries = append(r.retries, PartRetry{Part: part, Attempts: attempt})
+				r.mu.Unlock()
+			}
+			return etag, nil
+		}
+		if ctx.Err() != nil || !r.policy.retryable(err) {
+			return "", err
+		}
+		if attempt >= maxAttempts {
+			if attempt > 1 {
+				err = fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+			return "", err
+		}
+
+		if _, seekErr := body.Seek(0, io.SeekStart); seekErr != nil {
+			return "", err
+		}
+		delay := r.policy.backoff(attempt)
+		if r.onRetry != nil {
+			r.onRetry(RetryEvent{
+				Key:         r.key,
+				Part:        part,
+				Attempt:     attempt + 1,
+				MaxAttempts: maxAttempts,
+				Delay:       delay,
+				Err:         err,
+			})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		}
+	}
+}
+
+// parts returns the retried requests in part order.
+func (r *retrier) parts() []PartRetry {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sort.Slice(r.retries, func(i, j int) bool {
+		return r.retries[i].Part < r.retries[j].Part
+	})
+	return r.retries
+}
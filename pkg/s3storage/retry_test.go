@@ -0,0 +1,118 @@
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func httpError(status int) error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      errors.New("request failed"),
+	}}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"cancelled", fmt.Errorf("put: %w", context.Canceled), false},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"truncated", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"service unavailable", httpError(http.StatusServiceUnavailable), true},
+		{"too many requests", httpError(http.StatusTooManyRequests), true},
+		{"slow down", &smithy.GenericAPIError{Code: "SlowDown"}, true},
+		{"forbidden", httpError(http.StatusForbidden), false},
+		{"not found", httpError(http.StatusNotFound), false},
+		{"precondition failed", httpError(http.StatusPreconditionFailed), false},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDenied"}, false},
+		{"other", errors.New("failed"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{40, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			if got := policy.backoff(tt.attempt); got <= 0 || got > tt.max {
+				t.Fatalf("backoff(%d) = %v, want up to %v", tt.attempt, got, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryOn(t *testing.T) {
+	retryable, err := RetryOn([]string{RetryNetwork, RetryThrottle})
+	if err != nil {
+		t.Fatalf("RetryOn() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"slow down", &smithy.GenericAPIError{Code: "SlowDown"}, true},
+		{"too many requests", httpError(http.StatusTooManyRequests), true},
+		{"request timeout", httpError(http.StatusRequestTimeout), false},
+		{"service unavailable", httpError(http.StatusServiceUnavailable), false},
+		{"forbidden", httpError(http.StatusForbidden), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := RetryOn([]string{"network", "5xx"}); err == nil {
+		t.Error("RetryOn() accepted an unknown kind of error")
+	}
+}
+
+func TestFormatRetries(t *testing.T) {
+	tests := []struct {
+		retries []PartRetry
+		want    string
+	}{
+		{nil, ""},
+		{[]PartRetry{{Part: 0, Attempts: 2}}, "took 2 attempts"},
+		{[]PartRetry{{Part: 2, Attempts: 2}, {Part: 7, Attempts: 3}}, "part 2 took 2 attempts, part 7 took 3 attempts"},
+	}
+	for _, tt := range tests {
+		if got := FormatRetries(tt.retries); got != tt.want {
+			t.Errorf("FormatRetries(%v) = %q, want %q", tt.retries, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package s3storage
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/nizar0x1f/termup/pkg/config"
+)
+
+// ClientOptions configure the connection to the storage service.
+type ClientOptions struct {
+	InsecureTLS bool
+}
+
+// S3Backend stores objects in a bucket of an S3-compatible service.
+type S3Backend struct {
+	cfg    *config.Config
+	client *s3.Client
+}
+
+// NewS3Backend connects to the bucket configured in cfg. ctx bounds the
+// loading of the SDK configuration.
+func NewS3Backend(ctx context.Context, cfg *config.Config, opts *ClientOptions) (*S3Backend, error) {
+	client, err := newClient(ctx, cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Backend{cfg: cfg, client: client}, nil
+}
+
+func newClient(ctx context.Context, cfg *config.Config, opts *ClientOptions) (*s3.Client, error) {
+	var httpClient *http.Client
+	if opts != nil && opts.InsecureTLS {
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+	}
+
+	configOptions := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, "")),
+		awsconfig.WithRegion("auto"),
+	}
+
+	if httpClient != nil {
+		configOptions = append(configOptions, awsconfig.WithHTTPClient(httpClient))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, configOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = true
+
+		o.BaseEndpoint = aws.String(cfg.Endpoint)
+	}), nil
+}
+
+func (b *S3Backend) Endpoint() string { return b.cfg.Endpoint }
+func (b *S3Backend) Bucket() string   { return b.cfg.Bucket }
+
+func (b *S3Backend) URL(key string) string {
+	return publicURL(b.cfg, key)
+}
+
+func (b *S3Backend) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(b.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (b *S3Backend) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	out, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(key),
+	})
+	if isHTTPStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ETag:         trimETag(out.ETag),
+		LastModified: aws.ToTime(out.LastModified),
+		ContentType:  aws.ToString(out.ContentType),
+		Metadata:     out.Metadata,
+
+		ContentDisposition: aws.ToString(out.ContentDisposition),
+		CacheControl:       aws.ToString(out.CacheControl),
+		ContentLanguage:    aws.ToString(out.ContentLanguage),
+	}, nil
+}
+
+func (b *S3Backend) PartSize(ctx context.Context, key string, number int32) (int64, error) {
+	out, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:     aws.String(b.cfg.Bucket),
+		Key:        aws.String(key),
+		PartNumber: aws.Int32(number),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return aws.ToInt64(out.ContentLength), nil
+}
+
+func (b *S3Backend) List(ctx context.Context, opts *ListOptions) (*Listing, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:            aws.String(b.cfg.Bucket),
+		Prefix:            optionalString(opts.Prefix),
+		ContinuationToken: optionalString(opts.Token),
+	}
+	if !opts.Recursive {
+		input.Delimiter = aws.String("/")
+	}
+	if opts.PageSize > 0 {
+		input.MaxKeys = aws.Int32(opts.PageSize)
+	}
+
+	out, err := b.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &Listing{}
+	for _, prefix := range out.CommonPrefixes {
+		listing.Prefixes = append(listing.Prefixes, aws.ToString(prefix.Prefix))
+	}
+	for _, object := range out.Contents {
+		listing.Objects = append(listing.Objects, ObjectInfo{
+			Key:          aws.ToString(object.Key),
+			Size:         aws.ToInt64(object.Size),
+			ETag:         trimETag(object.ETag),
+			LastModified: aws.ToTime(object.LastModified),
+		})
+	}
+	if aws.ToBool(out.IsTruncated) {
+		listing.NextToken = aws.ToString(out.NextContinuationToken)
+	}
+	return listing, nil
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string, offset, length int64, etag string) (io.ReadCloser, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(b.cfg.Bucket),
+		Key:     aws.String(key),
+		Range:   aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+		IfMatch: optionalString(quoteETag(etag)),
+	})
+	if isPreconditionFailed(err) {
+		return nil, fmt.Errorf("%w: %v", ErrPreconditionFailed, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// Put drops If-None-Match and writes unconditionally if the provider does
+// not support it.
+func (b *S3Backend) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, opts *WriteOptions) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket:             aws.String(b.cfg.Bucket),
+		Key:                aws.String(key),
+		Body:               body,
+		ContentLength:      aws.Int64(size),
+		ContentType:        optionalString(opts.ContentType),
+		ContentDisposition: optionalString(opts.ContentDisposition),
+		CacheControl:       optionalString(opts.CacheControl),
+		ContentLanguage:    optionalString(opts.ContentLanguage),
+		Metadata:           opts.Metadata,
+		IfNoneMatch:        ifNoneMatch(opts.IfNoneMatch),
+	}
+
+	out, err := b.client.PutObject(ctx, input, withoutRetries)
+	if err != nil && input.IfNoneMatch != nil && isConditionalUnsupported(err) {
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		input.IfNoneMatch = nil
+		out, err = b.client.PutObject(ctx, input, withoutRetries)
+	}
+	if isPreconditionFailed(err) {
+		return "", fmt.Errorf("%w: %v", ErrPreconditionFailed, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return trimETag(out.ETag), nil
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (b *S3Backend) Copy(ctx context.Context, src, dst string) (string, error) {
+	out, err := b.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(b.cfg.Bucket),
+		Key:        aws.String(dst),
+		CopySource: aws.String(copySource(b.cfg.Bucket, src)),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.CopyObjectResult == nil {
+		return "", nil
+	}
+	return trimETag(out.CopyObjectResult.ETag), nil
+}
+
+func (b *S3Backend) CreateMultipart(ctx context.Context, key string, opts *WriteOptions) (string, error) {
+	out, err := b.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:             aws.String(b.cfg.Bucket),
+		Key:                aws.String(key),
+		ContentType:        optionalString(opts.ContentType),
+		ContentDisposition: optionalString(opts.ContentDisposition),
+		CacheControl:       optionalString(opts.CacheControl),
+		ContentLanguage:    optionalString(opts.ContentLanguage),
+		Metadata:           opts.Metadata,
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+func (b *S3Backend) UploadPart(ctx context.Context, key, uploadID string, number int32, body io.ReadSeeker, size int64) (string, error) {
+	out, err := b.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(b.cfg.Bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(number),
+		ContentLength: aws.Int64(size),
+		Body:          body,
+	}, withoutRetries)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+func (b *S3Backend) CopyPart(ctx context.Context, key, uploadID string, number int32, src string, offset, size int64) (string, error) {
+	out, err := b.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+		Bucket:          aws.String(b.cfg.Bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		PartNumber:      aws.Int32(number),
+		CopySource:      aws.String(copySource(b.cfg.Bucket, src)),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.CopyPartResult.ETag), nil
+}
+
+func (b *S3Backend) ListParts(ctx context.Context, key, uploadID string) ([]Part, error) {
+	var parts []Part
+
+	paginator := s3.NewListPartsPaginator(b.client, &s3.ListPartsInput{
+		Bucket:   aws.String(b.cfg.Bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		var noSuchUpload *types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			return nil, fmt.Errorf("%w: %v", ErrUploadNotFound, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range page.Parts {
+			parts = append(parts, Part{
+				Number: aws.ToInt32(part.PartNumber),
+				ETag:   aws.ToString(part.ETag),
+				Size:   aws.ToInt64(part.Size),
+			})
+		}
+	}
+
+	return parts, nil
+}
+
+// CompleteMultipart drops If-None-Match if the provider does not support
+// it.
+func (b *S3Backend) CompleteMultipart(ctx context.Context, key, uploadID string, parts []Part, ifNoneMatchKey bool) (string, error) {
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completed = append(completed, types.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int32(part.Number),
+		})
+	}
+
+	input := &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(b.cfg.Bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+		IfNoneMatch:     ifNoneMatch(ifNoneMatchKey),
+	}
+
+	out, err := b.client.CompleteMultipartUpload(ctx, input)
+	if err != nil && input.IfNoneMatch != nil && isConditionalUnsupported(err) {
+		input.IfNoneMatch = nil
+		out, err = b.client.CompleteMultipartUpload(ctx, input)
+	}
+	if isPreconditionFailed(err) {
+		return "", fmt.Errorf("%w: %v", ErrPreconditionFailed, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return trimETag(out.ETag), nil
+}
+
+func (b *S3Backend) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	_, err := b.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(b.cfg.Bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	return err
+}
+
+func (b *S3Backend) ListMultipartUploads(ctx context.Context) ([]MultipartUpload, error) {
+	var uploads []MultipartUpload
+
+	paginator := s3.NewListMultipartUploadsPaginator(b.client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(b.cfg.Bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, upload := range page.Uploads {
+			uploads = append(uploads, MultipartUpload{
+				Key:       aws.ToString(upload.Key),
+				UploadID:  aws.ToString(upload.UploadId),
+				Initiated: aws.ToTime(upload.Initiated),
+			})
+		}
+	}
+
+	return uploads, nil
+}
+
+// withoutRetries turns off the SDK's retries for requests that upload data,
+// which are retried according to the upload's RetryPolicy instead.
+func withoutRetries(o *s3.Options) {
+	o.RetryMaxAttempts = 1
+}
+
+func ifNoneMatch(on bool) *string {
+	if !on {
+		return nil
+	}
+	return aws.String("*")
+}
+
+func quoteETag(etag string) string {
+	if etag == "" {
+		return ""
+	}
+	return `"` + etag + `"`
+}
+
+func trimETag(etag *string) string {
+	return strings.Trim(aws.ToString(etag), `"`)
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
@@ -2,26 +2,112 @@ package s3storage
 
 import (
 	"context"
-	"crypto/tls"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/cheggaaa/pb/v3"
 	"github.com/nizar0x1f/termup/pkg/config"
 )
 
 type UploadOptions struct {
-	InsecureTLS bool
+	// PartSize is the size of each part of a multipart upload. Zero means
+	// DefaultPartSize; the value is raised if the file would need more than
+	// MaxParts parts.
+	PartSize int64
+
+	// Concurrency is the number of parts uploaded at once. Zero means
+	// DefaultConcurrency.
+	Concurrency int
+
+	// MultipartThreshold is the file size at which the upload switches from a
+	// single PutObject to a multipart upload. Zero means
+	// DefaultMultipartThreshold.
+	MultipartThreshold int64
+
+	// Key is the object key to upload to. Empty means the file's base name.
+	Key string
+
+	// ContentType is detected from the key's extension, or from the content,
+	// if empty.
+	ContentType string
+
+	// ContentDisposition is "inline", "attachment" (both sent with the
+	// original file name) or a complete header value.
+	ContentDisposition string
+
+	CacheControl    string
+	ContentLanguage string
+
+	// Metadata is stored as x-amz-meta-* headers.
+	Metadata map[string]string
+
+	// Checksum computes the SHA-256 of a file's content for
+	// UploadResult.Checksum, which costs an extra read of the file. Streams
+	// are always hashed as they are read.
+	Checksum bool
+
+	// PresignExpiry makes the returned URL a presigned GET URL valid for this
+	// long instead of the public URL. Zero means the public URL.
+	PresignExpiry time.Duration
+
+	// Collision decides what happens when an object already exists under
+	// the key. Empty means CollisionOverwrite.
+	Collision CollisionPolicy
+
+	// OnCollision picks the policy for an existing object when Collision is
+	// CollisionAsk. Without it, CollisionAsk acts as CollisionFail.
+	OnCollision func(existing *ObjectInfo) CollisionPolicy
+
+	// Retry decides how failed requests are retried. Nil means the
+	// defaults of RetryPolicy.
+	Retry *RetryPolicy
+
+	// OnRetry is called before a failed request is sent again. It may be
+	// called from several goroutines at once.
+	OnRetry func(RetryEvent)
+
+	// Limiter caps the upload rate. Uploads that share one are limited
+	// together. Nil means no limit.
+	Limiter *Limiter
+
+	// conditional makes writes fail if the key has been taken since it was
+	// checked.
+	conditional bool
+
+	// filename is the original file name used in Content-Disposition.
+	filename string
+
+	// retries retries the requests of the upload in progress.
+	retries *retrier
+}
+
+// UploadResult describes an uploaded object.
+type UploadResult struct {
+	Key    string
+	URL    string
+	Bucket string
+	Size   int64
+	ETag   string
+
+	// Checksum is the hex SHA-256 of the content, if it was computed.
+	Checksum string
+
+	// Skipped is set when the collision policy kept an existing object
+	// instead of uploading.
+	Skipped bool
+
+	// Retries lists the requests that succeeded only after being retried.
+	Retries []PartRetry
 }
 
+// ProgressCallback receives the total number of bytes sent so far. For
+// multipart uploads this is the combined count across all parts.
 type ProgressCallback func(uploaded int64)
 
 type progressReader struct {
@@ -51,86 +137,150 @@ func (pr *progressReader) Seek(offset int64, whence int) (int64, error) {
 	return pos, err
 }
 
-func Upload(cfg *config.Config, filePath string) (string, error) {
-	return UploadWithOptions(cfg, filePath, nil)
+func Upload(ctx context.Context, cfg *config.Config, filePath string) (string, error) {
+	return UploadWithOptions(ctx, cfg, filePath, nil)
 }
 
-func UploadWithProgress(cfg *config.Config, filePath string, progressCallback ProgressCallback) (string, error) {
-	return UploadWithOptionsAndProgress(cfg, filePath, nil, progressCallback)
+func UploadWithProgress(ctx context.Context, cfg *config.Config, filePath string, progressCallback ProgressCallback) (string, error) {
+	return UploadWithOptionsAndProgress(ctx, cfg, filePath, nil, progressCallback)
 }
 
-func UploadWithOptions(cfg *config.Config, filePath string, opts *UploadOptions) (string, error) {
-	return UploadWithOptionsAndProgress(cfg, filePath, opts, nil)
+func UploadWithOptions(ctx context.Context, cfg *config.Config, filePath string, opts *UploadOptions) (string, error) {
+	return UploadWithOptionsAndProgress(ctx, cfg, filePath, opts, nil)
 }
 
-func UploadWithOptionsAndProgress(cfg *config.Config, filePath string, opts *UploadOptions, progressCallback ProgressCallback) (string, error) {
+func UploadWithOptionsAndProgress(ctx context.Context, cfg *config.Config, filePath string, opts *UploadOptions, progressCallback ProgressCallback) (string, error) {
+	backend, err := NewS3Backend(ctx, cfg, nil)
+	if err != nil {
+		return "", err
+	}
+	result, err := UploadFile(ctx, backend, filePath, opts, progressCallback)
+	if err != nil {
+		return "", err
+	}
+	return result.URL, nil
+}
+
+// UploadFile uploads a file and describes the resulting object. Cancelling
+// ctx stops the requests in flight and returns ErrCancelled. A multipart
+// upload that was cancelled or failed is kept so that it can be resumed.
+func UploadFile(ctx context.Context, backend Backend, filePath string, opts *UploadOptions, progressCallback ProgressCallback) (result *UploadResult, err error) {
+	defer func() { err = cancelled(ctx, err) }()
+
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
 	fileInfo, err := file.Stat()
 	if err != nil {
-		return "", fmt.Errorf("failed to get file info: %w", err)
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+	size := fileInfo.Size()
+
+	fileName := filepath.Base(filePath)
+	if opts.Key != "" {
+		fileName = opts.Key
 	}
 
-	var httpClient *http.Client
-	if opts != nil && opts.InsecureTLS {
-		httpClient = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
+	var checksum string
+	if opts.Checksum {
+		if checksum = hashFile(sha256.New(), file, size); checksum == "" {
+			return nil, fmt.Errorf("failed to hash file")
 		}
 	}
 
-	configOptions := []func(*awsconfig.LoadOptions) error{
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, "")),
-		awsconfig.WithRegion("auto"),
+	fileName, existing, conditional, err := resolveCollision(ctx, backend, fileName, opts, sameFileContent(file, size, opts))
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if progressCallback != nil {
+			progressCallback(size)
+		}
+		url, err := objectURL(ctx, backend, fileName, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &UploadResult{
+			Key:      fileName,
+			URL:      url,
+			Bucket:   backend.Bucket(),
+			Size:     existing.Size,
+			ETag:     existing.ETag,
+			Checksum: checksum,
+			Skipped:  true,
+		}, nil
 	}
 
-	if httpClient != nil {
-		configOptions = append(configOptions, awsconfig.WithHTTPClient(httpClient))
+	writeOpts := *opts
+	writeOpts.conditional = conditional
+	writeOpts.filename = filepath.Base(filePath)
+	writeOpts.retries = opts.newRetrier(fileName)
+	if writeOpts.ContentType == "" {
+		writeOpts.ContentType = detectContentType(fileName, file)
 	}
 
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.TODO(), configOptions...)
-	if err != nil {
-		return "", fmt.Errorf("failed to load aws config: %w", err)
+	if progressCallback == nil {
+
+		bar := pb.Full.Start64(size)
+		defer bar.Finish()
+		defer bar.SetCurrent(size)
 	}
 
-	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		o.UsePathStyle = true
+	var etag string
+	if size >= opts.multipartThreshold() {
+		etag, err = uploadMultipart(ctx, backend, fileName, file, size, &writeOpts, progressCallback)
+	} else {
+		etag, err = putObject(ctx, backend, fileName, file, size, &writeOpts, progressCallback)
+	}
 
-		o.BaseEndpoint = aws.String(cfg.Endpoint)
-	})
+	if err != nil {
 
-	fileName := filepath.Base(filePath)
+		return nil, fmt.Errorf("failed to upload file '%s' to bucket '%s': %w", fileName, backend.Bucket(), err)
+	}
+
+	url, err := objectURL(ctx, backend, fileName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{
+		Key:      fileName,
+		URL:      url,
+		Bucket:   backend.Bucket(),
+		Size:     size,
+		ETag:     etag,
+		Checksum: checksum,
+		Retries:  writeOpts.retries.parts(),
+	}, nil
+}
+
+func publicURL(cfg *config.Config, key string) string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.PublicUrl, "/"), key)
+}
 
+func putObject(ctx context.Context, backend Backend, key string, file io.ReadSeeker, size int64, opts *UploadOptions, progressCallback ProgressCallback) (string, error) {
 	var body io.ReadSeeker = file
 
 	if progressCallback != nil {
 		body = &progressReader{
 			reader:   file,
-			total:    fileInfo.Size(),
+			total:    size,
 			callback: progressCallback,
 		}
-	} else {
-
-		bar := pb.Full.Start64(fileInfo.Size())
-		defer bar.Finish()
-		defer bar.SetCurrent(fileInfo.Size())
 	}
+	body = opts.Limiter.limit(ctx, body)
 
-	_, err = client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(cfg.Bucket),
-		Key:    aws.String(fileName),
-		Body:   body,
+	etag, err := opts.retries.do(ctx, 0, body, func() (string, error) {
+		return backend.Put(ctx, key, body, size, opts.writeOptions())
 	})
-
-	if err != nil {
-
-		return "", fmt.Errorf("failed to upload file '%s' to bucket '%s': %w", fileName, cfg.Bucket, err)
+	if errors.Is(err, ErrPreconditionFailed) {
+		return "", &ObjectExistsError{Key: key}
 	}
-
-	return fmt.Sprintf("%s/%s", strings.TrimSuffix(cfg.PublicUrl, "/"), fileName), nil
+	return etag, err
 }
@@ -0,0 +1,242 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"sync"
+)
+
+// UploadStream uploads everything read from r to opts.Key without knowing
+// the length in advance. Data is read in parts of opts.PartSize; a stream
+// that fits in one part is sent with a single PutObject, anything longer as
+// a multipart upload with up to opts.Concurrency parts in memory and in
+// flight at once. Stream uploads cannot be resumed, so a failed multipart
+// upload is aborted. Cancelling ctx stops the upload with ErrCancelled.
+func UploadStream(ctx context.Context, backend Backend, r io.Reader, opts *UploadOptions, progressCallback ProgressCallback) (result *UploadResult, err error) {
+	defer func() { err = cancelled(ctx, err) }()
+
+	if opts == nil || opts.Key == "" {
+		return nil, errors.New("a key is required to upload a stream")
+	}
+
+	key, existing, conditional, err := resolveCollision(ctx, backend, opts.Key, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		url, err := objectURL(ctx, backend, key, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &UploadResult{
+			Key:     key,
+			URL:     url,
+			Bucket:  backend.Bucket(),
+			Size:    existing.Size,
+			ETag:    existing.ETag,
+			Skipped: true,
+		}, nil
+	}
+
+	writeOpts := *opts
+	writeOpts.conditional = conditional
+	writeOpts.filename = path.Base(key)
+	writeOpts.retries = opts.newRetrier(key)
+
+	hash := sha256.New()
+	r = io.TeeReader(r, hash)
+
+	partSize := opts.partSize(0)
+	first := make([]byte, partSize)
+	n, err := io.ReadFull(r, first)
+	if writeOpts.ContentType == "" {
+		writeOpts.ContentType = detectContentType(key, bytes.NewReader(first[:n]))
+	}
+
+	var (
+		etag string
+		size int64
+	)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		size = int64(n)
+		etag, err = putObject(ctx, backend, key, bytes.NewReader(first[:n]), size, &writeOpts, progressCallback)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	default:
+		counter := &countingReader{reader: r, count: int64(n)}
+		etag, err = uploadStreamMultipart(ctx, backend, key, first, counter, &writeOpts, progressCallback)
+		size = counter.count
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload '%s' to bucket '%s': %w", key, backend.Bucket(), err)
+	}
+
+	url, err := objectURL(ctx, backend, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadResult{
+		Key:      key,
+		URL:      url,
+		Bucket:   backend.Bucket(),
+		Size:     size,
+		ETag:     etag,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+		Retries:  writeOpts.retries.parts(),
+	}, nil
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+type streamPart struct {
+	number int32
+	data   []byte
+}
+
+// uploadStreamMultipart uploads first followed by the rest of r. Part buffers
+// are reused once their part is sent, which bounds memory to
+// concurrency * part size.
+func uploadStreamMultipart(parent context.Context, backend Backend, key string, first []byte, r io.Reader, opts *UploadOptions, progressCallback ProgressCallback) (string, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	uploadID, err := backend.CreateMultipart(ctx, key, opts.writeOptions())
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	concurrency := opts.concurrency()
+	free := make(chan []byte, concurrency)
+	free <- first
+	for i := 1; i < concurrency; i++ {
+		free <- make([]byte, len(first))
+	}
+
+	tracker := &progressTracker{callback: progressCallback}
+	jobs := make(chan streamPart)
+	var (
+		mu        sync.Mutex
+		firstErr  error
+		completed []Part
+		wg        sync.WaitGroup
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range jobs {
+				size := int64(len(part.data))
+				body := opts.Limiter.limit(ctx, &partReader{
+					reader:  io.NewSectionReader(bytes.NewReader(part.data), 0, size),
+					tracker: tracker,
+				})
+				etag, err := opts.retries.do(ctx, part.number, body, func() (string, error) {
+					return backend.UploadPart(ctx, key, uploadID, part.number, body, size)
+				})
+				free <- part.data[:cap(part.data)]
+				if err != nil {
+					fail(fmt.Errorf("failed to upload part %d: %w", part.number, err))
+					continue
+				}
+
+				mu.Lock()
+				completed = append(completed, Part{Number: part.number, ETag: etag, Size: size})
+				mu.Unlock()
+			}
+		}()
+	}
+
+	readErr := readStreamParts(ctx, r, free, jobs)
+	close(jobs)
+	wg.Wait()
+
+	if readErr != nil {
+		fail(readErr)
+	}
+	if firstErr == nil {
+		firstErr = parent.Err()
+	}
+	if firstErr != nil {
+		abortMultipart(ctx, backend, key, uploadID)
+		return "", firstErr
+	}
+
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].Number < completed[j].Number
+	})
+
+	etag, err := completeMultipart(parent, backend, key, uploadID, completed, opts.conditional)
+	if err != nil {
+		abortMultipart(ctx, backend, key, uploadID)
+		return "", err
+	}
+	return etag, nil
+}
+
+// readStreamParts fills buffers from free with data from r and hands them
+// to jobs as numbered parts until r is exhausted. The first buffer in free
+// already holds a full part.
+func readStreamParts(ctx context.Context, r io.Reader, free chan []byte, jobs chan<- streamPart) error {
+	for number := int32(1); ; number++ {
+		var buf []byte
+		select {
+		case buf = <-free:
+		case <-ctx.Done():
+			return nil
+		}
+
+		n := len(buf)
+		if number > 1 {
+			var err error
+			n, err = io.ReadFull(r, buf)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return fmt.Errorf("failed to read input: %w", err)
+			}
+			if n == 0 {
+				return nil
+			}
+		}
+
+		if number > MaxParts {
+			return fmt.Errorf("input is larger than %d parts of %d MiB, use a larger part size", MaxParts, len(buf)/MiB)
+		}
+
+		select {
+		case jobs <- streamPart{number: number, data: buf[:n]}:
+		case <-ctx.Done():
+			return nil
+		}
+
+		if n < len(buf) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadStreamParts(t *testing.T) {
+	tests := []struct {
+		name     string
+		rest     string
+		expected []string
+	}{
+		{name: "one full part", rest: "", expected: []string{"abcd"}},
+		{name: "short last part", rest: "efghij", expected: []string{"abcd", "efgh", "ij"}},
+		{name: "exact multiple", rest: "efgh", expected: []string{"abcd", "efgh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			free := make(chan []byte, 2)
+			free <- []byte("abcd")
+			free <- make([]byte, 4)
+
+			jobs := make(chan streamPart)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- readStreamParts(context.Background(), strings.NewReader(tt.rest), free, jobs)
+				close(jobs)
+			}()
+
+			var got []string
+			for part := range jobs {
+				if part.number != int32(len(got)+1) {
+					t.Errorf("part %d has number %d", len(got)+1, part.number)
+				}
+				got = append(got, string(bytes.Clone(part.data)))
+				free <- part.data[:cap(part.data)]
+			}
+
+			if err := <-errCh; err != nil {
+				t.Fatalf("readStreamParts() error = %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("parts = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	fileFormatVersion = 1
+	kdfIterations     = 600000
+	saltSize          = 16
+	keySize           = 32
+)
+
+// PassphraseFunc returns the passphrase protecting the secrets file. confirm
+// is true when the file is being created and the passphrase should be asked
+// for twice.
+type PassphraseFunc func(confirm bool) (string, error)
+
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// FileStore keeps secrets in a file encrypted with AES-256-GCM under a key
+// derived from a passphrase with PBKDF2-SHA256.
+type FileStore struct {
+	path       string
+	passphrase PassphraseFunc
+
+	mu   sync.Mutex
+	key  []byte
+	salt []byte
+}
+
+func NewFileStore(path string, passphrase PassphraseFunc) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase}
+}
+
+func (s *FileStore) Name() string {
+	return "encrypted file"
+}
+
+func (s *FileStore) Get(account string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	secret, ok := entries[account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (s *FileStore) Set(account, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	entries[account] = secret
+	return s.save(entries)
+}
+
+func (s *FileStore) Delete(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := entries[account]; !ok {
+		return nil
+	}
+	delete(entries, account)
+	return s.save(entries)
+}
+
+func (s *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %w", s.path, err)
+	}
+	if file.Version != fileFormatVersion {
+		return nil, fmt.Errorf("unsupported secrets file version %d", file.Version)
+	}
+
+	if s.key == nil {
+		passphrase, err := s.passphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.deriveKey(passphrase, file.Salt, file.Iterations); err != nil {
+			return nil, err
+		}
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		s.key = nil
+		return nil, errors.New("cannot decrypt secrets file: wrong passphrase")
+	}
+
+	entries := map[string]string{}
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, fmt.Errorf("invalid secrets file contents: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *FileStore) save(entries map[string]string) error {
+	if s.key == nil {
+		passphrase, err := s.passphrase(true)
+		if err != nil {
+			return err
+		}
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		if err := s.deriveKey(passphrase, salt, kdfIterations); err != nil {
+			return err
+		}
+	}
+
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(encryptedFile{
+		Version:    fileFormatVersion,
+		KDF:        "pbkdf2-sha256",
+		Iterations: kdfIterations,
+		Salt:       s.salt,
+		Nonce:      nonce,
+		Data:       gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileStore) deriveKey(passphrase string, salt []byte, iterations int) error {
+	if passphrase == "" {
+		return errors.New("empty passphrase")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return err
+	}
+	s.key = key
+	s.salt = salt
+	return nil
+}
+
+func (s *FileStore) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,84 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fixedPassphrase(passphrase string) PassphraseFunc {
+	return func(bool) (string, error) {
+		return passphrase, nil
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testsecrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "secrets.enc")
+	store := NewFileStore(path, fixedPassphrase("correct horse"))
+
+	if _, err := store.Get("r2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() on empty store error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Set("r2", "r2-secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("minio", "minio-secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	reopened := NewFileStore(path, fixedPassphrase("correct horse"))
+	secret, err := reopened.Get("r2")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if secret != "r2-secret" {
+		t.Errorf("Get() = %q, want r2-secret", secret)
+	}
+
+	if err := reopened.Delete("r2"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := reopened.Get("r2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+
+	wrong := NewFileStore(path, fixedPassphrase("wrong"))
+	if _, err := wrong.Get("minio"); err == nil {
+		t.Error("Get() with the wrong passphrase should fail")
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Backend
+		wantErr bool
+	}{
+		{input: "", want: BackendAuto},
+		{input: "auto", want: BackendAuto},
+		{input: "keyring", want: BackendKeyring},
+		{input: "file", want: BackendFile},
+		{input: "plaintext", want: BackendPlaintext},
+		{input: "vault", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBackend(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBackend(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBackend(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const keyringService = "termup"
+
+// keyringStore keeps secrets in the freedesktop Secret Service (GNOME
+// Keyring, KWallet) through the secret-tool command from libsecret.
+type keyringStore struct{}
+
+func newKeyringStore() *keyringStore {
+	return &keyringStore{}
+}
+
+func KeyringAvailable() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (s *keyringStore) Name() string {
+	return "keyring"
+}
+
+func (s *keyringStore) Get(account string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", keyringService, "account", account)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("keyring lookup failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+func (s *keyringStore) Set(account, secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "store", "--label", "termup ("+account+")", "service", keyringService, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("keyring store failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (s *keyringStore) Delete(account string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "clear", "service", keyringService, "account", account)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("keyring clear failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// PassphraseEnvVar supplies the secrets file passphrase without a prompt,
+// for scripts and CI.
+const PassphraseEnvVar = "TERMUP_PASSPHRASE"
+
+// PromptPassphrase reads the passphrase from $TERMUP_PASSPHRASE or, failing
+// that, from the terminal without echoing it.
+func PromptPassphrase(confirm bool) (string, error) {
+	if env := os.Getenv(PassphraseEnvVar); env != "" {
+		return env, nil
+	}
+
+	fd := os.Stdin.Fd()
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("a passphrase is needed for the encrypted secrets file: set %s or run in a terminal", PassphraseEnvVar)
+	}
+
+	passphrase, err := readPassphrase(fd, "Passphrase for termup secrets: ")
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		again, err := readPassphrase(fd, "Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+func readPassphrase(fd uintptr, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Backend names a place secrets can be kept.
+type Backend string
+
+const (
+	// BackendAuto uses the OS keyring when it is available and the encrypted
+	// file otherwise.
+	BackendAuto      Backend = ""
+	BackendKeyring   Backend = "keyring"
+	BackendFile      Backend = "file"
+	BackendPlaintext Backend = "plaintext"
+)
+
+// BackendEnvVar overrides the configured backend.
+const BackendEnvVar = "TERMUP_SECRET_BACKEND"
+
+var ErrNotFound = errors.New("secret not found")
+
+// Store keeps secrets by account name.
+type Store interface {
+	Name() string
+	Get(account string) (string, error)
+	Set(account, secret string) error
+	Delete(account string) error
+}
+
+func ParseBackend(name string) (Backend, error) {
+	switch b := Backend(name); b {
+	case BackendAuto, BackendKeyring, BackendFile, BackendPlaintext:
+		return b, nil
+	case "auto":
+		return BackendAuto, nil
+	}
+	return "", fmt.Errorf("unknown secret backend %q (want keyring, file or plaintext)", name)
+}
+
+// Resolve applies $TERMUP_SECRET_BACKEND and picks a concrete backend for
+// BackendAuto.
+func Resolve(backend Backend) (Backend, error) {
+	if env := os.Getenv(BackendEnvVar); env != "" {
+		b, err := ParseBackend(env)
+		if err != nil {
+			return "", err
+		}
+		backend = b
+	}
+
+	if backend == BackendAuto {
+		if KeyringAvailable() {
+			return BackendKeyring, nil
+		}
+		return BackendFile, nil
+	}
+	return backend, nil
+}
+
+// Open returns the store for backend, keeping any files in dir. It returns a
+// nil Store for BackendPlaintext, where secrets stay in the config file.
+func Open(backend Backend, dir string) (Store, error) {
+	backend, err := Resolve(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case BackendKeyring:
+		if !KeyringAvailable() {
+			return nil, errors.New("the Secret Service keyring is not available (secret-tool not found or no D-Bus session)")
+		}
+		return newKeyringStore(), nil
+	case BackendFile:
+		return NewFileStore(filepath.Join(dir, "secrets.enc"), PromptPassphrase), nil
+	}
+	return nil, nil
+}
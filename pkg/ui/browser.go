@@ -0,0 +1,595 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nizar0x1f/termup/pkg/clipboard"
+)
+
+// BrowserEntry is an object or a folder (a key prefix ending in "/") in the
+// bucket browser.
+type BrowserEntry struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	Dir          bool
+}
+
+// BrowserPage is one page of a folder listing. NextToken is empty on the
+// last page.
+type BrowserPage struct {
+	Entries   []BrowserEntry
+	NextToken string
+}
+
+// ObjectDetails is the metadata shown in the browser's preview pane.
+type ObjectDetails struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	ContentType  string
+	Metadata     map[string]string
+}
+
+// BrowserSource is the bucket the browser shows.
+type BrowserSource interface {
+	// List returns the page of entries directly under prefix that token
+	// points to; the first page has an empty token.
+	List(ctx context.Context, prefix, token string) (*BrowserPage, error)
+	Details(ctx context.Context, key string) (*ObjectDetails, error)
+	URL(ctx context.Context, key string) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type BrowserActionKind int
+
+const (
+	BrowserQuit BrowserActionKind = iota
+	BrowserDownload
+	BrowserUpload
+)
+
+// BrowserAction is what the browser was closed for. Downloads and uploads
+// are run by the caller, which can then reopen the browser at Prefix.
+type BrowserAction struct {
+	Kind   BrowserActionKind
+	Prefix string
+
+	// Key is the object to download.
+	Key string
+
+	// Path is the local file to upload into Prefix.
+	Path string
+}
+
+type browserMode int
+
+const (
+	browseNormal browserMode = iota
+	browseFilter
+	browseConfirmDelete
+	browseUploadPrompt
+)
+
+// loadAhead is how close to the end of the loaded entries the cursor gets
+// before the next page is requested.
+const loadAhead = 50
+
+// previewDelay keeps the preview from sending a request for every entry the
+// cursor passes while scrolling.
+const previewDelay = 150 * time.Millisecond
+
+type browserPageMsg struct {
+	seq  int
+	page *BrowserPage
+	err  error
+}
+
+type browserDetailsMsg struct {
+	key     string
+	details *ObjectDetails
+	err     error
+}
+
+type browserPreviewMsg struct {
+	seq int
+}
+
+type browserDeletedMsg struct {
+	key string
+	err error
+}
+
+type browserCopiedMsg struct {
+	url string
+	err error
+}
+
+// BrowserModel is a full-screen browser for a bucket: folders are key
+// prefixes, and folders are listed page by page as the cursor reaches the
+// end of what has been loaded.
+type BrowserModel struct {
+	source BrowserSource
+	prefix string
+
+	entries   []BrowserEntry
+	nextToken string
+	loading   bool
+	loadErr   error
+	listSeq   int
+
+	mode   browserMode
+	filter textinput.Model
+	input  textinput.Model
+
+	cursor int
+	offset int
+	rows   int
+	width  int
+
+	details    map[string]*ObjectDetails
+	detailsErr map[string]error
+	previewSeq int
+
+	spinner spinner.Model
+	status  string
+	action  BrowserAction
+}
+
+var (
+	dirStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#00D7FF")).
+			Bold(true)
+
+	previewStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#626262")).
+			Padding(0, 1)
+)
+
+// NewBrowserModel creates a browser for source that opens at prefix.
+func NewBrowserModel(source BrowserSource, prefix string) BrowserModel {
+	filter := textinput.New()
+	filter.Prompt = "/"
+	filter.Placeholder = "filter by name"
+
+	input := textinput.New()
+	input.Prompt = "Upload file: "
+	input.Placeholder = "path/to/file"
+
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	return BrowserModel{
+		source:     source,
+		prefix:     prefix,
+		filter:     filter,
+		input:      input,
+		rows:       defaultQueueRows,
+		details:    make(map[string]*ObjectDetails),
+		detailsErr: make(map[string]error),
+		spinner:    s,
+		loading:    true,
+		action:     BrowserAction{Kind: BrowserQuit, Prefix: prefix},
+	}
+}
+
+func (m BrowserModel) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, m.load(""))
+}
+
+// Action is what the browser was closed for.
+func (m BrowserModel) Action() BrowserAction {
+	return m.action
+}
+
+func (m *BrowserModel) load(token string) tea.Cmd {
+	m.loading = true
+	source, prefix, seq := m.source, m.prefix, m.listSeq
+	return func() tea.Msg {
+		page, err := source.List(context.Background(), prefix, token)
+		return browserPageMsg{seq: seq, page: page, err: err}
+	}
+}
+
+// open shows the folder at prefix.
+func (m *BrowserModel) open(prefix string) tea.Cmd {
+	m.prefix = prefix
+	m.action.Prefix = prefix
+	m.entries = nil
+	m.nextToken = ""
+	m.loadErr = nil
+	m.cursor, m.offset = 0, 0
+	m.filter.SetValue("")
+	m.listSeq++
+	return m.load("")
+}
+
+func parentPrefix(prefix string) string {
+	trimmed := strings.TrimSuffix(prefix, "/")
+	if i := strings.LastIndex(trimmed, "/"); i >= 0 {
+		return trimmed[:i+1]
+	}
+	return ""
+}
+
+func (m BrowserModel) name(entry BrowserEntry) string {
+	return strings.TrimPrefix(entry.Key, m.prefix)
+}
+
+// visible returns the loaded entries that match the filter.
+func (m BrowserModel) visible() []BrowserEntry {
+	query := strings.ToLower(m.filter.Value())
+	if query == "" {
+		return m.entries
+	}
+	var matched []BrowserEntry
+	for _, entry := range m.entries {
+		if strings.Contains(strings.ToLower(m.name(entry)), query) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+func (m BrowserModel) selected() (BrowserEntry, bool) {
+	entries := m.visible()
+	if m.cursor < 0 || m.cursor >= len(entries) {
+		return BrowserEntry{}, false
+	}
+	return entries[m.cursor], true
+}
+
+// moved keeps the cursor in range, loads the next page when the cursor gets
+// close to the end and schedules the preview of the selected object.
+func (m *BrowserModel) moved() tea.Cmd {
+	count := len(m.visible())
+	m.cursor = max(0, min(m.cursor, count-1))
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.rows {
+		m.offset = m.cursor - m.rows + 1
+	}
+	m.offset = max(0, min(m.offset, count-m.rows))
+
+	var cmds []tea.Cmd
+	if m.nextToken != "" && !m.loading && m.cursor+loadAhead >= count {
+		cmds = append(cmds, m.load(m.nextToken))
+	}
+
+	if entry, ok := m.selected(); ok && !entry.Dir {
+		if m.details[entry.Key] == nil && m.detailsErr[entry.Key] == nil {
+			m.previewSeq++
+			seq := m.previewSeq
+			cmds = append(cmds, tea.Tick(previewDelay, func(time.Time) tea.Msg {
+				return browserPreviewMsg{seq: seq}
+			}))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m BrowserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.rows = max(3, msg.Height-8)
+		return m, m.moved()
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case browserPageMsg:
+		if msg.seq != m.listSeq {
+			return m, nil
+		}
+		m.loading = false
+		if msg.err != nil {
+			m.loadErr = msg.err
+			return m, nil
+		}
+		m.entries = append(m.entries, msg.page.Entries...)
+		m.nextToken = msg.page.NextToken
+		return m, m.moved()
+
+	case browserPreviewMsg:
+		entry, ok := m.selected()
+		if msg.seq != m.previewSeq || !ok || entry.Dir {
+			return m, nil
+		}
+		source := m.source
+		return m, func() tea.Msg {
+			details, err := source.Details(context.Background(), entry.Key)
+			return browserDetailsMsg{key: entry.Key, details: details, err: err}
+		}
+
+	case browserDetailsMsg:
+		if msg.err != nil {
+			m.detailsErr[msg.key] = msg.err
+		} else {
+			m.details[msg.key] = msg.details
+		}
+		return m, nil
+
+	case browserDeletedMsg:
+		if msg.err != nil {
+			m.status = errorStyle.Render("✗ " + msg.err.Error())
+			return m, nil
+		}
+		m.status = successStyle.Render("✓ Deleted " + msg.key)
+		for i, entry := range m.entries {
+			if entry.Key == msg.key {
+				m.entries = append(m.entries[:i], m.entries[i+1:]...)
+				break
+			}
+		}
+		delete(m.details, msg.key)
+		return m, m.moved()
+
+	case browserCopiedMsg:
+		if msg.err != nil {
+			m.status = errorStyle.Render("✗ " + msg.err.Error())
+		} else {
+			m.status = successStyle.Render("✓ Copied ") + urlStyle.Render(msg.url)
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.action = BrowserAction{Kind: BrowserQuit, Prefix: m.prefix}
+			return m, tea.Quit
+		}
+		switch m.mode {
+		case browseFilter:
+			return m.updateFilter(msg)
+		case browseConfirmDelete:
+			return m.updateConfirmDelete(msg)
+		case browseUploadPrompt:
+			return m.updateUploadPrompt(msg)
+		}
+		return m.updateNormal(msg)
+	}
+
+	return m, nil
+}
+
+func (m BrowserModel) updateNormal(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "esc":
+		if m.filter.Value() != "" {
+			m.filter.SetValue("")
+			return m, m.moved()
+		}
+		return m, tea.Quit
+	case "up", "k":
+		m.cursor--
+	case "down", "j":
+		m.cursor++
+	case "pgup":
+		m.cursor -= m.rows
+	case "pgdown":
+		m.cursor += m.rows
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = len(m.visible()) - 1
+	case "enter", "right", "l":
+		if entry, ok := m.selected(); ok && entry.Dir {
+			return m, m.open(entry.Key)
+		}
+	case "backspace", "left", "h":
+		if m.prefix != "" {
+			return m, m.open(parentPrefix(m.prefix))
+		}
+	case "r":
+		return m, m.open(m.prefix)
+	case "/":
+		m.mode = browseFilter
+		return m, m.filter.Focus()
+	case "c", "y":
+		entry, ok := m.selected()
+		if !ok || entry.Dir {
+			return m, nil
+		}
+		source := m.source
+		return m, func() tea.Msg {
+			url, err := source.URL(context.Background(), entry.Key)
+			if err == nil {
+				err = clipboard.Write(url)
+			}
+			return browserCopiedMsg{url: url, err: err}
+		}
+	case "d":
+		if entry, ok := m.selected(); ok && !entry.Dir {
+			m.action = BrowserAction{Kind: BrowserDownload, Prefix: m.prefix, Key: entry.Key}
+			return m, tea.Quit
+		}
+	case "x", "delete":
+		if entry, ok := m.selected(); ok && !entry.Dir {
+			m.mode = browseConfirmDelete
+		}
+	case "u":
+		m.mode = browseUploadPrompt
+		m.input.SetValue("")
+		return m, m.input.Focus()
+	}
+
+	return m, m.moved()
+}
+
+func (m BrowserModel) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.filter.SetValue("")
+		fallthrough
+	case "enter":
+		m.mode = browseNormal
+		m.filter.Blur()
+		return m, m.moved()
+	}
+
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.cursor = 0
+	return m, tea.Batch(cmd, m.moved())
+}
+
+func (m BrowserModel) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.mode = browseNormal
+
+	entry, ok := m.selected()
+	if msg.String() != "y" || !ok {
+		return m, nil
+	}
+	m.status = "Deleting " + entry.Key + "…"
+	source := m.source
+	return m, func() tea.Msg {
+		return browserDeletedMsg{key: entry.Key, err: source.Delete(context.Background(), entry.Key)}
+	}
+}
+
+func (m BrowserModel) updateUploadPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = browseNormal
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		path := strings.TrimSpace(m.input.Value())
+		if path == "" {
+			return m, nil
+		}
+		m.action = BrowserAction{Kind: BrowserUpload, Prefix: m.prefix, Path: path}
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m BrowserModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("Bucket Browser"))
+	b.WriteString(" ")
+	b.WriteString(filenameStyle.Render("/" + m.prefix))
+	b.WriteString("\n\n")
+
+	entries := m.visible()
+	var list strings.Builder
+	switch {
+	case m.loadErr != nil:
+		list.WriteString(errorStyle.Render("✗ " + m.loadErr.Error()))
+	case len(entries) == 0 && m.loading:
+		list.WriteString(m.spinner.View() + " Loading…")
+	case len(entries) == 0:
+		list.WriteString(mutedStyle.Render("(empty)"))
+	default:
+		end := min(len(entries), m.offset+m.rows)
+		for i := m.offset; i < end; i++ {
+			list.WriteString(m.entryView(entries[i], i == m.cursor))
+			list.WriteString("\n")
+		}
+		more := ""
+		if m.nextToken != "" {
+			more = "+"
+		}
+		list.WriteString(mutedStyle.Render(fmt.Sprintf("  %d-%d of %d%s", m.offset+1, end, len(entries), more)))
+		if m.loading {
+			list.WriteString(" " + m.spinner.View())
+		}
+	}
+
+	if preview := m.previewView(); preview != "" && m.width >= 80 {
+		b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, list.String(), "  ", preview))
+	} else {
+		b.WriteString(list.String())
+	}
+	b.WriteString("\n\n")
+
+	switch m.mode {
+	case browseFilter:
+		b.WriteString(m.filter.View())
+	case browseConfirmDelete:
+		entry, _ := m.selected()
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Delete %s? ", entry.Key)))
+		b.WriteString(helpStyle.Render("y to confirm, any other key to cancel"))
+	case browseUploadPrompt:
+		b.WriteString(m.input.View())
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("enter upload into /" + m.prefix + " • esc cancel"))
+	default:
+		if m.status != "" {
+			b.WriteString(m.status)
+			b.WriteString("\n")
+		} else if m.filter.Value() != "" {
+			b.WriteString(mutedStyle.Render("filter: " + m.filter.Value()))
+			b.WriteString("\n")
+		}
+		b.WriteString(helpStyle.Render("enter open • ⌫ up • / filter • c copy URL • d download • x delete • u upload • r refresh • q quit"))
+	}
+
+	return b.String()
+}
+
+func (m BrowserModel) entryView(entry BrowserEntry, selected bool) string {
+	cursor := "  "
+	if selected {
+		cursor = cursorStyle.Render("> ")
+	}
+
+	if entry.Dir {
+		return cursor + dirStyle.Render(m.name(entry))
+	}
+	return fmt.Sprintf("%s%-40s %10s  %s", cursor, m.name(entry),
+		FormatBytes(entry.Size), mutedStyle.Render(entry.LastModified.Local().Format("2006-01-02 15:04")))
+}
+
+func (m BrowserModel) previewView() string {
+	entry, ok := m.selected()
+	if !ok || entry.Dir {
+		return ""
+	}
+	if err := m.detailsErr[entry.Key]; err != nil {
+		return previewStyle.Render(errorStyle.Render("✗ " + err.Error()))
+	}
+	details := m.details[entry.Key]
+	if details == nil {
+		return previewStyle.Render(mutedStyle.Render("Loading details…"))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", filenameStyle.Render(m.name(entry)))
+	fmt.Fprintf(&b, "Size:     %s\n", FormatBytes(details.Size))
+	fmt.Fprintf(&b, "Modified: %s\n", details.LastModified.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "Type:     %s\n", details.ContentType)
+	fmt.Fprintf(&b, "ETag:     %s", details.ETag)
+
+	names := make([]string, 0, len(details.Metadata))
+	for name := range details.Metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n%s: %s", name, details.Metadata[name])
+	}
+	return previewStyle.Render(b.String())
+}
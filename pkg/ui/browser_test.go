@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type fakeSource struct {
+	deleted []string
+}
+
+func (s *fakeSource) List(ctx context.Context, prefix, token string) (*BrowserPage, error) {
+	return &BrowserPage{}, nil
+}
+
+func (s *fakeSource) Details(ctx context.Context, key string) (*ObjectDetails, error) {
+	return &ObjectDetails{Key: key}, nil
+}
+
+func (s *fakeSource) URL(ctx context.Context, key string) (string, error) {
+	return "https://example.com/" + key, nil
+}
+
+func (s *fakeSource) Delete(ctx context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func updateBrowser(t *testing.T, m BrowserModel, msgs ...tea.Msg) (BrowserModel, tea.Cmd) {
+	t.Helper()
+	var cmd tea.Cmd
+	for _, msg := range msgs {
+		var model tea.Model
+		model, cmd = m.Update(msg)
+		m = model.(BrowserModel)
+	}
+	return m, cmd
+}
+
+func objectsPage(prefix string, n int, next string) browserPageMsg {
+	page := &BrowserPage{NextToken: next}
+	for i := 0; i < n; i++ {
+		page.Entries = append(page.Entries, BrowserEntry{Key: fmt.Sprintf("%sfile-%03d.txt", prefix, i)})
+	}
+	return browserPageMsg{page: page}
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	m := NewBrowserModel(&fakeSource{}, "")
+	m, _ = updateBrowser(t, m, browserPageMsg{page: &BrowserPage{Entries: []BrowserEntry{
+		{Key: "photos/", Dir: true},
+		{Key: "notes.txt"},
+	}}})
+
+	m, cmd := updateBrowser(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.prefix != "photos/" || cmd == nil || !m.loading {
+		t.Fatalf("enter on folder: prefix = %q, loading = %v", m.prefix, m.loading)
+	}
+
+	// A page for the folder that was left is ignored.
+	m, _ = updateBrowser(t, m, browserPageMsg{seq: 0, page: &BrowserPage{Entries: []BrowserEntry{{Key: "stale"}}}})
+	if len(m.entries) != 0 {
+		t.Errorf("stale page was shown: %+v", m.entries)
+	}
+
+	m, _ = updateBrowser(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.prefix != "" {
+		t.Errorf("prefix after backspace = %q, want root", m.prefix)
+	}
+}
+
+func TestBrowserPagination(t *testing.T) {
+	m := NewBrowserModel(&fakeSource{}, "logs/")
+	m, _ = updateBrowser(t, m, objectsPage("logs/", 100, "next"))
+	if m.loading {
+		t.Fatal("next page loaded before the cursor got close to the end")
+	}
+
+	m, _ = updateBrowser(t, m, key("G"))
+	if !m.loading {
+		t.Fatal("next page not requested at the end of the list")
+	}
+
+	m, _ = updateBrowser(t, m, objectsPage("logs/more-", 10, ""))
+	if len(m.entries) != 110 || m.nextToken != "" {
+		t.Errorf("entries = %d, next = %q", len(m.entries), m.nextToken)
+	}
+}
+
+func TestBrowserFilterAndDelete(t *testing.T) {
+	source := &fakeSource{}
+	m := NewBrowserModel(source, "")
+	m, _ = updateBrowser(t, m, browserPageMsg{page: &BrowserPage{Entries: []BrowserEntry{
+		{Key: "alpha.png"}, {Key: "beta.png"}, {Key: "gamma.txt"},
+	}}})
+
+	m, _ = updateBrowser(t, m, key("/"), key("b"), key("e"), tea.KeyMsg{Type: tea.KeyEnter})
+	if got := m.visible(); len(got) != 1 || got[0].Key != "beta.png" {
+		t.Fatalf("visible = %+v, want beta.png", got)
+	}
+
+	m, _ = updateBrowser(t, m, key("x"), key("n"))
+	if m.mode != browseNormal {
+		t.Fatal("delete prompt still open after n")
+	}
+
+	m, cmd := updateBrowser(t, m, key("x"), key("y"))
+	if cmd == nil {
+		t.Fatal("confirmed delete returned no command")
+	}
+	m, _ = updateBrowser(t, m, cmd())
+	if len(source.deleted) != 1 || source.deleted[0] != "beta.png" {
+		t.Errorf("deleted = %v, want [beta.png]", source.deleted)
+	}
+	if len(m.entries) != 2 {
+		t.Errorf("entries after delete = %+v", m.entries)
+	}
+}
+
+func TestBrowserActions(t *testing.T) {
+	m := NewBrowserModel(&fakeSource{}, "docs/")
+	m, _ = updateBrowser(t, m, browserPageMsg{page: &BrowserPage{Entries: []BrowserEntry{{Key: "docs/a.pdf"}}}})
+
+	download, _ := updateBrowser(t, m, key("d"))
+	if got := download.Action(); got.Kind != BrowserDownload || got.Key != "docs/a.pdf" {
+		t.Errorf("download action = %+v", got)
+	}
+
+	upload, _ := updateBrowser(t, m, key("u"), key("f"), key("."), key("t"), tea.KeyMsg{Type: tea.KeyEnter})
+	if got := upload.Action(); got.Kind != BrowserUpload || got.Path != "f.t" || got.Prefix != "docs/" {
+		t.Errorf("upload action = %+v", got)
+	}
+
+	quit, _ := updateBrowser(t, m, key("q"))
+	if got := quit.Action(); got.Kind != BrowserQuit {
+		t.Errorf("quit action = %+v", got)
+	}
+}
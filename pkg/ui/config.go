@@ -14,6 +14,8 @@ type ConfigModel struct {
 	current  string
 	config   *config.Config
 	finished bool
+	profile  string
+	existing *config.Config
 }
 
 const (
@@ -50,6 +52,34 @@ func NewConfigModel() ConfigModel {
 	}
 }
 
+// NewProfileConfigModel edits a single named profile. If existing is not nil
+// its values are offered as defaults; the secret key is kept when left empty.
+func NewProfileConfigModel(profile string, existing *config.Config) ConfigModel {
+	m := NewConfigModel()
+	m.profile = profile
+	m.existing = existing
+	m.current = m.prefill(stepAccessKey)
+	return m
+}
+
+func (m ConfigModel) prefill(step int) string {
+	if m.existing == nil {
+		return ""
+	}
+
+	switch step {
+	case stepAccessKey:
+		return m.existing.AccessKeyID
+	case stepBucket:
+		return m.existing.Bucket
+	case stepEndpoint:
+		return m.existing.Endpoint
+	case stepPublicUrl:
+		return m.existing.PublicUrl
+	}
+	return ""
+}
+
 func (m ConfigModel) Init() tea.Cmd {
 	return nil
 }
@@ -67,8 +97,8 @@ func (m ConfigModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			m.inputs[m.step] = m.current
-			m.current = ""
 			m.step++
+			m.current = m.prefill(m.step)
 			if m.step == stepComplete {
 				return m, m.createConfig()
 			}
@@ -112,7 +142,11 @@ func (m ConfigModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m ConfigModel) View() string {
 	var b strings.Builder
 
-	b.WriteString(titleStyle.Render("S3 Storage Configuration"))
+	title := "S3 Storage Configuration"
+	if m.profile != "" {
+		title += " - profile " + m.profile
+	}
+	b.WriteString(titleStyle.Render(title))
 	b.WriteString("\n\n")
 
 	prompts := []string{
@@ -129,8 +163,13 @@ func (m ConfigModel) View() string {
 		b.WriteString(inputStyle.Render(m.current + "█"))
 		b.WriteString("\n\n")
 
+		if m.step == stepSecretKey && m.existing != nil {
+			b.WriteString(helpStyle.Render("Leave empty to keep the current secret"))
+			b.WriteString("\n\n")
+		}
+
 		if m.step == stepPublicUrl {
-			b.WriteString(helpStyle.Render("Default: https://your-bucket.s3.amazonaws.com/"))
+			b.WriteString(helpStyle.Render("Default: " + config.DefaultPublicUrl))
 			b.WriteString("\n\n")
 		}
 
@@ -148,15 +187,20 @@ func (m ConfigModel) createConfig() tea.Cmd {
 	return func() tea.Msg {
 		PublicUrl := strings.TrimSpace(m.inputs[stepPublicUrl])
 		if PublicUrl == "" {
-			PublicUrl = "https://your-bucket.s3.amazonaws.com/"
+			PublicUrl = config.DefaultPublicUrl
+		}
+
+		cfg := &config.Config{}
+		if m.existing != nil {
+			*cfg = *m.existing
 		}
+		cfg.AccessKeyID = strings.TrimSpace(m.inputs[stepAccessKey])
+		cfg.Bucket = strings.TrimSpace(m.inputs[stepBucket])
+		cfg.Endpoint = strings.TrimSpace(m.inputs[stepEndpoint])
+		cfg.PublicUrl = PublicUrl
 
-		cfg := &config.Config{
-			AccessKeyID:     strings.TrimSpace(m.inputs[stepAccessKey]),
-			SecretAccessKey: strings.TrimSpace(m.inputs[stepSecretKey]),
-			Bucket:          strings.TrimSpace(m.inputs[stepBucket]),
-			Endpoint:        strings.TrimSpace(m.inputs[stepEndpoint]),
-			PublicUrl:       PublicUrl,
+		if secret := strings.TrimSpace(m.inputs[stepSecretKey]); secret != "" || m.existing == nil {
+			cfg.SecretAccessKey = secret
 		}
 
 		return configCreatedMsg(cfg)
@@ -0,0 +1,56 @@
+package ui
+
+import "fmt"
+
+// minRateLimit is the lowest speed limit the - key sets.
+const minRateLimit = 64 * 1024
+
+// rateLimit is the speed limit of a running transfer, which + and - raise
+// and lower by a quarter and 0 removes.
+type rateLimit struct {
+	rate int64
+	set  func(rate int64)
+}
+
+// adjust handles the limit keys and reports whether key was one of them.
+// Lowering without a limit starts from speed, the current speed.
+func (l *rateLimit) adjust(key string, speed float64) bool {
+	if l.set == nil {
+		return false
+	}
+
+	rate := l.rate
+	switch key {
+	case "+", "=":
+		if rate == 0 {
+			return true
+		}
+		rate = rate * 5 / 4
+	case "-", "_":
+		if rate == 0 {
+			rate = int64(speed)
+		}
+		if rate == 0 {
+			return true
+		}
+		rate = max(rate*4/5, minRateLimit)
+	case "0":
+		rate = 0
+	default:
+		return false
+	}
+
+	l.rate = rate
+	l.set(rate)
+	return true
+}
+
+func (l rateLimit) view() string {
+	if l.set == nil {
+		return ""
+	}
+	if l.rate == 0 {
+		return "No speed limit • - to set one"
+	}
+	return fmt.Sprintf("Limit %s/s • +/- to change, 0 to remove", FormatBytes(l.rate))
+}
@@ -0,0 +1,368 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type QueueState int
+
+const (
+	QueueQueued QueueState = iota
+	QueueUploading
+	QueueDone
+	QueueFailed
+	QueueCancelled
+)
+
+func (s QueueState) finished() bool {
+	return s == QueueDone || s == QueueFailed || s == QueueCancelled
+}
+
+// QueueItem is one file in the upload queue.
+type QueueItem struct {
+	Name     string
+	Size     int64
+	State    QueueState
+	Uploaded int64
+	URL      string
+	Err      error
+	Skipped  bool
+}
+
+type QueueStartMsg int
+
+type QueueProgressMsg struct {
+	Index    int
+	Uploaded int64
+}
+
+type QueueDoneMsg struct {
+	Index     int
+	URL       string
+	Err       error
+	Cancelled bool
+	Skipped   bool
+}
+
+const defaultQueueRows = 10
+
+// QueueModel shows the progress of a multi-file upload: one line per file
+// with a progress bar for the active ones, and overall totals.
+type QueueModel struct {
+	items  []QueueItem
+	cancel func(index int)
+
+	overall progress.Model
+	file    progress.Model
+	spinner spinner.Model
+
+	cursor int
+	offset int
+	rows   int
+	width  int
+
+	total      int64
+	uploaded   int64
+	startTime  time.Time
+	lastUpdate time.Time
+	lastBytes  int64
+	speed      float64
+
+	limit rateLimit
+
+	quitting bool
+}
+
+var (
+	cursorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#7D56F4")).
+			Bold(true)
+
+	mutedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262"))
+)
+
+// NewQueueModel creates a queue view for items. cancel is called with the
+// index of a file the user wants to stop.
+func NewQueueModel(items []QueueItem, cancel func(index int)) QueueModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	var total int64
+	for _, item := range items {
+		total += item.Size
+	}
+
+	now := time.Now()
+	return QueueModel{
+		items:      items,
+		cancel:     cancel,
+		overall:    progress.New(progress.WithDefaultGradient()),
+		file:       progress.New(progress.WithDefaultGradient(), progress.WithWidth(20), progress.WithoutPercentage()),
+		spinner:    s,
+		rows:       defaultQueueRows,
+		total:      total,
+		startTime:  now,
+		lastUpdate: now,
+	}
+}
+
+// SetRateLimit shows the speed limit shared by all files, rate bytes per
+// second or zero for none, and lets the user change it with + and - while
+// files are uploading. set is called with each new limit.
+func (m *QueueModel) SetRateLimit(rate int64, set func(rate int64)) {
+	m.limit = rateLimit{rate: rate, set: set}
+}
+
+func (m QueueModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m QueueModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.rows = max(3, msg.Height-12)
+		m.scroll()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			m.quitting = true
+			return m, tea.Quit
+		case "enter", "q":
+			if m.Finished() {
+				m.quitting = true
+				return m, tea.Quit
+			}
+		case "up", "k":
+			m.cursor--
+		case "down", "j":
+			m.cursor++
+		case "pgup":
+			m.cursor -= m.rows
+		case "pgdown":
+			m.cursor += m.rows
+		case "home", "g":
+			m.cursor = 0
+		case "end", "G":
+			m.cursor = len(m.items) - 1
+		case "x", "delete":
+			item := &m.items[m.cursor]
+			if !item.State.finished() && m.cancel != nil {
+				m.cancel(m.cursor)
+				if item.State == QueueQueued {
+					item.State = QueueCancelled
+				}
+			}
+		default:
+			if !m.Finished() {
+				m.limit.adjust(msg.String(), m.speed)
+			}
+		}
+		m.scroll()
+		return m, nil
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case QueueStartMsg:
+		m.items[msg].State = QueueUploading
+		return m, nil
+
+	case QueueProgressMsg:
+		item := &m.items[msg.Index]
+		m.addUploaded(msg.Uploaded - item.Uploaded)
+		item.Uploaded = msg.Uploaded
+		return m, nil
+
+	case QueueDoneMsg:
+		item := &m.items[msg.Index]
+		switch {
+		case msg.Err == nil:
+			item.State = QueueDone
+			item.URL = msg.URL
+			item.Skipped = msg.Skipped
+			m.addUploaded(item.Size - item.Uploaded)
+			item.Uploaded = item.Size
+		case msg.Cancelled:
+			item.State = QueueCancelled
+		default:
+			item.State = QueueFailed
+			item.Err = msg.Err
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m *QueueModel) addUploaded(delta int64) {
+	m.uploaded += delta
+
+	now := time.Now()
+	if elapsed := now.Sub(m.lastUpdate).Seconds(); elapsed >= 0.5 {
+		m.speed = float64(m.uploaded-m.lastBytes) / elapsed
+		m.lastUpdate = now
+		m.lastBytes = m.uploaded
+	}
+}
+
+// scroll keeps the cursor inside the list and visible.
+func (m *QueueModel) scroll() {
+	m.cursor = max(0, min(m.cursor, len(m.items)-1))
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.rows {
+		m.offset = m.cursor - m.rows + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.items)-m.rows))
+}
+
+// Finished reports whether every file has been uploaded, failed or been
+// cancelled.
+func (m QueueModel) Finished() bool {
+	for _, item := range m.items {
+		if !item.State.finished() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m QueueModel) Items() []QueueItem {
+	return m.items
+}
+
+func (m QueueModel) counts() (done, failed, cancelled, active int) {
+	for _, item := range m.items {
+		switch item.State {
+		case QueueDone:
+			done++
+		case QueueFailed:
+			failed++
+		case QueueCancelled:
+			cancelled++
+		case QueueUploading:
+			active++
+		}
+	}
+	return
+}
+
+func (m QueueModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("File Upload"))
+	b.WriteString("\n\n")
+
+	done, failed, cancelled, active := m.counts()
+	b.WriteString(fmt.Sprintf("Uploading %s files (%s)\n\n",
+		filenameStyle.Render(fmt.Sprint(len(m.items))), FormatBytes(m.total)))
+
+	percent := 1.0
+	if m.total > 0 {
+		percent = float64(m.uploaded) / float64(m.total)
+	}
+	if !m.Finished() {
+		b.WriteString(m.spinner.View())
+		b.WriteString(" ")
+	}
+	b.WriteString(m.overall.ViewAs(percent))
+	b.WriteString("\n\n")
+
+	eta := "--:--"
+	if m.speed > 0 {
+		eta = FormatDuration(time.Duration(float64(m.total-m.uploaded) / m.speed * float64(time.Second)))
+	}
+	b.WriteString(statsStyle.Render(fmt.Sprintf(
+		"%s / %s %s/s ETA: %s Elapsed: %s",
+		FormatBytes(m.uploaded),
+		FormatBytes(m.total),
+		speedStyle.Render(FormatBytes(int64(m.speed))),
+		eta,
+		FormatDuration(time.Since(m.startTime)),
+	)))
+	b.WriteString("\n")
+	b.WriteString(statsStyle.Render(fmt.Sprintf(
+		"%d uploading, %d done, %d failed, %d cancelled, %d queued",
+		active, done, failed, cancelled, len(m.items)-done-failed-cancelled-active,
+	)))
+	b.WriteString("\n\n")
+
+	end := min(len(m.items), m.offset+m.rows)
+	for i := m.offset; i < end; i++ {
+		b.WriteString(m.itemView(i))
+		b.WriteString("\n")
+	}
+	if len(m.items) > m.rows {
+		b.WriteString(mutedStyle.Render(fmt.Sprintf("  %d-%d of %d", m.offset+1, end, len(m.items))))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	if m.Finished() {
+		b.WriteString(helpStyle.Render("↑/↓ scroll • q or enter to exit"))
+	} else {
+		if view := m.limit.view(); view != "" {
+			b.WriteString(helpStyle.Render(view))
+			b.WriteString("\n")
+		}
+		b.WriteString(helpStyle.Render("↑/↓ scroll • x cancel file • esc quit"))
+	}
+
+	return b.String()
+}
+
+func (m QueueModel) itemView(i int) string {
+	item := m.items[i]
+
+	cursor := "  "
+	if i == m.cursor {
+		cursor = cursorStyle.Render("> ")
+	}
+
+	name := item.Name
+	if m.width > 0 && len(name) > m.width/2 {
+		name = "…" + name[len(name)-m.width/2+1:]
+	}
+
+	var status string
+	switch item.State {
+	case QueueQueued:
+		status = mutedStyle.Render("queued")
+	case QueueUploading:
+		percent := 1.0
+		if item.Size > 0 {
+			percent = float64(item.Uploaded) / float64(item.Size)
+		}
+		status = fmt.Sprintf("%s %3.0f%%", m.file.ViewAs(percent), percent*100)
+	case QueueDone:
+		status = successStyle.Render("✓ ") + urlStyle.Render(item.URL)
+		if item.Skipped {
+			status += mutedStyle.Render(" (already uploaded)")
+		}
+	case QueueFailed:
+		status = errorStyle.Render("✗ ") + item.Err.Error()
+	case QueueCancelled:
+		status = mutedStyle.Render("cancelled")
+	}
+
+	return fmt.Sprintf("%s%s %s %s", cursor, filenameStyle.Render(name), mutedStyle.Render(FormatBytes(item.Size)), status)
+}
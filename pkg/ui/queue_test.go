@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func updateQueue(t *testing.T, m QueueModel, msgs ...tea.Msg) QueueModel {
+	t.Helper()
+	for _, msg := range msgs {
+		model, _ := m.Update(msg)
+		m = model.(QueueModel)
+	}
+	return m
+}
+
+func key(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestQueueModelStates(t *testing.T) {
+	items := []QueueItem{{Name: "a", Size: 100}, {Name: "b", Size: 200}, {Name: "c", Size: 300}}
+	m := NewQueueModel(items, nil)
+
+	m = updateQueue(t, m,
+		QueueStartMsg(0),
+		QueueStartMsg(1),
+		QueueProgressMsg{Index: 0, Uploaded: 50},
+		QueueProgressMsg{Index: 1, Uploaded: 20},
+		QueueDoneMsg{Index: 0, URL: "https://example.com/a"},
+		QueueDoneMsg{Index: 1, Err: errors.New("boom")},
+	)
+
+	got := m.Items()
+	if got[0].State != QueueDone || got[0].URL != "https://example.com/a" {
+		t.Errorf("item 0 = %+v, want done", got[0])
+	}
+	if got[1].State != QueueFailed {
+		t.Errorf("item 1 state = %v, want failed", got[1].State)
+	}
+	if got[2].State != QueueQueued {
+		t.Errorf("item 2 state = %v, want queued", got[2].State)
+	}
+	if m.uploaded != 120 {
+		t.Errorf("uploaded = %d, want 120", m.uploaded)
+	}
+	if m.Finished() {
+		t.Error("Finished() = true with a queued file")
+	}
+
+	m = updateQueue(t, m, QueueDoneMsg{Index: 2, Err: errors.New("cancelled"), Cancelled: true})
+	if m.Items()[2].State != QueueCancelled || !m.Finished() {
+		t.Errorf("item 2 state = %v, finished = %v", m.Items()[2].State, m.Finished())
+	}
+}
+
+func TestQueueModelCancelAndScroll(t *testing.T) {
+	items := make([]QueueItem, 30)
+	for i := range items {
+		items[i] = QueueItem{Name: "file", Size: 1}
+	}
+
+	var cancelled []int
+	m := NewQueueModel(items, func(index int) { cancelled = append(cancelled, index) })
+	m = updateQueue(t, m, tea.WindowSizeMsg{Width: 80, Height: 17})
+
+	if m.rows != 5 {
+		t.Fatalf("rows = %d, want 5", m.rows)
+	}
+
+	m = updateQueue(t, m, key("j"), key("j"), key("j"), key("j"), key("j"), key("j"))
+	if m.cursor != 6 || m.offset != 2 {
+		t.Errorf("cursor = %d, offset = %d, want 6, 2", m.cursor, m.offset)
+	}
+
+	m = updateQueue(t, m, key("x"))
+	if len(cancelled) != 1 || cancelled[0] != 6 {
+		t.Errorf("cancelled = %v, want [6]", cancelled)
+	}
+	if m.Items()[6].State != QueueCancelled {
+		t.Errorf("queued file state after cancel = %v", m.Items()[6].State)
+	}
+
+	m = updateQueue(t, m, key("G"))
+	if m.cursor != 29 || m.offset != 25 {
+		t.Errorf("cursor = %d, offset = %d, want 29, 25", m.cursor, m.offset)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/nizar0x1f/termup/pkg/clipboard"
 )
 
 type UploadModel struct {
@@ -25,6 +26,25 @@ type UploadModel struct {
 	lastUpdate time.Time
 	lastBytes  int64
 	speed      float64
+	skipped    bool
+	conflict   *UploadConflictMsg
+	download   bool
+
+	// autoCopy copies the URL to the clipboard once the upload is done.
+	autoCopy  bool
+	copyURL   func(string) error
+	copyState string
+
+	// cancel stops the transfer when the user quits before it is done.
+	cancel func()
+
+	// retry is the latest retried request, shown for a while after
+	// retryAt; retried lists the requests that needed retries once done.
+	retry   *UploadRetryMsg
+	retryAt time.Time
+	retried string
+
+	limit rateLimit
 }
 
 var (
@@ -52,6 +72,25 @@ var (
 			Bold(true)
 )
 
+// unknownSize is the file size of uploads whose length is not known in
+// advance, such as data read from stdin.
+const unknownSize = -1
+
+// NewStreamUploadModel creates an upload view for data of unknown length. It
+// shows the bytes sent and the speed instead of a percentage.
+func NewStreamUploadModel(name string) UploadModel {
+	return NewUploadModel(name, unknownSize)
+}
+
+// NewDownloadModel creates the same progress view for downloading key. The
+// download is driven with the Upload*Msg messages; UploadCompleteMsg
+// carries the path the object was saved to.
+func NewDownloadModel(key string, size int64) UploadModel {
+	m := NewUploadModel(key, size)
+	m.download = true
+	return m
+}
+
 func NewUploadModel(filename string, fileSize int64) UploadModel {
 	p := progress.New(progress.WithDefaultGradient())
 	s := spinner.New()
@@ -69,7 +108,35 @@ func NewUploadModel(filename string, fileSize int64) UploadModel {
 		lastUpdate: now,
 		lastBytes:  0,
 		speed:      0,
+		copyURL:    clipboard.Write,
+	}
+}
+
+// SetAutoCopy sets whether the URL is copied to the clipboard when the
+// upload succeeds. It can be copied again with "c" either way.
+func (m *UploadModel) SetAutoCopy(on bool) {
+	m.autoCopy = on
+}
+
+// SetCancel sets the function that stops the transfer when the user quits
+// before it has finished.
+func (m *UploadModel) SetCancel(cancel func()) {
+	m.cancel = cancel
+}
+
+// SetRateLimit shows the speed limit, rate bytes per second or zero for
+// none, and lets the user change it with + and - while the transfer is
+// running. set is called with each new limit.
+func (m *UploadModel) SetRateLimit(rate int64, set func(rate int64)) {
+	m.limit = rateLimit{rate: rate, set: set}
+}
+
+// quit leaves the UI, cancelling the transfer if it is still running.
+func (m UploadModel) quit() (tea.Model, tea.Cmd) {
+	if !m.done && m.err == nil && m.cancel != nil {
+		m.cancel()
 	}
+	return m, tea.Quit
 }
 
 func (m UploadModel) Init() tea.Cmd {
@@ -82,13 +149,24 @@ func (m UploadModel) Init() tea.Cmd {
 func (m UploadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.conflict != nil {
+			return m.answerConflict(msg.String())
+		}
 		switch msg.String() {
 		case "ctrl+c", "esc":
-			return m, tea.Quit
+			return m.quit()
 		case "enter", "q":
 			if m.done {
 				return m, tea.Quit
 			}
+		case "c":
+			if m.canCopy() {
+				return m, m.copy()
+			}
+		default:
+			if m.uploading {
+				m.limit.adjust(msg.String(), m.speed)
+			}
 		}
 
 	case spinner.TickMsg:
@@ -123,10 +201,38 @@ func (m UploadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case UploadRetryMsg:
+		m.retry = &msg
+		m.retryAt = time.Now()
+		return m, nil
+
+	case UploadRetriedMsg:
+		m.retried = string(msg)
+		return m, nil
+
+	case UploadConflictMsg:
+		m.conflict = &msg
+		return m, nil
+
 	case UploadCompleteMsg:
 		m.url = string(msg)
 		m.done = true
 		m.uploading = false
+		return m, m.autoCopyURL()
+
+	case UploadSkippedMsg:
+		m.url = string(msg)
+		m.skipped = true
+		m.done = true
+		m.uploading = false
+		return m, m.autoCopyURL()
+
+	case urlCopiedMsg:
+		if msg.err != nil {
+			m.copyState = errorStyle.Render("✗ Could not copy the URL: " + msg.err.Error())
+		} else {
+			m.copyState = successStyle.Render("✓ Copied to clipboard")
+		}
 		return m, nil
 
 	case UploadErrorMsg:
@@ -142,14 +248,38 @@ func (m UploadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m UploadModel) View() string {
 	var b strings.Builder
 
-	b.WriteString(titleStyle.Render("File Upload"))
+	title, action, result, failed := "File Upload", "Uploading: ", "✓ Upload successful!", "✗ Upload failed"
+	if m.download {
+		title, action, result, failed = "File Download", "Downloading: ", "✓ Download successful!", "✗ Download failed"
+	}
+
+	b.WriteString(titleStyle.Render(title))
 	b.WriteString("\n\n")
 
-	b.WriteString("Uploading: ")
+	b.WriteString(action)
 	b.WriteString(filenameStyle.Render(m.filename))
 	b.WriteString("\n\n")
 
-	if m.uploading {
+	if m.conflict != nil {
+		b.WriteString(errorStyle.Render("! " + m.conflict.Key + " already exists"))
+		b.WriteString("\n\n")
+		b.WriteString(statsStyle.Render(fmt.Sprintf("%s, last modified %s",
+			FormatBytes(m.conflict.Size), m.conflict.LastModified.Local().Format("2006-01-02 15:04"))))
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render("o overwrite • r rename • s skip • c cancel"))
+	} else if m.uploading && m.fileSize == unknownSize {
+		b.WriteString(m.spinner.View())
+		b.WriteString(" ")
+		b.WriteString(statsStyle.Render(fmt.Sprintf(
+			"%s sent %s/s",
+			FormatBytes(m.uploaded),
+			speedStyle.Render(FormatBytes(int64(m.speed))),
+		)))
+		b.WriteString("\n")
+		b.WriteString(statsStyle.Render("Elapsed: " + FormatDuration(time.Since(m.startTime))))
+		m.writeRetry(&b)
+		m.writeLimit(&b)
+	} else if m.uploading {
 
 		b.WriteString(m.spinner.View())
 		b.WriteString(" ")
@@ -166,45 +296,179 @@ func (m UploadModel) View() string {
 			if m.speed > 0 && m.uploaded > 0 {
 				remaining := m.fileSize - m.uploaded
 				etaSeconds := float64(remaining) / m.speed
-				eta = formatDuration(time.Duration(etaSeconds * float64(time.Second)))
+				eta = FormatDuration(time.Duration(etaSeconds * float64(time.Second)))
 			} else {
 				eta = "--:--"
 			}
 
 			b.WriteString(statsStyle.Render(fmt.Sprintf(
 				"%s / %s (%.1f%%) %s/s ETA: %s",
-				formatBytes(m.uploaded),
-				formatBytes(m.fileSize),
+				FormatBytes(m.uploaded),
+				FormatBytes(m.fileSize),
 				percent,
-				speedStyle.Render(formatBytes(int64(m.speed))),
+				speedStyle.Render(FormatBytes(int64(m.speed))),
 				eta,
 			)))
 			b.WriteString("\n")
 
-			b.WriteString(statsStyle.Render("Elapsed: " + formatDuration(elapsed)))
+			b.WriteString(statsStyle.Render("Elapsed: " + FormatDuration(elapsed)))
 		}
+		m.writeRetry(&b)
+		m.writeLimit(&b)
 	} else if m.err != nil {
-		b.WriteString(errorStyle.Render("✗ Upload failed"))
+		b.WriteString(errorStyle.Render(failed))
 		b.WriteString("\n\n")
 		b.WriteString("Error: " + m.err.Error())
 		b.WriteString("\n\n")
 		b.WriteString(helpStyle.Render("Press q or enter to exit"))
-	} else {
-		b.WriteString(successStyle.Render("✓ Upload successful!"))
+	} else if m.skipped {
+		b.WriteString(successStyle.Render("✓ Already uploaded, nothing to do"))
 		b.WriteString("\n\n")
 		b.WriteString("URL: ")
 		b.WriteString(urlStyle.Render(m.url))
 		b.WriteString("\n\n")
-		b.WriteString(helpStyle.Render("Press q or enter to exit"))
+		m.writeCopyHelp(&b)
+	} else {
+		b.WriteString(successStyle.Render(result))
+		b.WriteString("\n\n")
+		if m.download {
+			b.WriteString("Saved to: ")
+			b.WriteString(filenameStyle.Render(m.url))
+		} else {
+			b.WriteString("URL: ")
+			b.WriteString(urlStyle.Render(m.url))
+		}
+		b.WriteString("\n\n")
+		if m.retried != "" {
+			b.WriteString(statsStyle.Render("Retried: " + m.retried))
+			b.WriteString("\n\n")
+		}
+		m.writeCopyHelp(&b)
 	}
 
 	return b.String()
 }
 
+// retryNotice is how long a retry stays on screen.
+const retryNotice = 10 * time.Second
+
+func (m UploadModel) writeRetry(b *strings.Builder) {
+	if m.retry == nil || time.Since(m.retryAt) > retryNotice {
+		return
+	}
+	what := "retrying"
+	if m.retry.Part > 0 {
+		what = fmt.Sprintf("retrying part %d", m.retry.Part)
+	}
+	b.WriteString("\n")
+	b.WriteString(errorStyle.Render(fmt.Sprintf("↻ %s (attempt %d/%d)", what, m.retry.Attempt, m.retry.MaxAttempts)))
+}
+
+func (m UploadModel) writeLimit(b *strings.Builder) {
+	if view := m.limit.view(); view != "" {
+		b.WriteString("\n\n")
+		b.WriteString(helpStyle.Render(view))
+	}
+}
+
+func (m UploadModel) writeCopyHelp(b *strings.Builder) {
+	if !m.canCopy() {
+		b.WriteString(helpStyle.Render("Press q or enter to exit"))
+		return
+	}
+	if m.copyState != "" {
+		b.WriteString(m.copyState)
+		b.WriteString("\n\n")
+	}
+	b.WriteString(helpStyle.Render("c copy URL • q or enter to exit"))
+}
+
+// urlCopiedMsg reports the result of copying the URL to the clipboard.
+type urlCopiedMsg struct {
+	err error
+}
+
+func (m UploadModel) canCopy() bool {
+	return m.done && m.err == nil && !m.download && m.url != ""
+}
+
+func (m UploadModel) autoCopyURL() tea.Cmd {
+	if !m.autoCopy || !m.canCopy() {
+		return nil
+	}
+	return m.copy()
+}
+
+func (m UploadModel) copy() tea.Cmd {
+	url, copyURL := m.url, m.copyURL
+	return func() tea.Msg {
+		return urlCopiedMsg{err: copyURL(url)}
+	}
+}
+
 type UploadProgressMsg int64
 type UploadCompleteMsg string
 type UploadErrorMsg error
 
+// UploadRetryMsg reports that a failed request of the upload is being sent
+// again. Part is zero for an upload sent in a single request.
+type UploadRetryMsg struct {
+	Part        int32
+	Attempt     int
+	MaxAttempts int
+}
+
+// UploadRetriedMsg lists the requests that needed retries, e.g. "part 3
+// took 2 attempts", to show once the upload is complete.
+type UploadRetriedMsg string
+
+// UploadSkippedMsg ends an upload that was not needed because the object
+// already exists; it carries the object's URL.
+type UploadSkippedMsg string
+
+// ConflictChoice is the answer to an UploadConflictMsg.
+type ConflictChoice string
+
+const (
+	ConflictOverwrite ConflictChoice = "overwrite"
+	ConflictRename    ConflictChoice = "rename"
+	ConflictSkip      ConflictChoice = "skip"
+	ConflictCancel    ConflictChoice = "cancel"
+)
+
+// UploadConflictMsg asks the user what to do about an object that already
+// exists under the upload's key. The answer is sent on Reply.
+type UploadConflictMsg struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	Reply        chan<- ConflictChoice
+}
+
+func (m UploadModel) answerConflict(key string) (tea.Model, tea.Cmd) {
+	var choice ConflictChoice
+	switch key {
+	case "o":
+		choice = ConflictOverwrite
+	case "r":
+		choice = ConflictRename
+	case "s":
+		choice = ConflictSkip
+	case "c", "n", "esc":
+		choice = ConflictCancel
+	case "ctrl+c":
+		m.conflict.Reply <- ConflictCancel
+		m.conflict = nil
+		return m.quit()
+	default:
+		return m, nil
+	}
+
+	m.conflict.Reply <- choice
+	m.conflict = nil
+	return m, nil
+}
+
 func (m *UploadModel) UpdateProgress(uploaded int64) tea.Cmd {
 	return func() tea.Msg {
 		return UploadProgressMsg(uploaded)
@@ -223,7 +487,7 @@ func (m *UploadModel) ErrorUpload(err error) tea.Cmd {
 	}
 }
 
-func formatBytes(bytes int64) string {
+func FormatBytes(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
 		return fmt.Sprintf("%d B", bytes)
@@ -248,7 +512,7 @@ func (m UploadModel) GetError() error {
 	return m.err
 }
 
-func formatDuration(d time.Duration) string {
+func FormatDuration(d time.Duration) string {
 	if d < time.Minute {
 		return fmt.Sprintf("%02.0fs", d.Seconds())
 	}
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestUploadModelConflict(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected ConflictChoice
+	}{
+		{"o", ConflictOverwrite},
+		{"r", ConflictRename},
+		{"s", ConflictSkip},
+		{"c", ConflictCancel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			reply := make(chan ConflictChoice, 1)
+			var model tea.Model = NewUploadModel("a.txt", 5)
+			model, _ = model.Update(UploadConflictMsg{Key: "a.txt", Size: 5, LastModified: time.Now(), Reply: reply})
+
+			model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+			select {
+			case choice := <-reply:
+				t.Fatalf("unrelated key answered %q", choice)
+			default:
+			}
+
+			model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.key)})
+			if choice := <-reply; choice != tt.expected {
+				t.Errorf("choice = %q, want %q", choice, tt.expected)
+			}
+			if model.(UploadModel).conflict != nil {
+				t.Error("conflict prompt still shown after answering")
+			}
+		})
+	}
+}
+
+func TestUploadModelCopy(t *testing.T) {
+	var copied []string
+	model := NewUploadModel("a.txt", 5)
+	model.copyURL = func(url string) error {
+		copied = append(copied, url)
+		return nil
+	}
+	model.SetAutoCopy(true)
+
+	run := func(m tea.Model, cmd tea.Cmd) tea.Model {
+		if cmd != nil {
+			m, _ = m.Update(cmd())
+		}
+		return m
+	}
+
+	var m tea.Model = model
+	m = run(m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")}))
+	if len(copied) != 0 {
+		t.Fatal("copied before the upload finished")
+	}
+
+	m = run(m.Update(UploadCompleteMsg("https://example.com/a.txt")))
+	if len(copied) != 1 || copied[0] != "https://example.com/a.txt" {
+		t.Fatalf("auto copy = %q", copied)
+	}
+	if !strings.Contains(m.View(), "Copied to clipboard") {
+		t.Error("view does not confirm the copy")
+	}
+
+	run(m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")}))
+	if len(copied) != 2 {
+		t.Errorf("c copied %d times in total, want 2", len(copied))
+	}
+
+	download := NewDownloadModel("a.txt", 5)
+	download.copyURL = model.copyURL
+	download.SetAutoCopy(true)
+	run(download.Update(UploadCompleteMsg("/tmp/a.txt")))
+	if len(copied) != 2 {
+		t.Error("download path was copied")
+	}
+}
+
+func TestUploadModelCancel(t *testing.T) {
+	cancelled := 0
+	model := NewUploadModel("a.txt", 5)
+	model.SetCancel(func() { cancelled++ })
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil || cancelled != 1 {
+		t.Fatalf("ctrl+c during the upload cancelled %d times, want 1", cancelled)
+	}
+
+	var m tea.Model = model
+	m, _ = m.Update(UploadCompleteMsg("https://example.com/a.txt"))
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cancelled != 1 {
+		t.Error("quitting after the upload finished cancelled it")
+	}
+}
+
+func TestUploadModelRetry(t *testing.T) {
+	var m tea.Model = NewUploadModel("a.txt", 5)
+	m, _ = m.Update(UploadRetryMsg{Part: 7, Attempt: 2, MaxAttempts: 5})
+	if view := m.View(); !strings.Contains(view, "retrying part 7 (attempt 2/5)") {
+		t.Errorf("view during the retry = %q", view)
+	}
+
+	m, _ = m.Update(UploadRetriedMsg("part 2 took 2 attempts, part 7 took 3 attempts"))
+	m, _ = m.Update(UploadCompleteMsg("https://example.com/a.txt"))
+	if view := m.View(); !strings.Contains(view, "Retried: part 2 took 2 attempts, part 7 took 3 attempts") {
+		t.Errorf("view after the upload = %q", view)
+	}
+}
+
+func TestUploadModelRateLimit(t *testing.T) {
+	var limits []int64
+	model := NewUploadModel("a.txt", 100*1024*1024)
+	model.SetRateLimit(0, func(rate int64) { limits = append(limits, rate) })
+	model.speed = 10 * 1024 * 1024
+
+	keys := []string{"+", "-", "-", "=", "0", "x"}
+	want := []int64{8 * 1024 * 1024, 6710886, 8388607, 0}
+
+	var m tea.Model = model
+	if !strings.Contains(m.View(), "No speed limit") {
+		t.Errorf("view without a limit = %q", m.View())
+	}
+	for _, key := range keys {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		if key == "-" && len(limits) == 1 && !strings.Contains(m.View(), "Limit 8.0 MB/s") {
+			t.Errorf("view after lowering the limit = %q", m.View())
+		}
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("limits = %v, want %v", limits, want)
+	}
+
+	m, _ = m.Update(UploadCompleteMsg("https://example.com/a.txt"))
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("-")})
+	if len(limits) != len(want) {
+		t.Error("the limit changed after the upload finished")
+	}
+}